@@ -15,7 +15,9 @@ import (
 	"time"
 
 	"github.com/btcsuite/btclog"
+	"github.com/dmigwi/dhamana-protocol/client/server"
 	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
 	flags "github.com/jessevdk/go-flags"
 )
 
@@ -26,13 +28,38 @@ const (
 )
 
 type config struct {
-	Network     string `long:"network" description:"Network to use; Supported networks: SapphireMainnet, SapphireTestnet and SapphireLocalnet" default:"SapphireTestnet" required:"required"`
+	Network     string `long:"network" description:"Network to use; Supported networks: SapphireMainnet, SapphireTestnet, SapphireLocalnet and Development" default:"SapphireTestnet" required:"required"`
 	DataDirPath string `long:"datadir" description:"Directory path to where the app data is stored"`
 	LogLevel    string `long:"loglevel" description:"Logging level {trace, debug, info, warn, error, critical, off}" default:"info"`
 	TLSCertFile string `long:"certfile" description:"tls certificate file name" default:"server.crt"`
 	TLSKeyFile  string `long:"keyfile" description:"tls key file name" default:"server.key"`
 	ServerURL   string `long:"url" description:"Server url to server content using" default:"https://0.0.0.0:30443"`
 
+	// KeyManagerPubKey verifies the runtime public keys the calls are encrypted with.
+	KeyManagerPubKey string `long:"keymanagerpubkey" description:"Base64 encoded public key of the network's key manager, used to verify the runtime public keys the calls are encrypted with. Required on the Sapphire networks"`
+
+	// Trust organisations configuration
+	TrustOrgCAFile  string   `long:"trustorgcas" description:"Trust organisations CA certificates file name used to verify the POA client certificates" default:"trustorgs.crt"`
+	TrustOrgCRLFile string   `long:"trustorgcrl" description:"Optional file name of the trust organisations CRLs revoking the POA client certificates"`
+	DeniedOrgs      []string `long:"denyorg" description:"Trust organisation denied access to the server. Can be set multiple times"`
+	Admins          []string `long:"admin" description:"Address allowed to manage the registered trust organisations. Can be set multiple times"`
+
+	// Sessions configuration
+	SessionStore   string `long:"sessionstore" description:"Store holding the POA clients sessions and session challenges {memory, postgres}. memory: sessions are lost on restart and not shared between the server instances, which then require sticky routing, postgres: sessions are persisted in the db with their secrets encrypted under the master key" default:"memory"`
+	SessionKeyFile string `long:"sessionkeyfile" description:"File name of the hex encoded 32 bytes master key encrypting the sessions persisted by the postgres store. Must be shared by all the server instances" default:"session.key"`
+
+	// Sync configuration
+	Confirmations   uint64 `long:"confirmations" description:"Number of blocks an event must be buried under before it is persisted" default:"0"`
+	PendingEvents   bool   `long:"pendingevents" description:"Return the events yet to be confirmed with getBondByAddress and getChats if requested via their includePending param. Requires confirmations to be set"`
+	BackfillWorkers int    `long:"backfillworkers" description:"Maximum number of historical events block windows fetched at once" default:"4"`
+
+	// Signing configuration
+	SigningMode string `long:"signingmode" description:"Who signs the contract transactions {server, client, any}. server: the POA sends the user's encrypted private key, client: the POA signs the unsigned transactions returned, any: both are allowed" default:"server"`
+
+	// Ops configuration
+	Metrics   bool   `long:"metrics" description:"Expose the prometheus metrics via the /metrics route of the ops listener"`
+	OpsListen string `long:"opslisten" description:"Address of the plain HTTP listener serving the /healthz, /syncstatus and /metrics routes without client certificates" default:"127.0.0.1:9100"`
+
 	// DB configuration
 	DbPort     uint16 `long:"db_port" description:"Port to use when connecting to the db" default:"5432"`
 	DbHost     string `long:"db_host" description:"Host to use in connecting to the db" default:"localhost"`
@@ -73,6 +100,18 @@ func loadConfig() (*config, error) {
 		return nil, fmt.Errorf("unsupported network used: %q \n %s", conf.Network, h.String())
 	}
 
+	// The confidential networks calls are encrypted with the runtime public
+	// key, which can't be trusted without the key manager public key.
+	params, err := utils.GetNetworkConfig(utils.ToNetType(conf.Network))
+	if err != nil {
+		return nil, fmt.Errorf("%v \n %s", err, h.String())
+	}
+
+	if !params.PlainCalls && conf.KeyManagerPubKey == "" {
+		return nil, fmt.Errorf("missing key manager public key on the %q network \n %s",
+			conf.Network, h.String())
+	}
+
 	if _, ok := btclog.LevelFromString(conf.LogLevel); !ok {
 		return nil, fmt.Errorf("invalid LogLevel found: %q \n %s", conf.LogLevel, h.String())
 	}
@@ -90,6 +129,38 @@ func loadConfig() (*config, error) {
 		return nil, fmt.Errorf("invalid server url found: %q \n %s", conf.ServerURL, h.String())
 	}
 
+	if conf.OpsListen == "" {
+		return nil, fmt.Errorf("empty ops listener address found \n %s", h.String())
+	}
+
+	if utils.ToSigningMode(conf.SigningMode) == "" {
+		return nil, fmt.Errorf("unsupported signing mode used: %q \n %s",
+			conf.SigningMode, h.String())
+	}
+
+	switch conf.SessionStore {
+	case server.MemorySessions, server.PostgresSessions:
+	default:
+		return nil, fmt.Errorf("unsupported session store used: %q \n %s",
+			conf.SessionStore, h.String())
+	}
+
+	if conf.BackfillWorkers < 1 {
+		return nil, fmt.Errorf("invalid backfill workers found: %d \n %s",
+			conf.BackfillWorkers, h.String())
+	}
+
+	if conf.PendingEvents && conf.Confirmations == 0 {
+		return nil, fmt.Errorf("pending events set without confirmations, all events "+
+			"are persisted once found \n %s", h.String())
+	}
+
+	for _, admin := range conf.Admins {
+		if !common.IsHexAddress(admin) {
+			return nil, fmt.Errorf("invalid admin address found: %q \n %s", admin, h.String())
+		}
+	}
+
 	// confirm all the db configurations have supported values.
 	if !isDbConfig(&conf) {
 		return nil, fmt.Errorf("invalid db configurations found \n %s", h.String())
@@ -35,20 +35,58 @@ func run(ctx context.Context, cancelFunc context.CancelFunc) {
 	level, _ := btclog.LevelFromString(config.LogLevel)
 	setLogLevel(level)
 
-	s, err := server.NewServer(ctx, config.DbPort, config.TLSCertFile,
-		config.TLSKeyFile, config.DataDirPath, config.Network, config.ServerURL,
-		config.DbHost, config.DbName, config.DbUser, config.DbPassword)
+	s, err := server.NewServer(ctx, &server.Config{
+		Network:          config.Network,
+		ServerURL:        config.ServerURL,
+		KeyManagerPubKey: config.KeyManagerPubKey,
+		DataDir:          config.DataDirPath,
+		TLSCertFile:      config.TLSCertFile,
+		TLSKeyFile:       config.TLSKeyFile,
+		DbHost:           config.DbHost,
+		DbPort:           config.DbPort,
+		DbName:           config.DbName,
+		DbUser:           config.DbUser,
+		DbPassword:       config.DbPassword,
+		Confirmations:    config.Confirmations,
+		PendingEvents:    config.PendingEvents,
+		BackfillWorkers:  config.BackfillWorkers,
+		Metrics:          config.Metrics,
+		OpsListen:        config.OpsListen,
+		SigningMode:      config.SigningMode,
+		TrustOrgCAFile:   config.TrustOrgCAFile,
+		TrustOrgCRLFile:  config.TrustOrgCRLFile,
+		DeniedOrgs:       config.DeniedOrgs,
+		Admins:           config.Admins,
+		SessionStore:     config.SessionStore,
+		SessionKeyFile:   config.SessionKeyFile,
+	})
 	if err != nil {
 		log.Errorf("Server Config error: %v", err)
 		return
 	}
 
 	// Initiate the data syncer
-	if err = s.SyncData(); err != nil {
-		log.Errorf("SyncData failed error: %v", err)
+	syncer := s.Syncer()
+	if err = syncer.Start(ctx); err != nil {
+		log.Errorf("Syncer failed error: %v", err)
 		return
 	}
 
+	defer syncer.Stop()
+
+	// Shutdown the app if the events syncing fails since the data served
+	// would otherwise go stale.
+	go func() {
+		select {
+		case err := <-syncer.Done():
+			if err != nil {
+				log.Errorf("Syncer stopped error: %v", err)
+				cancelFunc()
+			}
+		case <-ctx.Done():
+		}
+	}()
+
 	// Run the server
 	if err = s.Run(); err != nil {
 		log.Errorf("Server failed error: %v", err)
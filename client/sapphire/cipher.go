@@ -33,6 +33,10 @@ const (
 var (
 	ErrCallFailed       = errors.New("call failed in module")
 	ErrCallResultDecode = errors.New("could not decode call result")
+
+	// ErrCallResultDecrypt is returned if the call result wasn't encrypted
+	// for the runtime public key in use.
+	ErrCallResultDecrypt = errors.New("could not decrypt call result")
 )
 
 type CallResult struct {
@@ -84,21 +88,62 @@ type CallDataPublicKey struct {
 	Checksum hexutil.Bytes `json:"checksum"`
 	// Signature is the Sign(sk, (key || checksum)) from the key manager.
 	Signature hexutil.Bytes `json:"signature"`
+	// Epoch is the epoch of the ephemeral runtime key.
+	Epoch uint64 `json:"epoch,omitempty"`
 }
 
 type Cipher interface {
 	Kind() uint64
 	Encrypt(plaintext []byte) (ciphertext []byte, nonce []byte)
 	Decrypt(nonce []byte, ciphertext []byte) (plaintext []byte, err error)
+	EncryptEnvelope(plaintext []byte) *EncryptedBodyEnvelope
 	EncryptEncode(plaintext []byte) []byte
 	DecryptEncoded(result []byte) ([]byte, error)
 	DecryptCallResult(result []byte) ([]byte, error)
 }
 
+// PlainCipher passes the calldata and the call results through unchanged. It
+// is used with the non-confidential EVM networks such as the local development
+// chains.
+type PlainCipher struct{}
+
+// Confirm that PlainCipher implements the Cipher interface.
+var _ Cipher = PlainCipher{}
+
+func (PlainCipher) Kind() uint64 {
+	return Plain
+}
+
+func (PlainCipher) Encrypt(plaintext []byte) (ciphertext []byte, nonce []byte) {
+	return plaintext, nil
+}
+
+func (PlainCipher) Decrypt(nonce []byte, ciphertext []byte) ([]byte, error) {
+	return ciphertext, nil
+}
+
+// EncryptEnvelope returns nil since the plain calldata has no envelope.
+func (PlainCipher) EncryptEnvelope(plaintext []byte) *EncryptedBodyEnvelope {
+	return nil
+}
+
+func (PlainCipher) EncryptEncode(plaintext []byte) []byte {
+	return plaintext
+}
+
+func (PlainCipher) DecryptEncoded(result []byte) ([]byte, error) {
+	return result, nil
+}
+
+func (PlainCipher) DecryptCallResult(result []byte) ([]byte, error) {
+	return result, nil
+}
+
 // X25519DeoxysIICipher is the default cipher that does what it says on the tin.
 type X25519DeoxysIICipher struct {
-	cipher  cipher.AEAD
-	keypair Curve25519KeyPair
+	cipher        cipher.AEAD
+	keypair       Curve25519KeyPair
+	peerPublicKey [curve25519.PointSize]byte
 }
 
 type Curve25519KeyPair struct {
@@ -131,8 +176,9 @@ func NewX25519DeoxysIICipher(keypair Curve25519KeyPair, peerPublicKey [curve2551
 	}
 
 	return &X25519DeoxysIICipher{
-		cipher:  cipher,
-		keypair: keypair,
+		cipher:        cipher,
+		keypair:       keypair,
+		peerPublicKey: peerPublicKey,
 	}, nil
 }
 
@@ -183,13 +229,17 @@ func (c X25519DeoxysIICipher) EncryptEncode(plaintext []byte) []byte {
 	return cbor.Marshal(envelope)
 }
 
+// DecryptCallResult returns the decrypted result of a successful call. Failed
+// calls return a *CallError while malformed results return an error wrapping
+// ErrCallResultDecode.
 func (c X25519DeoxysIICipher) DecryptCallResult(response []byte) ([]byte, error) {
 	var callResult CallResult
-	cbor.MustUnmarshal(response, &callResult)
+	if err := cbor.Unmarshal(response, &callResult); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCallResultDecode, err)
+	}
 
-	// TODO: actually decode and return failure
 	if callResult.Fail != nil {
-		return nil, ErrCallFailed
+		return nil, newCallError(callResult.Fail)
 	}
 
 	var aeadEnvelope AeadEnvelope
@@ -205,38 +255,32 @@ func (c X25519DeoxysIICipher) DecryptCallResult(response []byte) ([]byte, error)
 
 	decrypted, err := c.Decrypt(aeadEnvelope.Nonce, aeadEnvelope.Data)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrCallResultDecrypt, err)
 	}
 
 	var innerResult Inner
-	cbor.MustUnmarshal(decrypted, &innerResult)
+	if err = cbor.Unmarshal(decrypted, &innerResult); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCallResultDecode, err)
+	}
 
 	if innerResult.OK != nil {
 		return innerResult.OK, nil
 	}
 
 	if innerResult.Fail != nil {
-		msg := innerResult.Fail.Message
-		if len(msg) == 0 {
-			msg = fmt.Sprintf("Call failed in module %s with code %d", innerResult.Fail.Module, innerResult.Fail.Code)
-		}
-		return nil, errors.New(msg)
+		return nil, newCallError(innerResult.Fail)
 	}
 
-	return nil, errors.New("Unexpected inner call result:" + string(callResult.Unknown.Data))
+	return nil, fmt.Errorf("%w: unexpected inner call result", ErrCallResultDecode)
 }
 
 func (c X25519DeoxysIICipher) DecryptEncoded(response []byte) ([]byte, error) {
 	return c.DecryptCallResult(response)
 }
 
-// getRuntimePublicKey fetches the runtime calldata public key from the default Sapphire gateway.
-func getRuntimePublicKey(ctx context.Context, net utils.NetworkType) ([]byte, error) {
-	network, err := utils.GetNetworkConfig(net)
-	if err != nil {
-		return nil, err
-	}
-
+// fetchRuntimePublicKey fetches the runtime calldata public key from the
+// network's default Sapphire gateway.
+func fetchRuntimePublicKey(ctx context.Context, network *utils.NetworkParams) (*CallDataPublicKey, error) {
 	request := Request{
 		Version: "2.0",
 		Method:  "oasis_callDataPublicKey",
@@ -255,21 +299,22 @@ func getRuntimePublicKey(ctx context.Context, net utils.NetworkType) ([]byte, er
 	if err != nil {
 		return nil, fmt.Errorf("failed to request runtime calldata public key: %w", err)
 	}
+	defer res.Body.Close()
 
 	decoder := json.NewDecoder(res.Body)
 	rpcRes := new(Response)
 	if err := decoder.Decode(&rpcRes); err != nil {
 		return nil, fmt.Errorf("unexpected response to request for runtime calldata public key: %w", err)
 	}
-	res.Body.Close()
+
+	if rpcRes.Error != nil {
+		return nil, fmt.Errorf("runtime calldata public key request failed: %s", rpcRes.Error.Message)
+	}
 
 	var pubKey CallDataPublicKey
 	if err := json.Unmarshal(rpcRes.Result, &pubKey); err != nil {
 		return nil, fmt.Errorf("invalid response when fetching runtime calldata public key: %w", err)
 	}
-	if len(pubKey.PublicKey) != 32 {
-		return nil, fmt.Errorf("invalid public key length")
-	}
 
-	return pubKey.PublicKey, nil
+	return &pubKey, nil
 }
@@ -0,0 +1,64 @@
+package sapphire
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// mockCaller returns the same result for all the calls.
+type mockCaller struct {
+	bind.ContractBackend
+	result []byte
+	call   ethereum.CallMsg
+}
+
+func (m *mockCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	m.call = call
+	return m.result, nil
+}
+
+// TestPlainCallContract tests that the calls on the non-confidential networks
+// are sent and returned unchanged.
+func TestPlainCallContract(t *testing.T) {
+	contract := common.HexToAddress("0x3396FD816Dd81100477c8ea3853039822f36B7ed")
+	input := []byte("getBondSecureDetails")
+
+	td := []struct {
+		testName string
+		from     common.Address
+	}{
+		{"call_without_sender", common.Address{}},
+		{"call_with_unsigned_sender", sender1},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			caller := &mockCaller{result: []byte("result")}
+			b := &WrappedBackend{ContractBackend: caller, cipher: PlainCipher{}}
+
+			res, err := b.CallContract(context.Background(), ethereum.CallMsg{
+				From: v.from,
+				To:   &contract,
+				Data: input,
+			}, nil)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if !bytes.Equal(res, caller.result) {
+				t.Fatalf("expected result %q but found %q", caller.result, res)
+			}
+
+			if caller.call.From != v.from || !bytes.Equal(caller.call.Data, input) {
+				t.Fatalf("expected the call from %v with data %q but found %v with %q",
+					v.from, input, caller.call.From, caller.call.Data)
+			}
+		})
+	}
+}
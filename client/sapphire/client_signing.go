@@ -0,0 +1,205 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package sapphire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ErrSignerMismatch is returned if the client signed payload was not signed by
+// the expected sender.
+var ErrSignerMismatch = errors.New("payload not signed by the sender")
+
+// UnsignedCall defines the EIP-712 signed call payload built for a client to
+// sign with their own private key. The call is made at BlockNumber once the
+// client sends its signature back.
+type UnsignedCall struct {
+	TypedData   apitypes.TypedData `json:"typed_data"`
+	Digest      common.Hash        `json:"digest"`
+	BlockNumber uint64             `json:"block_number"`
+
+	data  []byte
+	leash Leash
+}
+
+// ChainID returns the chain ID of the wrapped network.
+func (b *WrappedBackend) ChainID() *big.Int {
+	return new(big.Int).Set(&b.chainID)
+}
+
+// txSigner returns the signer of the transactions on the wrapped network.
+func (b *WrappedBackend) txSigner() types.Signer {
+	return types.LatestSignerForChainID(&b.chainID)
+}
+
+// encryptData encrypts the transaction data. Plain ciphers leave the data
+// unencrypted.
+func (b *WrappedBackend) encryptData(data []byte) []byte {
+	return b.cipher.EncryptEncode(data)
+}
+
+// UnsignedTx returns the unsigned transaction sending the encrypted input to
+// the contract, and the hash the sender must sign for it to be accepted. Its
+// nonce is the sender's next one in the nonce manager, which only consumes it
+// once the signed transaction is broadcasted.
+func (b *WrappedBackend) UnsignedTx(ctx context.Context, from, contract common.Address,
+	input []byte,
+) (*types.Transaction, common.Hash, error) {
+	nonce, err := b.nonces.Next(ctx, from)
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("failed to fetch the sender nonce: %w", err)
+	}
+
+	fees, err := b.SuggestFees(ctx)
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("failed to fetch the tx fees: %w", err)
+	}
+
+	// The sender's key is unknown, the encrypted call is estimated without a
+	// from address. Calls restricted to the sender can't be estimated that
+	// way and fallback to the gas ceiling.
+	gas, err := b.EstimateGas(ctx, ethereum.CallMsg{To: &contract, Data: input})
+	if err != nil {
+		log.Debugf("estimating the unsigned tx gas failed: %v", err)
+		gas = b.gas.fallbackGasLimit()
+	}
+
+	tx := b.newTx(fees, nonce, gas, &contract, new(big.Int), b.encryptData(input))
+
+	return tx, b.txSigner().Hash(tx), nil
+}
+
+// WithClientSignature returns the unsigned transaction with the client's
+// signature in the [R || S || V] format attached. An error is returned if
+// the transaction was not signed by the sender.
+func (b *WrappedBackend) WithClientSignature(tx *types.Transaction, signature []byte,
+	from common.Address,
+) (*types.Transaction, error) {
+	signer := b.txSigner()
+
+	signedTx, err := tx.WithSignature(signer, normalizeRecoveryID(signature))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tx signature: %w", err)
+	}
+
+	sender, err := types.Sender(signer, signedTx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover the tx sender: %w", err)
+	}
+
+	if sender != from {
+		return nil, ErrSignerMismatch
+	}
+	return signedTx, nil
+}
+
+// SignableCall returns the EIP-712 signed call payload of the call made from
+// call.From at the provided block number, or the latest if not set.
+func (b *WrappedBackend) SignableCall(ctx context.Context, call ethereum.CallMsg,
+	blockNumber *big.Int,
+) (*UnsignedCall, error) {
+	leash, err := b.newLeash(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var callee []byte
+	if call.To != nil {
+		callee = call.To[:]
+	}
+
+	typedData := makeSignableCall(b.chainID.Uint64(), call.From[:], callee,
+		DefaultGasLimit, call.GasPrice, call.Value, call.Data, leash)
+
+	digest, err := typedDataDigest(typedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UnsignedCall{
+		TypedData:   typedData,
+		Digest:      digest,
+		BlockNumber: leash.BlockNumber + 1,
+		data:        call.Data,
+		leash:       leash,
+	}, nil
+}
+
+// VerifySigner confirms that the signature of the call was made by the sender.
+func (c *UnsignedCall) VerifySigner(signature []byte, from common.Address) error {
+	pubkey, err := crypto.SigToPub(c.Digest[:], normalizeRecoveryID(signature))
+	if err != nil {
+		return fmt.Errorf("invalid call signature: %w", err)
+	}
+
+	if crypto.PubkeyToAddress(*pubkey) != from {
+		return ErrSignerMismatch
+	}
+	return nil
+}
+
+// EncryptEncode returns the signed call data to be sent to Sapphire.
+func (c *UnsignedCall) EncryptEncode(cipher Cipher, signature []byte) []byte {
+	dataPack := SignedCallDataPack{
+		Data:      Data{Body: c.data},
+		Leash:     c.leash,
+		Signature: signature,
+	}
+	return dataPack.EncryptEncode(cipher)
+}
+
+// newLeash returns the leash binding a signed call to the block preceding the
+// provided block number, or the latest block if not set.
+func (b *WrappedBackend) newLeash(ctx context.Context, blockNumber *big.Int) (Leash, error) {
+	leashBlockNumber := big.NewInt(0)
+	if blockNumber != nil {
+		leashBlockNumber.Sub(blockNumber, big.NewInt(1))
+	} else {
+		latestHeader, err := b.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return Leash{}, fmt.Errorf("failed to fetch latest block number: %w", err)
+		}
+		leashBlockNumber.Sub(latestHeader.Number, big.NewInt(1))
+	}
+
+	header, err := b.HeaderByNumber(ctx, leashBlockNumber)
+	if err != nil {
+		return Leash{}, fmt.Errorf("failed to fetch leash block header: %w", err)
+	}
+
+	blockHash := header.Hash()
+	return NewLeash(header.Nonce.Uint64(), header.Number.Uint64(), blockHash[:], DefaultBlockRange), nil
+}
+
+// normalizeRecoveryID returns a copy of the signature with the high recovery
+// ID used by the eth wallets converted back to 0 or 1.
+func normalizeRecoveryID(signature []byte) []byte {
+	sig := make([]byte, len(signature))
+	copy(sig, signature)
+	if len(sig) == crypto.SignatureLength && sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	return sig
+}
+
+// highRecoveryID returns a copy of the signature with its recovery ID set to
+// the high 27 or 28 form the signed calls are sent with, as done by the eth
+// wallets.
+func highRecoveryID(signature []byte) []byte {
+	sig := make([]byte, len(signature))
+	copy(sig, signature)
+	if len(sig) == crypto.SignatureLength && sig[64] < 27 {
+		sig[64] += 27
+	}
+	return sig
+}
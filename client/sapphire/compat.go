@@ -29,37 +29,50 @@ type WrappedBackend struct {
 	cipher     Cipher
 	signerFunc SignerFn
 	ctx        context.Context
+	nonces     *NonceManager
+	gas        gasPolicy
 
-	privateKey []byte
-	noSend     bool // Used for running tests on a mocked wrapper instance.
+	noSend bool // Used for running tests on a mocked wrapper instance.
 }
 
+// signingKeyCtxKey is the context key holding the private key used to sign
+// the calls made with that context.
+type signingKeyCtxKey struct{}
+
+// clientSignatureCtxKey is the context key holding the client's signature of
+// the call made with that context.
+type clientSignatureCtxKey struct{}
+
 // Confirm that WrappedBacked implements the bind.ContractBackend interface.
 var _ bind.ContractBackend = (*WrappedBackend)(nil)
 
 // SignerFn is a function that produces secp256k1 signatures in RSV format.
 type SignerFn = func(digest [32]byte, privateKey []byte) ([]byte, error)
 
-// NewCipher creates a default cipher.
+// NewCipher creates a default cipher. The network's runtime public key is
+// verified against its key manager public key before being used, and is
+// refreshed in the background until the context is cancelled.
 func NewCipher(ctx context.Context, net utils.NetworkType) (Cipher, error) {
-	runtimePublicKey, err := getRuntimePublicKey(ctx, net)
+	network, err := utils.GetNetworkConfig(net)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch runtime calldata public key: %w", err)
+		return nil, err
 	}
 
-	keypair, err := NewCurve25519KeyPair()
+	verify, err := runtimeKeyVerifier(network)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate ephemeral keypair: %w", err)
+		return nil, err
 	}
 
-	// convert the slice to an array
-	var pubkey [32]byte
-	copy(pubkey[:], runtimePublicKey)
+	fetch := func(ctx context.Context) (*CallDataPublicKey, error) {
+		return fetchRuntimePublicKey(ctx, network)
+	}
 
-	cipher, err := NewX25519DeoxysIICipher(*keypair, pubkey)
+	cipher, err := newRotatingCipher(ctx, fetch, verify)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create default cipher: %w", err)
+		return nil, err
 	}
+
+	go cipher.run(ctx)
 	return cipher, nil
 }
 
@@ -73,10 +86,10 @@ func WrapClient(ctx context.Context, c bind.ContractBackend, net utils.NetworkTy
 	// Check if current network is set to unit tests
 	noSend := network.Name == utils.UnitTestNet
 
-	var cipher Cipher
-	if !noSend {
-		// if mocked instance running, cipher not required because
-		// no network calls will be made.
+	var cipher Cipher = PlainCipher{}
+	if !noSend && !network.PlainCalls {
+		// The mocked instance and the non-confidential networks calls are
+		// sent unencrypted.
 		cipher, err = NewCipher(ctx, net)
 		if err != nil {
 			return nil, err
@@ -88,41 +101,93 @@ func WrapClient(ctx context.Context, c bind.ContractBackend, net utils.NetworkTy
 		chainID:         network.ChainID,
 		cipher:          cipher,
 		signerFunc:      sign,
+		nonces:          NewNonceManager(c),
+		gas:             newGasPolicy(network),
 		noSend:          noSend,
 	}, nil
 }
 
-func (b *WrappedBackend) SetClientSigningKey(privateKey []byte) {
-	// Set the private key
-	b.privateKey = privateKey
+// Nonces returns the nonce manager handing out the senders nonces.
+func (b *WrappedBackend) Nonces() *NonceManager {
+	return b.nonces
 }
 
-// Transactor returns a TransactOpts that can be used with Sapphire.
-func (b *WrappedBackend) Transactor(from common.Address) *bind.TransactOpts {
-	signer := types.LatestSignerForChainID(&b.chainID)
-	signFn := func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
-		if b.noSend {
-			// ignore all tx signing since this will not be sent.
-			return tx, nil
+// SubmitTx submits the transaction built by the submit function using the
+// sender's next nonce from the nonce manager. Submissions of the same sender
+// are serialised. If no fees are set, the ones suggested by the fee oracle
+// are used.
+func (b *WrappedBackend) SubmitTx(ctx context.Context, opts *bind.TransactOpts,
+	submit func(opts *bind.TransactOpts) (*types.Transaction, error),
+) (*types.Transaction, error) {
+	if opts.Context != nil {
+		// Keep the signing key used to estimate the transaction gas.
+		if privateKey, ok := signingKey(opts.Context); ok {
+			ctx = WithSigningKey(ctx, privateKey)
+		}
+	}
+
+	fees := TxFees{GasPrice: opts.GasPrice, GasFeeCap: opts.GasFeeCap, GasTipCap: opts.GasTipCap}
+	if fees.GasPrice == nil && !fees.IsDynamic() {
+		var err error
+		fees, err = b.SuggestFees(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the tx fees: %w", err)
 		}
+	}
 
+	nonce, release, err := b.nonces.Acquire(ctx, opts.From)
+	if err != nil {
+		return nil, err
+	}
+
+	withNonce := *opts
+	withNonce.Nonce = new(big.Int).SetUint64(nonce)
+	withNonce.GasPrice = fees.GasPrice
+	withNonce.GasFeeCap = fees.GasFeeCap
+	withNonce.GasTipCap = fees.GasTipCap
+	withNonce.Context = ctx
+
+	tx, err := submit(&withNonce)
+	release(err)
+	return tx, err
+}
+
+// WithSigningKey returns a copy of the parent context bound to the private key
+// that signs the contract calls made with it.
+func WithSigningKey(parent context.Context, privateKey []byte) context.Context {
+	return context.WithValue(parent, signingKeyCtxKey{}, privateKey)
+}
+
+// signingKey returns the private key bound to the context if any.
+func signingKey(ctx context.Context) ([]byte, bool) {
+	privateKey, ok := ctx.Value(signingKeyCtxKey{}).([]byte)
+	return privateKey, ok && len(privateKey) > 0
+}
+
+// clientSignature returns the client's call signature bound to the context if
+// any.
+func clientSignature(ctx context.Context) ([]byte, bool) {
+	signature, ok := ctx.Value(clientSignatureCtxKey{}).([]byte)
+	return signature, ok && len(signature) > 0
+}
+
+// Transactor returns a TransactOpts that can be used with Sapphire. The
+// transactions are signed with the provided private key only and their gas
+// is estimated as a call signed with the same key, made with the provided
+// context. Both the legacy and the dynamic fee transactions are supported.
+func (b *WrappedBackend) Transactor(ctx context.Context, from common.Address, privateKey []byte) *bind.TransactOpts {
+	signer := b.txSigner()
+	signFn := func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
 		if addr != from {
 			return nil, bind.ErrNotAuthorized
 		}
 
-		packedTx := types.NewTx(&types.LegacyTx{
-			Nonce:    tx.Nonce(),
-			GasPrice: tx.GasPrice(),
-			Gas:      tx.Gas(),
-			To:       tx.To(),
-			Value:    tx.Value(),
-			Data:     b.cipher.EncryptEncode(tx.Data()),
-		})
+		packedTx := b.encryptTx(tx)
 
 		var signedTxBytes [32]byte
 		copy(signedTxBytes[:], signer.Hash(packedTx).Bytes())
 
-		sig, err := b.signerFunc(signedTxBytes, b.privateKey)
+		sig, err := b.signerFunc(signedTxBytes, privateKey)
 		if err != nil {
 			return nil, fmt.Errorf("failed to sign tx: %w", err)
 		}
@@ -130,63 +195,101 @@ func (b *WrappedBackend) Transactor(from common.Address) *bind.TransactOpts {
 		return packedTx.WithSignature(signer, sig)
 	}
 
-	return &bind.TransactOpts{
-		From:     from,
-		Signer:   signFn,
-		GasPrice: big.NewInt(DefaultGasPrice),
-		GasLimit: DefaultGasLimit,
-		NoSend:   b.noSend,
+	opts := &bind.TransactOpts{
+		From:    from,
+		Signer:  signFn,
+		Context: WithSigningKey(ctx, privateKey),
+		NoSend:  b.noSend,
+	}
+
+	if b.noSend {
+		// The mocked instance has no contract code to estimate the gas against.
+		opts.GasPrice = big.NewInt(DefaultGasPrice)
+		opts.GasLimit = DefaultGasLimit
+	}
+	return opts
+}
+
+// CallOpts returns a CallOpts whose calls are signed with the provided private
+// key only.
+func (b *WrappedBackend) CallOpts(ctx context.Context, from common.Address, privateKey []byte) *bind.CallOpts {
+	return &bind.CallOpts{
+		From:    from,
+		Context: WithSigningKey(ctx, privateKey),
+	}
+}
+
+// ClientSignedCallOpts returns a CallOpts whose call at the provided block
+// number is authenticated by the client's signature of its signed call
+// payload, built by SignableCall at the same block number. The signature is
+// sent with a high recovery ID whichever form the client signed with.
+func (b *WrappedBackend) ClientSignedCallOpts(ctx context.Context, from common.Address,
+	blockNumber *big.Int, signature []byte,
+) *bind.CallOpts {
+	return &bind.CallOpts{
+		From:        from,
+		BlockNumber: blockNumber,
+		Context:     context.WithValue(ctx, clientSignatureCtxKey{}, highRecoveryID(signature)),
 	}
 }
 
 // CallContract executes a Sapphire paratime contract call with the specified
-// data as the input. CallContract implements ContractCaller.
+// data as the input. Calls with a from address are signed with the private key
+// bound to the context. Non-confidential networks trust the from address, so
+// their calls are neither signed nor encrypted. CallContract implements
+// ContractCaller.
 func (b *WrappedBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
-	var err error
-	var packedCall *ethereum.CallMsg
+	packedCall := call
 
-	if call.From == [common.AddressLength]byte{} {
+	switch {
+	case b.isPlain():
+		// The call is sent as is.
+
+	case call.From == [common.AddressLength]byte{}:
 		// prepares call.Data for being sent to Sapphire. The call will be
 		// end-to-end encrypted, but the `from` address will be zero.
 		packedCall.Data = b.cipher.EncryptEncode(call.Data)
-	} else {
-		leashBlockNumber := big.NewInt(0)
-		if blockNumber != nil {
-			leashBlockNumber.Sub(blockNumber, big.NewInt(1))
-		} else {
-			latestHeader, err := b.HeaderByNumber(ctx, nil)
-			if err != nil {
-				return nil, fmt.Errorf("failed to fetch latest block number: %w", err)
-			}
-			leashBlockNumber.Sub(latestHeader.Number, big.NewInt(1))
+
+	default:
+		privateKey, hasKey := signingKey(ctx)
+		signature, hasSignature := clientSignature(ctx)
+		if !hasKey && !hasSignature {
+			return nil, fmt.Errorf("no signing key bound to the call from %v", call.From)
 		}
 
-		header, err := b.HeaderByNumber(ctx, leashBlockNumber)
+		unsignedCall, err := b.SignableCall(ctx, call, blockNumber)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch leash block header: %w", err)
+			return nil, err
 		}
 
-		blockHash := header.Hash()
-		leash := NewLeash(header.Nonce.Uint64(), header.Number.Uint64(), blockHash[:], DefaultBlockRange)
-
-		// prepares call.Data in-place for being sent to Sapphire. The call will be
+		// prepares call.Data for being sent to Sapphire. The call will be
 		// end-to-end encrypted and a signature will be used to authenticate the `from` address.
-		dataPack, err := NewDataPack(b.signerFunc, b.privateKey, b.chainID.Uint64(), call.From[:],
-			call.To[:], DefaultGasLimit, call.GasPrice, call.Value, call.Data, leash)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create signed call data back: %w", err)
+		if hasSignature {
+			// The client signed the call payload returned to it earlier.
+			if err = unsignedCall.VerifySigner(signature, call.From); err != nil {
+				return nil, err
+			}
+		} else {
+			signature, err = signTypedData(b.signerFunc, privateKey, unsignedCall.TypedData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create signed call data back: %w", err)
+			}
 		}
-		packedCall.Data = dataPack.EncryptEncode(b.cipher)
+
+		// The signed call gas limit must match the one that was signed.
+		packedCall.Gas = DefaultGasLimit
+		packedCall.Data = unsignedCall.EncryptEncode(b.cipher, signature)
 	}
 
-	res, err := b.ContractBackend.CallContract(ctx, *packedCall, blockNumber)
+	res, err := b.ContractBackend.CallContract(ctx, packedCall, blockNumber)
 	if err != nil {
-		return nil, err
+		return nil, nodeCallError(err)
 	}
 	return b.cipher.DecryptEncoded(res)
 }
 
-// EstimateGas implements ContractTransactor.
-func (b *WrappedBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (gas uint64, err error) {
-	return DefaultGasLimit, nil
+// isPlain returns true if the calls and the transactions data are sent
+// unencrypted.
+func (b *WrappedBackend) isPlain() bool {
+	return b.cipher.Kind() == Plain
 }
@@ -0,0 +1,121 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package sapphire
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// evmModule is the Sapphire runtime module executing the contracts.
+	evmModule = "evm"
+
+	// evmRevertedCode is the evm module error code of the reverted calls.
+	evmRevertedCode = 8
+
+	// revertedPrefix prefixes the base64 encoded revert data in the evm
+	// module failure messages.
+	revertedPrefix = "reverted: "
+)
+
+// CallError defines a failed Sapphire call. It holds the runtime module and
+// the error code the call failed with, and the Solidity revert reason if the
+// contract reverted it.
+type CallError struct {
+	Module  string
+	Code    uint64
+	Message string
+	Reason  string
+}
+
+// Error implements the error interface.
+func (e *CallError) Error() string {
+	switch {
+	case e.Reason != "":
+		return "execution reverted: " + e.Reason
+	case e.Message != "":
+		return fmt.Sprintf("call failed in module %s with code %d: %s", e.Module, e.Code, e.Message)
+	default:
+		return fmt.Sprintf("call failed in module %s with code %d", e.Module, e.Code)
+	}
+}
+
+// Unwrap allows the call errors to match ErrCallFailed.
+func (e *CallError) Unwrap() error {
+	return ErrCallFailed
+}
+
+// IsReverted returns true if the contract reverted the call.
+func (e *CallError) IsReverted() bool {
+	return e.Reason != "" || (e.Module == evmModule && e.Code == evmRevertedCode)
+}
+
+// newCallError returns the call error of the failure with the revert reason
+// decoded if the contract reverted the call.
+func newCallError(f *Failure) *CallError {
+	callErr := &CallError{
+		Module:  f.Module,
+		Code:    f.Code,
+		Message: f.Message,
+	}
+
+	if f.Module == evmModule && f.Code == evmRevertedCode {
+		callErr.Reason = decodeRevertMessage(f.Message)
+	}
+	return callErr
+}
+
+// decodeRevertMessage returns the revert reason in the evm module failure
+// message. The revert data is expected to be base64 encoded, plain text
+// messages are returned as is.
+func decodeRevertMessage(msg string) string {
+	data := strings.TrimPrefix(msg, revertedPrefix)
+
+	revertData, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return data
+	}
+
+	// Reverts without an Error(string) reason have no reason to return.
+	reason, _ := abi.UnpackRevert(revertData)
+	return reason
+}
+
+// nodeCallError returns the node error as a call error if it carries the ABI
+// encoded revert data of a reverted call, otherwise the error is returned as is.
+func nodeCallError(err error) error {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return err
+	}
+
+	data, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return err
+	}
+
+	revertData, decodeErr := hexutil.Decode(data)
+	if decodeErr != nil {
+		return err
+	}
+
+	reason, unpackErr := abi.UnpackRevert(revertData)
+	if unpackErr != nil {
+		return err
+	}
+
+	return &CallError{
+		Module:  evmModule,
+		Code:    evmRevertedCode,
+		Message: err.Error(),
+		Reason:  reason,
+	}
+}
@@ -0,0 +1,119 @@
+package sapphire
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+// packRevert returns the revert data of a require statement failing with the
+// provided reason.
+func packRevert(t *testing.T, reason string) []byte {
+	stringType, _ := abi.NewType("string", "", nil)
+	data, err := abi.Arguments{{Type: stringType}}.Pack(reason)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	// Error(string) function selector.
+	return append([]byte{0x08, 0xc3, 0x79, 0xa0}, data...)
+}
+
+// TestDecryptCallResult tests the decoding of the failed and malformed call
+// results.
+func TestDecryptCallResult(t *testing.T) {
+	keypair, err := NewCurve25519KeyPair()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	cipher, err := NewX25519DeoxysIICipher(*keypair, keypair.PublicKey)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	// encrypted returns the call result holding the encrypted inner result.
+	encrypted := func(inner Inner) []byte {
+		data, nonce := cipher.Encrypt(cbor.Marshal(inner))
+		return cbor.Marshal(CallResult{Unknown: &AeadEnvelope{Nonce: nonce, Data: data}})
+	}
+
+	revertMsg := revertedPrefix + base64.StdEncoding.EncodeToString(
+		packRevert(t, "Edits disabled on finalized Bond"))
+
+	td := []struct {
+		testName string
+		response []byte
+		result   []byte
+		err      error
+		callErr  *CallError
+	}{
+		{
+			"successful_call",
+			encrypted(Inner{OK: []byte("ok")}),
+			[]byte("ok"), nil, nil,
+		},
+		{
+			"malformed_call_result",
+			[]byte{0xff, 0x01},
+			nil, ErrCallResultDecode, nil,
+		},
+		{
+			"empty_call_result",
+			cbor.Marshal(CallResult{}),
+			nil, ErrCallResultDecode, nil,
+		},
+		{
+			"unencrypted_module_failure",
+			cbor.Marshal(CallResult{Fail: &Failure{Module: "core", Code: 12, Message: "out of gas"}}),
+			nil, ErrCallFailed, &CallError{Module: "core", Code: 12, Message: "out of gas"},
+		},
+		{
+			"encrypted_contract_revert",
+			encrypted(Inner{Fail: &Failure{Module: evmModule, Code: evmRevertedCode, Message: revertMsg}}),
+			nil, ErrCallFailed, &CallError{
+				Module: evmModule, Code: evmRevertedCode, Message: revertMsg,
+				Reason: "Edits disabled on finalized Bond",
+			},
+		},
+		{
+			"plain_text_revert",
+			encrypted(Inner{Fail: &Failure{Module: evmModule, Code: evmRevertedCode,
+				Message: "reverted: Only used by the bond parties"}}),
+			nil, ErrCallFailed, &CallError{
+				Module: evmModule, Code: evmRevertedCode,
+				Message: "reverted: Only used by the bond parties",
+				Reason:  "Only used by the bond parties",
+			},
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			result, err := cipher.DecryptCallResult(v.response)
+			if !errors.Is(err, v.err) {
+				t.Fatalf("expected error %v but found %v", v.err, err)
+			}
+
+			if string(result) != string(v.result) {
+				t.Fatalf("expected result %q but found %q", v.result, result)
+			}
+
+			if v.callErr == nil {
+				return
+			}
+
+			var callErr *CallError
+			if !errors.As(err, &callErr) {
+				t.Fatalf("expected a call error but found %T", err)
+			}
+
+			if *callErr != *v.callErr {
+				t.Fatalf("expected call error %+v but found %+v", v.callErr, callErr)
+			}
+		})
+	}
+}
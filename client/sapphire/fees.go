@@ -0,0 +1,116 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package sapphire
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// baseFeeMultiplier defines how many times the current base fee can be paid
+// by a dynamic fee transaction, leaving room for the base fee to increase
+// before the transaction is mined.
+const baseFeeMultiplier = 2
+
+// TxFees defines the fees set on a transaction. GasPrice is only set on the
+// legacy transactions while GasFeeCap and GasTipCap are only set on the
+// dynamic fee transactions.
+type TxFees struct {
+	GasPrice  *big.Int
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+}
+
+// IsDynamic returns true if the fees are set for a dynamic fee transaction.
+func (f TxFees) IsDynamic() bool {
+	return f.GasFeeCap != nil && f.GasTipCap != nil
+}
+
+// SuggestFees is the fee oracle of the transactions submitted. If the network
+// advertises a base fee, the dynamic fee caps are suggested otherwise the
+// legacy gas price is. Fees are bounded by the network's gas price bounds.
+func (b *WrappedBackend) SuggestFees(ctx context.Context) (TxFees, error) {
+	if b.noSend {
+		// The mocked instance makes no network calls.
+		return TxFees{GasPrice: big.NewInt(DefaultGasPrice)}, nil
+	}
+
+	head, err := b.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return TxFees{}, fmt.Errorf("failed to fetch the latest header: %w", err)
+	}
+
+	if head.BaseFee == nil {
+		gasPrice, err := b.SuggestGasPrice(ctx)
+		if err != nil {
+			return TxFees{}, err
+		}
+		return TxFees{GasPrice: gasPrice}, nil
+	}
+
+	tipCap, err := b.ContractBackend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return TxFees{}, fmt.Errorf("failed to fetch the gas tip cap: %w", err)
+	}
+	return b.gas.dynamicFees(head.BaseFee, tipCap), nil
+}
+
+// dynamicFees returns the dynamic fee caps paying the suggested tip on top of
+// the base fee. The fee cap is bounded by the min and max gas prices and the
+// tip never exceeds the fee cap.
+func (p gasPolicy) dynamicFees(baseFee, suggestedTip *big.Int) TxFees {
+	tipCap := new(big.Int)
+	if suggestedTip != nil {
+		tipCap.Set(suggestedTip)
+	}
+
+	feeCap := new(big.Int).Mul(baseFee, big.NewInt(baseFeeMultiplier))
+	feeCap = p.gasPrice(feeCap.Add(feeCap, tipCap))
+
+	if tipCap.Cmp(feeCap) > 0 {
+		tipCap.Set(feeCap)
+	}
+	return TxFees{GasFeeCap: feeCap, GasTipCap: tipCap}
+}
+
+// newTx returns the transaction of the encoding matching the fees set.
+func (b *WrappedBackend) newTx(fees TxFees, nonce, gas uint64, to *common.Address,
+	value *big.Int, data []byte,
+) *types.Transaction {
+	if fees.IsDynamic() {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   b.ChainID(),
+			Nonce:     nonce,
+			GasTipCap: fees.GasTipCap,
+			GasFeeCap: fees.GasFeeCap,
+			Gas:       gas,
+			To:        to,
+			Value:     value,
+			Data:      data,
+		})
+	}
+
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: fees.GasPrice,
+		Gas:      gas,
+		To:       to,
+		Value:    value,
+		Data:     data,
+	})
+}
+
+// encryptTx returns a copy of the transaction with its data encrypted. The
+// transaction encoding is preserved.
+func (b *WrappedBackend) encryptTx(tx *types.Transaction) *types.Transaction {
+	fees := TxFees{GasPrice: tx.GasPrice()}
+	if tx.Type() == types.DynamicFeeTxType {
+		fees = TxFees{GasFeeCap: tx.GasFeeCap(), GasTipCap: tx.GasTipCap()}
+	}
+	return b.newTx(fees, tx.Nonce(), tx.Gas(), tx.To(), tx.Value(), b.encryptData(tx.Data()))
+}
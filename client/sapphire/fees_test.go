@@ -0,0 +1,155 @@
+package sapphire
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mockFeeOracle returns the same header and fee suggestions for all queries.
+type mockFeeOracle struct {
+	bind.ContractBackend
+	baseFee *big.Int
+}
+
+func (m *mockFeeOracle) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(10), BaseFee: m.baseFee}, nil
+}
+
+func (m *mockFeeOracle) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(200), nil
+}
+
+func (m *mockFeeOracle) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(20), nil
+}
+
+// TestSuggestFees tests the fees suggested depending on whether the network
+// advertises a base fee.
+func TestSuggestFees(t *testing.T) {
+	td := []struct {
+		testName string
+		baseFee  *big.Int
+		expected TxFees
+	}{
+		{"legacy_gas_price", nil, TxFees{GasPrice: big.NewInt(200)}},
+		{
+			"dynamic_fee_caps", big.NewInt(100),
+			TxFees{GasFeeCap: big.NewInt(220), GasTipCap: big.NewInt(20)},
+		},
+		{
+			"fee_cap_bounded", big.NewInt(400),
+			TxFees{GasFeeCap: big.NewInt(500), GasTipCap: big.NewInt(20)},
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			b := &WrappedBackend{
+				ContractBackend: &mockFeeOracle{baseFee: v.baseFee},
+				gas:             testGasPolicy,
+			}
+
+			fees, err := b.SuggestFees(context.Background())
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if fees.IsDynamic() != v.expected.IsDynamic() {
+				t.Fatalf("expected dynamic fees to be %v but found %v",
+					v.expected.IsDynamic(), fees.IsDynamic())
+			}
+
+			for _, fee := range [][2]*big.Int{
+				{fees.GasPrice, v.expected.GasPrice},
+				{fees.GasFeeCap, v.expected.GasFeeCap},
+				{fees.GasTipCap, v.expected.GasTipCap},
+			} {
+				if (fee[0] == nil) != (fee[1] == nil) || (fee[0] != nil && fee[0].Cmp(fee[1]) != 0) {
+					t.Fatalf("expected fees %+v but found %+v", v.expected, fees)
+				}
+			}
+		})
+	}
+}
+
+// TestTransactorEncodings tests that the transactor signs both the legacy and
+// the dynamic fee transactions without changing their encoding.
+func TestTransactorEncodings(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	privateKey := []byte(hexutil.Encode(crypto.FromECDSA(key)))
+
+	b := &WrappedBackend{
+		chainID: *big.NewInt(1337),
+		cipher:  PlainCipher{},
+		signerFunc: func(digest [32]byte, _ []byte) ([]byte, error) {
+			return crypto.Sign(digest[:], key)
+		},
+		noSend: true,
+	}
+
+	to := common.HexToAddress("0x3396FD816Dd81100477c8ea3853039822f36B7ed")
+	data := []byte("createBond")
+
+	td := []struct {
+		testName string
+		fees     TxFees
+		txType   uint8
+	}{
+		{"legacy_tx", TxFees{GasPrice: big.NewInt(200)}, types.LegacyTxType},
+		{
+			"dynamic_fee_tx",
+			TxFees{GasFeeCap: big.NewInt(220), GasTipCap: big.NewInt(20)},
+			types.DynamicFeeTxType,
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			tx := b.newTx(v.fees, 3, 50_000, &to, new(big.Int), data)
+
+			signedTx, err := b.Transactor(context.Background(), from, privateKey).Signer(from, tx)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if signedTx.Type() != v.txType {
+				t.Fatalf("expected tx type %d but found %d", v.txType, signedTx.Type())
+			}
+
+			sender, err := types.Sender(b.txSigner(), signedTx)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if sender != from {
+				t.Fatalf("expected tx sender %v but found %v", from, sender)
+			}
+
+			if signedTx.Nonce() != 3 || signedTx.Gas() != 50_000 || *signedTx.To() != to {
+				t.Fatalf("expected the tx fields to be preserved")
+			}
+
+			if signedTx.GasFeeCap().Cmp(tx.GasFeeCap()) != 0 ||
+				signedTx.GasTipCap().Cmp(tx.GasTipCap()) != 0 {
+				t.Fatalf("expected fee caps %v/%v but found %v/%v", tx.GasFeeCap(),
+					tx.GasTipCap(), signedTx.GasFeeCap(), signedTx.GasTipCap())
+			}
+
+			if v.txType == types.DynamicFeeTxType && signedTx.ChainId().Cmp(big.NewInt(1337)) != 0 {
+				t.Fatalf("expected chain ID 1337 but found %v", signedTx.ChainId())
+			}
+		})
+	}
+}
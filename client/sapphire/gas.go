@@ -0,0 +1,144 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package sapphire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrGasLimitExceeded is returned if the gas estimated for a transaction is
+// above the network's gas ceiling.
+var ErrGasLimitExceeded = errors.New("estimated gas exceeds the gas ceiling")
+
+// gasPolicy defines how the gas limits and gas prices suggested by the node
+// are adjusted before being set on the transactions.
+type gasPolicy struct {
+	multiplier  float64
+	maxGasLimit uint64
+	minGasPrice *big.Int
+	maxGasPrice *big.Int
+}
+
+// newGasPolicy returns the gas policy configured on the network.
+func newGasPolicy(network *utils.NetworkParams) gasPolicy {
+	return gasPolicy{
+		multiplier:  network.GasMultiplier,
+		maxGasLimit: network.MaxGasLimit,
+		minGasPrice: new(big.Int).Set(&network.MinGasPrice),
+		maxGasPrice: new(big.Int).Set(&network.MaxGasPrice),
+	}
+}
+
+// gasLimit returns the estimated gas with the safety multiplier applied and
+// capped at the gas ceiling. An estimate already above the ceiling returns
+// an error since the transaction would run out of gas.
+func (p gasPolicy) gasLimit(estimate uint64) (uint64, error) {
+	if p.maxGasLimit > 0 && estimate > p.maxGasLimit {
+		return 0, fmt.Errorf("%w: %d > %d", ErrGasLimitExceeded, estimate, p.maxGasLimit)
+	}
+
+	gas := estimate
+	if p.multiplier > 1 {
+		gas = uint64(math.Ceil(float64(estimate) * p.multiplier))
+	}
+
+	if p.maxGasLimit > 0 && gas > p.maxGasLimit {
+		gas = p.maxGasLimit
+	}
+	return gas, nil
+}
+
+// fallbackGasLimit returns the gas limit set when the gas can't be estimated.
+func (p gasPolicy) fallbackGasLimit() uint64 {
+	if p.maxGasLimit > 0 {
+		return p.maxGasLimit
+	}
+	return DefaultGasLimit
+}
+
+// gasPrice returns the suggested gas price bounded by the min and max gas
+// prices. Unset bounds are ignored.
+func (p gasPolicy) gasPrice(suggested *big.Int) *big.Int {
+	price := new(big.Int)
+	if suggested != nil {
+		price.Set(suggested)
+	}
+
+	if p.minGasPrice.Sign() > 0 && price.Cmp(p.minGasPrice) < 0 {
+		price.Set(p.minGasPrice)
+	}
+	if p.maxGasPrice.Sign() > 0 && price.Cmp(p.maxGasPrice) > 0 {
+		price.Set(p.maxGasPrice)
+	}
+	return price
+}
+
+// EstimateGas estimates the gas needed by the call through the node with the
+// network's gas policy applied. Calls with a from address bound to a signing
+// key in the context are estimated as signed calls, otherwise the encrypted
+// call is estimated with a zero from address. Non-confidential networks
+// estimate the call as is. EstimateGas implements ContractTransactor.
+func (b *WrappedBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	if b.noSend {
+		// The mocked instance makes no network calls.
+		return DefaultGasLimit, nil
+	}
+
+	packedCall := call
+	privateKey, ok := signingKey(ctx)
+
+	switch {
+	case b.isPlain():
+		// Non-confidential networks trust the from address.
+
+	case call.From != (common.Address{}) && ok:
+		unsignedCall, err := b.SignableCall(ctx, call, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		signature, err := signTypedData(b.signerFunc, privateKey, unsignedCall.TypedData)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create signed call data back: %w", err)
+		}
+
+		// The signed call gas limit must match the one that was signed.
+		packedCall.Gas = DefaultGasLimit
+		packedCall.Data = unsignedCall.EncryptEncode(b.cipher, signature)
+
+	default:
+		packedCall.From = common.Address{}
+		packedCall.Data = b.cipher.EncryptEncode(call.Data)
+	}
+
+	estimate, err := b.ContractBackend.EstimateGas(ctx, packedCall)
+	if err != nil {
+		return 0, nodeCallError(err)
+	}
+	return b.gas.gasLimit(estimate)
+}
+
+// SuggestGasPrice returns the gas price suggested by the node bounded by the
+// network's min and max gas prices. SuggestGasPrice implements
+// ContractTransactor.
+func (b *WrappedBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if b.noSend {
+		// The mocked instance makes no network calls.
+		return big.NewInt(DefaultGasPrice), nil
+	}
+
+	price, err := b.ContractBackend.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.gas.gasPrice(price), nil
+}
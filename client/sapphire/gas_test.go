@@ -0,0 +1,131 @@
+package sapphire
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// mockEstimator returns the same gas estimate for all the calls.
+type mockEstimator struct {
+	bind.ContractBackend
+	estimate uint64
+	call     ethereum.CallMsg
+}
+
+func (m *mockEstimator) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	m.call = call
+	return m.estimate, nil
+}
+
+var testGasPolicy = gasPolicy{
+	multiplier:  1.2,
+	maxGasLimit: 1_000_000,
+	minGasPrice: big.NewInt(100),
+	maxGasPrice: big.NewInt(500),
+}
+
+// TestGasLimit tests the gas policy applied on the node's gas estimates.
+func TestGasLimit(t *testing.T) {
+	td := []struct {
+		testName string
+		policy   gasPolicy
+		estimate uint64
+		gas      uint64
+		err      error
+	}{
+		{"safety_margin_added", testGasPolicy, 50_000, 60_000, nil},
+		{"capped_at_ceiling", testGasPolicy, 900_000, 1_000_000, nil},
+		{"estimate_above_ceiling", testGasPolicy, 1_000_001, 0, ErrGasLimitExceeded},
+		{"no_policy_set", gasPolicy{}, 50_000, 50_000, nil},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			gas, err := v.policy.gasLimit(v.estimate)
+			if !errors.Is(err, v.err) {
+				t.Fatalf("expected error %v but found %v", v.err, err)
+			}
+
+			if gas != v.gas {
+				t.Fatalf("expected gas limit %d but found %d", v.gas, gas)
+			}
+		})
+	}
+}
+
+// TestGasPrice tests the bounds applied on the node's suggested gas prices.
+func TestGasPrice(t *testing.T) {
+	td := []struct {
+		testName  string
+		policy    gasPolicy
+		suggested *big.Int
+		price     *big.Int
+	}{
+		{"within_bounds", testGasPolicy, big.NewInt(200), big.NewInt(200)},
+		{"below_min_price", testGasPolicy, big.NewInt(10), big.NewInt(100)},
+		{"above_max_price", testGasPolicy, big.NewInt(1000), big.NewInt(500)},
+		{"no_suggestion", testGasPolicy, nil, big.NewInt(100)},
+		{"no_bounds_set", gasPolicy{minGasPrice: new(big.Int), maxGasPrice: new(big.Int)},
+			big.NewInt(1000), big.NewInt(1000)},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			if price := v.policy.gasPrice(v.suggested); price.Cmp(v.price) != 0 {
+				t.Fatalf("expected gas price %v but found %v", v.price, price)
+			}
+		})
+	}
+}
+
+// TestEstimateGasUnsignedCall tests that the calls without a signing key are
+// estimated encrypted and with a zero from address.
+func TestEstimateGasUnsignedCall(t *testing.T) {
+	keypair, err := NewCurve25519KeyPair()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	cipher, err := NewX25519DeoxysIICipher(*keypair, keypair.PublicKey)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	estimator := &mockEstimator{estimate: 50_000}
+	b := &WrappedBackend{
+		ContractBackend: estimator,
+		cipher:          cipher,
+		gas:             testGasPolicy,
+	}
+
+	contract := common.HexToAddress("0x3396FD816Dd81100477c8ea3853039822f36B7ed")
+	input := []byte("createBond")
+
+	gas, err := b.EstimateGas(context.Background(), ethereum.CallMsg{
+		From: sender1,
+		To:   &contract,
+		Data: input,
+	})
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if gas != 60_000 {
+		t.Fatalf("expected gas limit %d but found %d", 60_000, gas)
+	}
+
+	if estimator.call.From != (common.Address{}) {
+		t.Fatalf("expected a zero from address but found %v", estimator.call.From)
+	}
+
+	if len(estimator.call.Data) == 0 || bytes.Contains(estimator.call.Data, input) {
+		t.Fatal("expected the estimated call data to be encrypted")
+	}
+}
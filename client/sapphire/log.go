@@ -5,7 +5,8 @@ package sapphire
 
 import "github.com/btcsuite/btclog"
 
-var log btclog.Logger
+// log is disabled until the caller sets the logger to use.
+var log = btclog.Disabled
 
 // UseLogger sets the subsystem logs to use the provided loggers.
 func UseLogger(sLogger btclog.Logger) {
@@ -0,0 +1,216 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package sapphire
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// nonceResyncInterval defines how long a sender can stay idle before their
+// next nonce is seeded from the chain again.
+const nonceResyncInterval = 5 * time.Minute
+
+// nonceErrs are the node errors returned when a transaction nonce isn't the
+// one the chain expects from the sender.
+var nonceErrs = []string{
+	"nonce too low",
+	"nonce too high",
+	"invalid nonce",
+	"already known",
+	"replacement transaction underpriced",
+}
+
+// NonceSource defines the method used to seed the senders nonces from the chain.
+type NonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceManager hands out the senders transaction nonces locally. Submissions
+// of the same sender are serialised while the different senders submit their
+// transactions in parallel. The senders idle for long are evicted since their
+// nonce is seeded from the chain again anyway.
+type NonceManager struct {
+	source NonceSource
+
+	mtx       sync.Mutex
+	senders   map[common.Address]*senderNonce
+	lastSweep time.Time
+}
+
+// senderNonce holds the next nonce of a single sender.
+type senderNonce struct {
+	// mtx serialises the sender's submissions.
+	mtx      sync.Mutex
+	seeded   bool
+	next     uint64
+	lastUsed time.Time
+
+	// refs counts the ongoing uses of the sender's nonce. It is guarded by the
+	// manager's mutex, senders in use are never evicted.
+	refs int
+}
+
+// NewNonceManager returns a nonce manager seeding the nonces from the source.
+func NewNonceManager(source NonceSource) *NonceManager {
+	return &NonceManager{
+		source:  source,
+		senders: make(map[common.Address]*senderNonce),
+	}
+}
+
+// sender returns the nonce state of the provided address. done must be called
+// once the returned state is no longer used.
+func (m *NonceManager) sender(from common.Address) *senderNonce {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.evictIdle()
+
+	s, ok := m.senders[from]
+	if !ok {
+		s = new(senderNonce)
+		m.senders[from] = s
+	}
+	s.refs++
+	return s
+}
+
+// done marks the end of a use of the sender's nonce state.
+func (m *NonceManager) done(s *senderNonce) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	s.refs--
+}
+
+// evictIdle drops the senders not in use and idle for longer than the nonce
+// resync interval. The senders are swept at most once per interval. The
+// manager's lock must be held.
+func (m *NonceManager) evictIdle() {
+	now := time.Now()
+	if now.Sub(m.lastSweep) < nonceResyncInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for from, s := range m.senders {
+		// No one holds the sender's lock while it isn't in use.
+		if s.refs == 0 && now.Sub(s.lastUsed) > nonceResyncInterval {
+			delete(m.senders, from)
+		}
+	}
+}
+
+// Acquire locks the sender's submissions and returns their next nonce. The
+// release function returned must be called with the error returned while
+// submitting the transaction. The nonce is only consumed if no error was
+// returned, and a nonce rejected by the node reseeds it from the chain.
+func (m *NonceManager) Acquire(ctx context.Context, from common.Address) (uint64, func(error), error) {
+	s := m.sender(from)
+	s.mtx.Lock()
+
+	if err := m.seed(ctx, from, s); err != nil {
+		s.mtx.Unlock()
+		m.done(s)
+		return 0, nil, err
+	}
+
+	nonce := s.next
+	release := func(err error) {
+		defer m.done(s)
+		defer s.mtx.Unlock()
+
+		switch {
+		case err == nil:
+			s.next = nonce + 1
+			s.lastUsed = time.Now()
+		case IsNonceErr(err):
+			s.seeded = false
+		}
+	}
+
+	return nonce, release, nil
+}
+
+// Next returns the sender's next nonce without consuming it. It is used by the
+// transactions signed by the client, whose nonce is only consumed by Observe
+// once they are broadcasted.
+func (m *NonceManager) Next(ctx context.Context, from common.Address) (uint64, error) {
+	s := m.sender(from)
+	defer m.done(s)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := m.seed(ctx, from, s); err != nil {
+		return 0, err
+	}
+	return s.next, nil
+}
+
+// seed sets the sender's next nonce from the chain if it was never seeded or
+// the sender was idle for too long. The sender's lock must be held.
+func (m *NonceManager) seed(ctx context.Context, from common.Address, s *senderNonce) error {
+	if s.seeded && time.Since(s.lastUsed) <= nonceResyncInterval {
+		return nil
+	}
+
+	nonce, err := m.source.PendingNonceAt(ctx, from)
+	if err != nil {
+		return fmt.Errorf("failed to seed the sender nonce: %w", err)
+	}
+	s.next = nonce
+	s.seeded = true
+	s.lastUsed = time.Now()
+	return nil
+}
+
+// Observe records a nonce used by a transaction the sender submitted without
+// the nonce manager so that it is not handed out again.
+func (m *NonceManager) Observe(from common.Address, nonce uint64) {
+	s := m.sender(from)
+	defer m.done(s)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.seeded && nonce >= s.next {
+		s.next = nonce + 1
+		s.lastUsed = time.Now()
+	}
+}
+
+// Reset discards the sender's local nonce so that the next one is seeded from
+// the chain. It is used to fill the nonce gaps left by dropped transactions.
+func (m *NonceManager) Reset(from common.Address) {
+	s := m.sender(from)
+	defer m.done(s)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.seeded = false
+}
+
+// IsNonceErr returns true if the node rejected the transaction because of
+// its nonce.
+func IsNonceErr(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, nonceErr := range nonceErrs {
+		if strings.Contains(msg, nonceErr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package sapphire
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// mockNonceSource returns the same chain nonce for all the senders.
+type mockNonceSource struct {
+	nonce uint64
+	calls int32
+}
+
+func (m *mockNonceSource) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	atomic.AddInt32(&m.calls, 1)
+	return atomic.LoadUint64(&m.nonce), nil
+}
+
+var (
+	sender1 = common.HexToAddress("0x3396FD816Dd81100477c8ea3853039822f36B7ad")
+	sender2 = common.HexToAddress("0x3396FD816Dd81100477c8ea3853039822f36B7bd")
+)
+
+// acquire returns the sender's next nonce and releases it with the error provided.
+func acquire(t *testing.T, m *NonceManager, from common.Address, err error) uint64 {
+	nonce, release, seedErr := m.Acquire(context.Background(), from)
+	if seedErr != nil {
+		t.Fatalf("expected no error but found %q", seedErr)
+	}
+	release(err)
+	return nonce
+}
+
+// TestNonceManager tests the nonces handed out to the senders.
+func TestNonceManager(t *testing.T) {
+	td := []struct {
+		testName string
+		run      func(m *NonceManager, source *mockNonceSource) []uint64
+		expected []uint64
+		seeds    int32
+	}{
+		{
+			"consecutive_nonces_seeded_once",
+			func(m *NonceManager, _ *mockNonceSource) []uint64 {
+				return []uint64{acquire(t, m, sender1, nil), acquire(t, m, sender1, nil),
+					acquire(t, m, sender1, nil)}
+			},
+			[]uint64{5, 6, 7}, 1,
+		},
+		{
+			"senders_seeded_separately",
+			func(m *NonceManager, _ *mockNonceSource) []uint64 {
+				return []uint64{acquire(t, m, sender1, nil), acquire(t, m, sender2, nil),
+					acquire(t, m, sender1, nil)}
+			},
+			[]uint64{5, 5, 6}, 2,
+		},
+		{
+			"failed_submission_reuses_nonce",
+			func(m *NonceManager, _ *mockNonceSource) []uint64 {
+				return []uint64{acquire(t, m, sender1, errors.New("execution reverted")),
+					acquire(t, m, sender1, nil)}
+			},
+			[]uint64{5, 5}, 1,
+		},
+		{
+			"rejected_nonce_reseeds",
+			func(m *NonceManager, source *mockNonceSource) []uint64 {
+				first := acquire(t, m, sender1, nil)
+				atomic.StoreUint64(&source.nonce, 9)
+				second := acquire(t, m, sender1, errors.New("nonce too low: next nonce 9, tx nonce 6"))
+				return []uint64{first, second, acquire(t, m, sender1, nil)}
+			},
+			[]uint64{5, 6, 9}, 2,
+		},
+		{
+			"reset_fills_dropped_tx_gap",
+			func(m *NonceManager, source *mockNonceSource) []uint64 {
+				first := acquire(t, m, sender1, nil)
+				second := acquire(t, m, sender1, nil)
+				// The tx with nonce 6 was dropped.
+				atomic.StoreUint64(&source.nonce, 6)
+				m.Reset(sender1)
+				return []uint64{first, second, acquire(t, m, sender1, nil)}
+			},
+			[]uint64{5, 6, 6}, 2,
+		},
+		{
+			"observed_nonce_skipped",
+			func(m *NonceManager, _ *mockNonceSource) []uint64 {
+				first := acquire(t, m, sender1, nil)
+				m.Observe(sender1, 6)
+				return []uint64{first, acquire(t, m, sender1, nil)}
+			},
+			[]uint64{5, 7}, 1,
+		},
+		{
+			"client_signed_nonce_shared",
+			func(m *NonceManager, _ *mockNonceSource) []uint64 {
+				next, err := m.Next(context.Background(), sender1)
+				if err != nil {
+					t.Fatalf("expected no error but found %q", err)
+				}
+				// The client signed tx is broadcasted with the nonce handed out.
+				m.Observe(sender1, next)
+				return []uint64{next, acquire(t, m, sender1, nil)}
+			},
+			[]uint64{5, 6}, 1,
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			source := &mockNonceSource{nonce: 5}
+			m := NewNonceManager(source)
+
+			nonces := v.run(m, source)
+			if len(nonces) != len(v.expected) {
+				t.Fatalf("expected %d nonces but found %d", len(v.expected), len(nonces))
+			}
+
+			for i, nonce := range nonces {
+				if nonce != v.expected[i] {
+					t.Fatalf("expected nonces %v but found %v", v.expected, nonces)
+				}
+			}
+
+			if seeds := atomic.LoadInt32(&source.calls); seeds != v.seeds {
+				t.Fatalf("expected %d chain queries but found %d", v.seeds, seeds)
+			}
+		})
+	}
+}
+
+// TestNonceManagerConcurrentSubmissions tests that the concurrent submissions
+// of each sender get unique consecutive nonces.
+func TestNonceManagerConcurrentSubmissions(t *testing.T) {
+	const submissions = 50
+
+	m := NewNonceManager(&mockNonceSource{})
+
+	var mtx sync.Mutex
+	used := make(map[common.Address]map[uint64]bool)
+
+	var wg sync.WaitGroup
+	for _, sender := range []common.Address{sender1, sender2} {
+		used[sender] = make(map[uint64]bool)
+
+		for i := 0; i < submissions; i++ {
+			wg.Add(1)
+			go func(from common.Address) {
+				defer wg.Done()
+
+				nonce, release, err := m.Acquire(context.Background(), from)
+				if err != nil {
+					t.Errorf("expected no error but found %q", err)
+					return
+				}
+				defer release(nil)
+
+				mtx.Lock()
+				defer mtx.Unlock()
+
+				if used[from][nonce] {
+					t.Errorf("nonce %d handed out twice to %v", nonce, from)
+				}
+				used[from][nonce] = true
+			}(sender)
+		}
+	}
+	wg.Wait()
+
+	for sender, nonces := range used {
+		for i := uint64(0); i < submissions; i++ {
+			if !nonces[i] {
+				t.Fatalf("expected nonce %d to be used by %v", i, sender)
+			}
+		}
+	}
+}
+
+// TestNonceManagerEviction tests that the idle senders are evicted while the
+// senders in use are kept.
+func TestNonceManagerEviction(t *testing.T) {
+	m := NewNonceManager(&mockNonceSource{nonce: 5})
+
+	// idle moves the sender's last use and the last sweep past the resync interval.
+	idle := func(from common.Address) {
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+
+		m.senders[from].lastUsed = time.Now().Add(-2 * nonceResyncInterval)
+		m.lastSweep = time.Now().Add(-2 * nonceResyncInterval)
+	}
+
+	tracked := func(from common.Address) bool {
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+
+		_, ok := m.senders[from]
+		return ok
+	}
+
+	t.Run("idle_sender_evicted", func(t *testing.T) {
+		acquire(t, m, sender1, nil)
+		idle(sender1)
+
+		if nonce := acquire(t, m, sender2, nil); nonce != 5 {
+			t.Fatalf("expected nonce 5 but found %d", nonce)
+		}
+
+		if tracked(sender1) {
+			t.Fatalf("expected the idle sender %v to be evicted", sender1)
+		}
+
+		if !tracked(sender2) {
+			t.Fatalf("expected the sender %v to be kept", sender2)
+		}
+	})
+
+	t.Run("sender_in_use_kept", func(t *testing.T) {
+		_, release, err := m.Acquire(context.Background(), sender1)
+		if err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+		idle(sender1)
+
+		acquire(t, m, sender2, nil)
+
+		if !tracked(sender1) {
+			t.Fatalf("expected the sender %v in use to be kept", sender1)
+		}
+		release(nil)
+	})
+}
+
+// TestIsNonceErr tests the detection of the nonce errors returned by the node.
+func TestIsNonceErr(t *testing.T) {
+	td := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{errors.New("nonce too low"), true},
+		{errors.New("Invalid nonce"), true},
+		{errors.New("replacement transaction underpriced"), true},
+		{errors.New("execution reverted: Missing bond holder address"), false},
+	}
+
+	for _, v := range td {
+		if isNonceErr := IsNonceErr(v.err); isNonceErr != v.expected {
+			t.Fatalf("expected error %v nonce check to be %v but found %v",
+				v.err, v.expected, isNonceErr)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package sapphire
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	// runtimeKeyRefreshInterval defines how often the runtime public key is
+	// fetched again to pick up the key manager rotations.
+	runtimeKeyRefreshInterval = 10 * time.Minute
+
+	// minKeyRefreshInterval limits how often a decryption failure can trigger
+	// a runtime public key refresh.
+	minKeyRefreshInterval = 30 * time.Second
+
+	// publicKeySignatureContext is the domain separation context the key
+	// manager signs the runtime public keys with.
+	publicKeySignatureContext = "oasis-core/keymanager: pk signature"
+
+	// callDataKeyPairIDContext is the context the call data key pair ID of
+	// each epoch is derived from.
+	callDataKeyPairIDContext = "oasis-runtime-sdk/private: tx"
+)
+
+// ErrInvalidRuntimeKey is returned if the runtime public key fetched is
+// malformed or wasn't signed by the network's key manager.
+var ErrInvalidRuntimeKey = errors.New("invalid runtime public key")
+
+// ErrMissingKeyManagerKey is returned if a confidential network has no key
+// manager public key to verify its runtime public keys with.
+var ErrMissingKeyManagerKey = errors.New("missing key manager public key")
+
+// Verify confirms that the runtime public key was signed by the key manager
+// for the provided runtime.
+func (k *CallDataPublicKey) Verify(signer ed25519.PublicKey, runtimeID []byte) error {
+	if len(k.PublicKey) != curve25519.PointSize {
+		return fmt.Errorf("%w: expected a %d bytes key but found %d bytes",
+			ErrInvalidRuntimeKey, curve25519.PointSize, len(k.PublicKey))
+	}
+
+	message := prepareSignerMessage(publicKeySignatureContext, k.signedBody(runtimeID))
+	if !ed25519.Verify(signer, message, k.Signature) {
+		return fmt.Errorf("%w: key manager signature mismatch", ErrInvalidRuntimeKey)
+	}
+	return nil
+}
+
+// signedBody returns the runtime public key details signed by the key manager.
+func (k *CallDataPublicKey) signedBody(runtimeID []byte) []byte {
+	var epoch [8]byte
+	binary.BigEndian.PutUint64(epoch[:], k.Epoch)
+	keyPairID := sha512.Sum512_256(append([]byte(callDataKeyPairIDContext), epoch[:]...))
+
+	var body bytes.Buffer
+	body.Write(k.PublicKey)
+	body.Write(k.Checksum)
+	body.Write(runtimeID)
+	body.Write(keyPairID[:])
+
+	binary.LittleEndian.PutUint64(epoch[:], k.Epoch)
+	body.Write(epoch[:])
+	return body.Bytes()
+}
+
+// prepareSignerMessage returns the digest signed for the message in the
+// provided context, as done by the oasis-core signers.
+func prepareSignerMessage(context string, message []byte) []byte {
+	h := sha512.New512_256()
+	_, _ = h.Write([]byte(context))
+	_, _ = h.Write(message)
+	return h.Sum(nil)
+}
+
+// runtimeKeyVerifier returns the function verifying the runtime public keys
+// of the network. The network must have a key manager public key configured
+// since the calls would otherwise be encrypted with an unverified key.
+func runtimeKeyVerifier(network *utils.NetworkParams) (func(*CallDataPublicKey) error, error) {
+	runtimeID, err := hexutil.Decode(network.RuntimeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s runtime ID: %w", network.Name, err)
+	}
+
+	if network.KeyManagerPubKey == "" {
+		return nil, fmt.Errorf("%w: required to verify the %s runtime public keys",
+			ErrMissingKeyManagerKey, network.Name)
+	}
+
+	signer, err := base64.StdEncoding.DecodeString(network.KeyManagerPubKey)
+	if err != nil || len(signer) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid %s key manager public key", network.Name)
+	}
+
+	return func(k *CallDataPublicKey) error {
+		return k.Verify(signer, runtimeID)
+	}, nil
+}
+
+// rotatingCipher is the default cipher. It encrypts the calls with the latest
+// verified runtime public key, which is refreshed at regular intervals and
+// whenever a call result can't be decrypted.
+type rotatingCipher struct {
+	keypair Curve25519KeyPair
+	fetch   func(ctx context.Context) (*CallDataPublicKey, error)
+	verify  func(*CallDataPublicKey) error
+
+	mtx         sync.RWMutex
+	cipher      *X25519DeoxysIICipher
+	epoch       uint64
+	verifiedAt  time.Time
+	lastRefresh time.Time
+}
+
+// Confirm that rotatingCipher implements the Cipher interface.
+var _ Cipher = (*rotatingCipher)(nil)
+
+// newRotatingCipher returns a cipher using the runtime public key fetched and
+// verified with the provided functions.
+func newRotatingCipher(ctx context.Context, fetch func(ctx context.Context) (*CallDataPublicKey, error),
+	verify func(*CallDataPublicKey) error,
+) (*rotatingCipher, error) {
+	keypair, err := NewCurve25519KeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral keypair: %w", err)
+	}
+
+	c := &rotatingCipher{
+		keypair: *keypair,
+		fetch:   fetch,
+		verify:  verify,
+	}
+
+	if err = c.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// refresh fetches and verifies the runtime public key, replacing the cipher
+// if the key was rotated.
+func (c *rotatingCipher) refresh(ctx context.Context) error {
+	c.mtx.Lock()
+	c.lastRefresh = time.Now()
+	c.mtx.Unlock()
+
+	key, err := c.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch runtime calldata public key: %w", err)
+	}
+
+	if err = c.verify(key); err != nil {
+		return err
+	}
+
+	var pubkey [curve25519.PointSize]byte
+	copy(pubkey[:], key.PublicKey)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.cipher == nil || c.cipher.peerPublicKey != pubkey {
+		cipher, err := NewX25519DeoxysIICipher(c.keypair, pubkey)
+		if err != nil {
+			return fmt.Errorf("failed to create default cipher: %w", err)
+		}
+
+		if c.cipher != nil {
+			log.Infof("Runtime public key rotated at epoch %d", key.Epoch)
+		}
+		c.cipher = cipher
+	}
+
+	c.epoch = key.Epoch
+	c.verifiedAt = time.Now()
+	return nil
+}
+
+// run refreshes the runtime public key at every refresh interval until the
+// context is cancelled.
+func (c *rotatingCipher) run(ctx context.Context) {
+	ticker := time.NewTicker(runtimeKeyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.refresh(ctx); err != nil {
+				log.Errorf("refreshing the runtime public key failed: %v", err)
+			}
+		}
+	}
+}
+
+// refreshOnFailure refreshes the runtime public key in the background unless
+// it was refreshed recently.
+func (c *rotatingCipher) refreshOnFailure() {
+	c.mtx.RLock()
+	recent := time.Since(c.lastRefresh) < minKeyRefreshInterval
+	c.mtx.RUnlock()
+
+	if recent {
+		return
+	}
+
+	go func() {
+		if err := c.refresh(context.Background()); err != nil {
+			log.Errorf("refreshing the runtime public key failed: %v", err)
+		}
+	}()
+}
+
+// current returns the cipher of the latest runtime public key.
+func (c *rotatingCipher) current() *X25519DeoxysIICipher {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.cipher
+}
+
+// keyInfo returns the epoch of the runtime public key and when it was last
+// verified.
+func (c *rotatingCipher) keyInfo() (uint64, time.Time) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.epoch, c.verifiedAt
+}
+
+func (c *rotatingCipher) Kind() uint64 {
+	return c.current().Kind()
+}
+
+func (c *rotatingCipher) Encrypt(plaintext []byte) ([]byte, []byte) {
+	return c.current().Encrypt(plaintext)
+}
+
+func (c *rotatingCipher) Decrypt(nonce []byte, ciphertext []byte) ([]byte, error) {
+	plaintext, err := c.current().Decrypt(nonce, ciphertext)
+	if err != nil {
+		c.refreshOnFailure()
+	}
+	return plaintext, err
+}
+
+func (c *rotatingCipher) EncryptEnvelope(plaintext []byte) *EncryptedBodyEnvelope {
+	return c.current().EncryptEnvelope(plaintext)
+}
+
+func (c *rotatingCipher) EncryptEncode(plaintext []byte) []byte {
+	return c.current().EncryptEncode(plaintext)
+}
+
+func (c *rotatingCipher) DecryptCallResult(result []byte) ([]byte, error) {
+	res, err := c.current().DecryptCallResult(result)
+	if errors.Is(err, ErrCallResultDecrypt) {
+		c.refreshOnFailure()
+	}
+	return res, err
+}
+
+func (c *rotatingCipher) DecryptEncoded(result []byte) ([]byte, error) {
+	return c.DecryptCallResult(result)
+}
+
+// RuntimeKeyAge returns the epoch of the runtime public key in use and the
+// time since it was last fetched and verified. False is returned if the
+// backend doesn't encrypt its calls.
+func (b *WrappedBackend) RuntimeKeyAge() (uint64, time.Duration, bool) {
+	c, ok := b.cipher.(*rotatingCipher)
+	if !ok {
+		return 0, 0, false
+	}
+
+	epoch, verifiedAt := c.keyInfo()
+	return epoch, time.Since(verifiedAt), true
+}
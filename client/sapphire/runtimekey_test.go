@@ -0,0 +1,225 @@
+package sapphire
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+var testRuntimeID = []byte{0x80, 0x01}
+
+// signedRuntimeKey returns a runtime public key of the epoch signed by the
+// key manager key provided.
+func signedRuntimeKey(t *testing.T, signer ed25519.PrivateKey, epoch uint64) *CallDataPublicKey {
+	keypair, err := NewCurve25519KeyPair()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	key := &CallDataPublicKey{
+		PublicKey: keypair.PublicKey[:],
+		Checksum:  []byte("checksum"),
+		Epoch:     epoch,
+	}
+
+	message := prepareSignerMessage(publicKeySignatureContext, key.signedBody(testRuntimeID))
+	key.Signature = ed25519.Sign(signer, message)
+	return key
+}
+
+// TestCallDataPublicKeyVerify tests the verification of the runtime public
+// keys signatures.
+func TestCallDataPublicKeyVerify(t *testing.T) {
+	signerPub, signer, _ := ed25519.GenerateKey(rand.Reader)
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	td := []struct {
+		testName  string
+		key       func() *CallDataPublicKey
+		signer    ed25519.PublicKey
+		runtimeID []byte
+		err       error
+	}{
+		{
+			"valid_signature",
+			func() *CallDataPublicKey { return signedRuntimeKey(t, signer, 5) },
+			signerPub, testRuntimeID, nil,
+		},
+		{
+			"tampered_epoch",
+			func() *CallDataPublicKey {
+				key := signedRuntimeKey(t, signer, 5)
+				key.Epoch = 6
+				return key
+			},
+			signerPub, testRuntimeID, ErrInvalidRuntimeKey,
+		},
+		{
+			"other_runtime",
+			func() *CallDataPublicKey { return signedRuntimeKey(t, signer, 5) },
+			signerPub, []byte{0x80, 0x02}, ErrInvalidRuntimeKey,
+		},
+		{
+			"other_key_manager",
+			func() *CallDataPublicKey { return signedRuntimeKey(t, signer, 5) },
+			otherPub, testRuntimeID, ErrInvalidRuntimeKey,
+		},
+		{
+			"short_public_key",
+			func() *CallDataPublicKey {
+				key := signedRuntimeKey(t, signer, 5)
+				key.PublicKey = key.PublicKey[:16]
+				return key
+			},
+			signerPub, testRuntimeID, ErrInvalidRuntimeKey,
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			if err := v.key().Verify(v.signer, v.runtimeID); !errors.Is(err, v.err) {
+				t.Fatalf("expected error %v but found %v", v.err, err)
+			}
+		})
+	}
+}
+
+// TestRuntimeKeyVerifier tests that the network's runtime public keys are
+// verified against its configured key manager public key, and that the
+// networks without one fail closed.
+func TestRuntimeKeyVerifier(t *testing.T) {
+	signerPub, signer, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherSigner, _ := ed25519.GenerateKey(rand.Reader)
+
+	network := func(pubKey string) *utils.NetworkParams {
+		return &utils.NetworkParams{
+			Name:             "testnet",
+			RuntimeID:        hexutil.Encode(testRuntimeID),
+			KeyManagerPubKey: pubKey,
+		}
+	}
+
+	td := []struct {
+		testName  string
+		network   *utils.NetworkParams
+		key       *CallDataPublicKey
+		err       error
+		verifyErr error
+	}{
+		{
+			"Test-for-a-key-signed-by-the-key-manager",
+			network(base64.StdEncoding.EncodeToString(signerPub)),
+			signedRuntimeKey(t, signer, 5), nil, nil,
+		},
+		{
+			"Test-for-a-key-with-a-bad-signature",
+			network(base64.StdEncoding.EncodeToString(signerPub)),
+			signedRuntimeKey(t, otherSigner, 5), nil, ErrInvalidRuntimeKey,
+		},
+		{
+			"Test-for-a-missing-key-manager-public-key",
+			network(""), nil, ErrMissingKeyManagerKey, nil,
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			verify, err := runtimeKeyVerifier(v.network)
+			if !errors.Is(err, v.err) {
+				t.Fatalf("expected error %v but found %v", v.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if err = verify(v.key); !errors.Is(err, v.verifyErr) {
+				t.Fatalf("expected verify error %v but found %v", v.verifyErr, err)
+			}
+		})
+	}
+}
+
+// TestRotatingCipherRefresh tests that a rotated runtime public key is picked
+// up once a call result can't be decrypted, and that unverified keys are
+// never used.
+func TestRotatingCipherRefresh(t *testing.T) {
+	signerPub, signer, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherSigner, _ := ed25519.GenerateKey(rand.Reader)
+
+	var mtx sync.Mutex
+	runtimeKey := signedRuntimeKey(t, signer, 1)
+
+	fetch := func(ctx context.Context) (*CallDataPublicKey, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return runtimeKey, nil
+	}
+	verify := func(k *CallDataPublicKey) error {
+		return k.Verify(signerPub, testRuntimeID)
+	}
+	setKey := func(key *CallDataPublicKey) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		runtimeKey = key
+	}
+
+	c, err := newRotatingCipher(context.Background(), fetch, verify)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if epoch, _ := c.keyInfo(); epoch != 1 {
+		t.Fatalf("expected runtime key epoch 1 but found %d", epoch)
+	}
+
+	// A key not signed by the key manager is rejected and the current one kept.
+	setKey(signedRuntimeKey(t, otherSigner, 2))
+	if err = c.refresh(context.Background()); !errors.Is(err, ErrInvalidRuntimeKey) {
+		t.Fatalf("expected error %v but found %v", ErrInvalidRuntimeKey, err)
+	}
+
+	if epoch, _ := c.keyInfo(); epoch != 1 {
+		t.Fatalf("expected runtime key epoch 1 but found %d", epoch)
+	}
+
+	// The key manager rotates the key, the next undecryptable result refreshes it.
+	rotatedKey := signedRuntimeKey(t, signer, 2)
+	setKey(rotatedKey)
+
+	c.mtx.Lock()
+	c.lastRefresh = time.Now().Add(-2 * minKeyRefreshInterval)
+	c.mtx.Unlock()
+
+	result := cbor.Marshal(CallResult{Unknown: &AeadEnvelope{
+		Nonce: make([]byte, 15),
+		Data:  []byte("encrypted with the rotated key"),
+	}})
+	if _, err = c.DecryptCallResult(result); !errors.Is(err, ErrCallResultDecrypt) {
+		t.Fatalf("expected error %v but found %v", ErrCallResultDecrypt, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if epoch, _ := c.keyInfo(); epoch == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the runtime key to be refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if peer := c.current().peerPublicKey; string(peer[:]) != string(rotatedKey.PublicKey) {
+		t.Fatal("expected the cipher to use the rotated runtime key")
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"math/big"
 
-	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
@@ -27,6 +26,13 @@ type SignedCallDataPack struct {
 	Signature []byte `json:"signature"`
 }
 
+// EncryptedSignedCallDataPack defines a signed call whose data is encrypted.
+type EncryptedSignedCallDataPack struct {
+	Data      EncryptedBodyEnvelope `json:"data"`
+	Leash     Leash                 `json:"leash"`
+	Signature []byte                `json:"signature"`
+}
+
 // Data is the plain data in the datapack.
 type Data struct {
 	Body []byte `json:"body"`
@@ -73,17 +79,25 @@ func NewDataPack(sign SignerFn, privateKey []byte, chainID uint64, caller, calle
 	}, nil
 }
 
+// Encode returns the CBOR encoded signed call with its data unencrypted.
 func (p SignedCallDataPack) Encode() []byte {
-	return hexutil.Bytes(cbor.Marshal(p.Data.Body))
+	return cbor.Marshal(p)
 }
 
+// EncryptEncode returns the CBOR encoded signed call with its data encrypted.
+// The leash and the signature are sent alongside the encrypted data.
 func (p SignedCallDataPack) EncryptEncode(cipher Cipher) []byte {
-	// Encrypt when data exists
-	if p.Data.Body != nil {
-		return cipher.EncryptEncode(p.Data.Body)
+	// Calls without data have nothing to encrypt.
+	envelope := cipher.EncryptEnvelope(p.Data.Body)
+	if envelope == nil {
+		return p.Encode()
 	}
 
-	return p.Encode()
+	return cbor.Marshal(EncryptedSignedCallDataPack{
+		Data:      *envelope,
+		Leash:     p.Leash,
+		Signature: p.Signature,
+	})
 }
 
 func NewLeash(nonce uint64, blockNumber uint64, blockHash []byte, blockRange uint64) Leash {
@@ -162,25 +176,35 @@ func makeSignableCall(chainID uint64, caller, callee []byte, gasLimit uint64, ga
 
 // signTypedData is based on go-ethereum/core/signer but modified to use an in-memory signer.
 func signTypedData(sign SignerFn, privateKey []byte, typedData apitypes.TypedData) ([]byte, error) {
+	digest, err := typedDataDigest(typedData)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := sign(digest, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign typed data: %w", err)
+	}
+	signature[64] += 27 // Eth wallets use a high recovery ID.
+	return signature, nil
+}
+
+// typedDataDigest returns the EIP-712 digest of the typed data to be signed.
+func typedDataDigest(typedData apitypes.TypedData) ([32]byte, error) {
+	var digest [32]byte
+
 	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash EIP721Domain: %w", err)
+		return digest, fmt.Errorf("failed to hash EIP721Domain: %w", err)
 	}
 
 	typedDataHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+		return digest, fmt.Errorf("failed to hash typed data: %w", err)
 	}
 
 	rawData := []byte(fmt.Sprintf("\x19\x01%s%s", string(domainSeparator), string(typedDataHash)))
 
-	var digest [32]byte
 	copy(digest[:], crypto.Keccak256Hash(rawData).Bytes())
-
-	signature, err := sign(digest, privateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to sign typed data: %w", err)
-	}
-	signature[64] = 28 // Eth wallets use a high recovery ID.
-	return signature, nil
+	return digest, nil
 }
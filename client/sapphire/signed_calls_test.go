@@ -0,0 +1,161 @@
+package sapphire
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+// TestSignedCallEncryptEncode tests that the encrypted signed calls keep their
+// leash and a signature recovering the caller.
+func TestSignedCallEncryptEncode(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+	caller := crypto.PubkeyToAddress(key.PublicKey)
+
+	keypair, err := NewCurve25519KeyPair()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	cipher, err := NewX25519DeoxysIICipher(*keypair, keypair.PublicKey)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	sign := func(digest [32]byte, _ []byte) ([]byte, error) {
+		return crypto.Sign(digest[:], key)
+	}
+
+	data := []byte("getBondSecureDetails")
+	leash := NewLeash(1, 99, bytes.Repeat([]byte{1}, 32), DefaultBlockRange)
+
+	dataPack, err := NewDataPack(sign, nil, 1337, caller[:], sender1[:],
+		DefaultGasLimit, big.NewInt(0), big.NewInt(0), data, leash)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	var encoded EncryptedSignedCallDataPack
+	if err = cbor.Unmarshal(dataPack.EncryptEncode(cipher), &encoded); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if encoded.Leash.BlockNumber != leash.BlockNumber || !bytes.Equal(encoded.Leash.BlockHash, leash.BlockHash) {
+		t.Fatalf("expected leash %+v but found %+v", leash, encoded.Leash)
+	}
+
+	if bytes.Contains(encoded.Data.Body.Data, data) {
+		t.Fatal("expected the call data to be encrypted")
+	}
+
+	if v := encoded.Signature[64]; v != 27 && v != 28 {
+		t.Fatalf("expected a high recovery ID but found %d", v)
+	}
+
+	digest, err := typedDataDigest(makeSignableCall(1337, caller[:], sender1[:],
+		DefaultGasLimit, big.NewInt(0), big.NewInt(0), data, leash))
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	pubkey, err := crypto.SigToPub(digest[:], normalizeRecoveryID(encoded.Signature))
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != caller {
+		t.Fatalf("expected the call signer %v but found %v", caller, signer)
+	}
+}
+
+// mockLeashCaller records the calls made and returns the leash block headers.
+type mockLeashCaller struct {
+	mockCaller
+}
+
+func (m *mockLeashCaller) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: number}, nil
+}
+
+// TestClientSignedCallContract tests that the client signed calls are sent
+// with a high recovery ID, like the calls signed by the server, whichever form
+// the client signed with.
+func TestClientSignedCallContract(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+	caller := crypto.PubkeyToAddress(key.PublicKey)
+
+	keypair, err := NewCurve25519KeyPair()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	cipher, err := NewX25519DeoxysIICipher(*keypair, keypair.PublicKey)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	contract := common.HexToAddress("0x3396FD816Dd81100477c8ea3853039822f36B7ed")
+	call := ethereum.CallMsg{From: caller, To: &contract, Data: []byte("getBondSecureDetails")}
+	blockNumber := big.NewInt(100)
+
+	td := []struct {
+		testName   string
+		recoveryID byte
+	}{
+		{"low_recovery_id", 0},
+		{"high_recovery_id", 27},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			conn := &mockLeashCaller{}
+			b := &WrappedBackend{ContractBackend: conn, chainID: *big.NewInt(1337), cipher: cipher}
+
+			unsignedCall, err := b.SignableCall(context.Background(), call, blockNumber)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			signature, err := crypto.Sign(unsignedCall.Digest[:], key)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+			signature[64] += v.recoveryID
+			sent := signature[64]
+
+			// The mocked result can't be decrypted, only the call sent is checked.
+			opts := b.ClientSignedCallOpts(context.Background(), caller, blockNumber, signature)
+			_, _ = b.CallContract(opts.Context, call, blockNumber)
+
+			if signature[64] != sent {
+				t.Fatal("expected the client signature to be left unchanged")
+			}
+
+			var encoded EncryptedSignedCallDataPack
+			if err = cbor.Unmarshal(conn.call.Data, &encoded); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if v := encoded.Signature[64]; v != 27 && v != 28 {
+				t.Fatalf("expected a high recovery ID but found %d", v)
+			}
+
+			if err = unsignedCall.VerifySigner(encoded.Signature, caller); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package sapphire
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// errNoTxReader is returned if the wrapped client can't query transactions.
+	errNoTxReader = errors.New("wrapped client doesn't support querying transactions")
+
+	// errNoNonceReader is returned if the wrapped client can't query the
+	// confirmed account nonces.
+	errNoNonceReader = errors.New("wrapped client doesn't support querying nonces")
+
+	// ErrNoTxInput is returned if the plain input of an encrypted transaction
+	// isn't known.
+	ErrNoTxInput = errors.New("the transaction plain input is unknown")
+)
+
+// nonceReader defines the method used to query the confirmed account nonces.
+type nonceReader interface {
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// txReader returns the wrapped client as a transaction reader if supported.
+func (b *WrappedBackend) txReader() (ethereum.TransactionReader, error) {
+	reader, ok := b.ContractBackend.(ethereum.TransactionReader)
+	if !ok {
+		return nil, errNoTxReader
+	}
+	return reader, nil
+}
+
+// TransactionReceipt returns the receipt of a mined transaction. If the
+// transaction is not yet mined ethereum.NotFound is returned.
+func (b *WrappedBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	reader, err := b.txReader()
+	if err != nil {
+		return nil, err
+	}
+	return reader.TransactionReceipt(ctx, txHash)
+}
+
+// TransactionByHash returns the transaction with the given hash and whether
+// it is still pending. If the node doesn't know the transaction
+// ethereum.NotFound is returned.
+func (b *WrappedBackend) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	reader, err := b.txReader()
+	if err != nil {
+		return nil, false, err
+	}
+	return reader.TransactionByHash(ctx, txHash)
+}
+
+// NonceAt returns the account's nonce confirmed at the provided block. The
+// latest block is used if the block number is nil.
+func (b *WrappedBackend) NonceAt(ctx context.Context, account common.Address,
+	blockNumber *big.Int,
+) (uint64, error) {
+	reader, ok := b.ContractBackend.(nonceReader)
+	if !ok {
+		return 0, errNoNonceReader
+	}
+	return reader.NonceAt(ctx, account, blockNumber)
+}
+
+// ReplayTx re-executes the transaction as a call made at the provided block
+// with its plain input. The call is encrypted like any other contract call.
+// The sender's key isn't available so the confidential networks replay it
+// without a from address. The error returned by the node, if any, holds the
+// reason the transaction reverted.
+func (b *WrappedBackend) ReplayTx(ctx context.Context, tx *types.Transaction,
+	from common.Address, input []byte, blockNumber *big.Int,
+) error {
+	call := ethereum.CallMsg{
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  input,
+	}
+
+	if b.isPlain() {
+		// The non-confidential networks trust the from address and their
+		// transactions data is not encrypted.
+		call.From = from
+		if call.Data == nil {
+			call.Data = tx.Data()
+		}
+	}
+
+	if call.Data == nil {
+		return ErrNoTxInput
+	}
+
+	_, err := b.CallContract(ctx, call, blockNumber)
+	return err
+}
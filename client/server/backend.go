@@ -4,22 +4,46 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/big"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/dmigwi/dhamana-protocol/client/contracts"
+	"github.com/dmigwi/dhamana-protocol/client/sapphire"
 	"github.com/dmigwi/dhamana-protocol/client/servertypes"
 	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
-// sessionTime defines the duration when the server public key is valid.
-const sessionTime = time.Minute * 10
+const (
+	// sessionTime defines the duration when the server public key is valid.
+	sessionTime = time.Minute * 10
+
+	// maxBatchSize defines the maximum number of messages that can be sent in
+	// a single batch request.
+	maxBatchSize = 20
+
+	// maxRequestSize defines the maximum size in bytes of a request body,
+	// which holds either a single message or a batch of messages.
+	maxRequestSize = 1 << 20
+
+	// notBondPartyReason is the revert reason of the contract calls restricted
+	// to the bond issuer and holder.
+	notBondPartyReason = "Only used by the bond parties"
+)
 
 // ZeroAddress defines an empty address value.
 var ZeroAddress = common.HexToAddress("")
@@ -27,10 +51,96 @@ var ZeroAddress = common.HexToAddress("")
 // decodeRequestBody attempts to extract contents of the request passed, if an error
 // occured a response in bytes is returned. isSignerKeyRequired is used to set
 // when existence of the signer key should be checked.
-// Its returns the method type depending on how it is implemented.
+// Its returns the method type depending on how it is implemented and the
+// method requested, which is cleared from the message once it is packed.
 func decodeRequestBody(req *http.Request, msg *servertypes.RPCMessage,
 	isSignerKeyRequired bool,
-) utils.MethodType {
+) (utils.MethodType, utils.Method) {
+	if req.Method != http.MethodPost {
+		err := fmt.Errorf("invalid http method %s found expected %s",
+			req.Method, http.MethodPost)
+		msg.PackServerError(utils.ErrInvalidReq, err)
+		return utils.UnknownType, ""
+	}
+
+	// extract the request body contents
+	if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+		msg.PackServerError(utils.ErrInvalidJSON, err)
+		return utils.UnknownType, ""
+	}
+
+	method := msg.Method
+	methodType := validateRequestMsg(msg, isSignerKeyRequired)
+	if msg.Error == nil {
+		msg.Sender.Org = requestOrg(req)
+	}
+	return methodType, method
+}
+
+// decodeBatchRequestBody attempts to extract a JSON-RPC 2.0 batch of messages
+// from the request passed. If the whole batch couldn't be decoded, a non-nil
+// message packed with the error to be returned to the client is returned
+// instead. Each message in the batch is not validated here.
+func decodeBatchRequestBody(req *http.Request) ([]json.RawMessage, *servertypes.RPCMessage) {
+	var msg servertypes.RPCMessage
+
+	if req.Method != http.MethodPost {
+		err := fmt.Errorf("invalid http method %s found expected %s",
+			req.Method, http.MethodPost)
+		msg.PackServerError(utils.ErrInvalidReq, err)
+		return nil, &msg
+	}
+
+	var batch []json.RawMessage
+	if err := json.NewDecoder(req.Body).Decode(&batch); err != nil {
+		msg.PackServerError(utils.ErrInvalidJSON, err)
+		return nil, &msg
+	}
+
+	switch {
+	case len(batch) == 0:
+		msg.PackServerError(utils.ErrInvalidReq, errors.New("empty batch request found"))
+		return nil, &msg
+
+	case len(batch) > maxBatchSize:
+		err := fmt.Errorf("expected a max of %d batch messages but found %d",
+			maxBatchSize, len(batch))
+		msg.PackServerError(utils.ErrInvalidReq, err)
+		return nil, &msg
+	}
+
+	return batch, nil
+}
+
+// isBatchRequest confirms if the request body holds a JSON array of messages
+// as defined by JSON-RPC 2.0 batching. The request body is restored after
+// being read so that it can be decoded again. An error is returned if the
+// body couldn't be read or exceeds the request size limit.
+func isBatchRequest(req *http.Request) (bool, error) {
+	if req.Body == nil {
+		return false, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return false, fmt.Errorf("expected a max of %d bytes request body", maxBytesErr.Limit)
+		}
+		return false, fmt.Errorf("reading the request body failed: %v", err)
+	}
+
+	body = bytes.TrimLeft(body, " \t\r\n")
+	return len(body) > 0 && body[0] == '[', nil
+}
+
+// validateRequestMsg confirms that the decoded message has all the required
+// fields set and that the params provided match what the method expects.
+// If an error occured it is packed into the message.
+// Its returns the method type depending on how it is implemented.
+func validateRequestMsg(msg *servertypes.RPCMessage, isSignerKeyRequired bool) utils.MethodType {
 	var msgError, err error
 
 	// creates the error response to be returned
@@ -40,19 +150,6 @@ func decodeRequestBody(req *http.Request, msg *servertypes.RPCMessage,
 		}
 	}()
 
-	if req.Method != http.MethodPost {
-		msgError = utils.ErrInvalidReq
-		err = fmt.Errorf("invalid http method %s found expected %s",
-			req.Method, http.MethodPost)
-		return utils.UnknownType
-	}
-
-	// extract the request body contents
-	if err = json.NewDecoder(req.Body).Decode(&msg); err != nil {
-		msgError = utils.ErrInvalidJSON
-		return utils.UnknownType
-	}
-
 	// Checks for JSON-RPC version mismatch
 	if msg.Version != utils.JSONRPCVersion {
 		msgError = utils.ErrInvalidReq
@@ -92,6 +189,12 @@ func decodeRequestBody(req *http.Request, msg *servertypes.RPCMessage,
 		return utils.UnknownType
 	}
 
+	// The optional last param defaults to false if missing.
+	if n := len(params); n > 0 && params[n-1] == utils.OptionalBoolType &&
+		len(msg.Params) == n-1 {
+		msg.Params = append(msg.Params, false)
+	}
+
 	if len(msg.Params) != len(params) {
 		err = fmt.Errorf("method %s requires %d params found %d params",
 			msg.Method, len(params), len(msg.Params))
@@ -112,8 +215,14 @@ func decodeRequestBody(req *http.Request, msg *servertypes.RPCMessage,
 
 // writeResponse writes the response using the provided response writter.
 func writeResponse(w http.ResponseWriter, response interface{}) {
+	writeResponseWithCode(w, http.StatusOK, response)
+}
+
+// writeResponseWithCode writes the response with the provided http status code.
+func writeResponseWithCode(w http.ResponseWriter, code int, response interface{}) {
 	w.Header().Add("Content-Type", "application/json")
 	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Errorf("response writter failed: %v", err)
 	}
@@ -135,10 +244,16 @@ func (s *ServerConfig) welcomeTextFunc(w http.ResponseWriter, req *http.Request)
 // to be used with diffie-hellman key exchange algorithm.
 // This server public key has an expiry date attached to it, after which the
 // client must fetch a new server pubkey to keep the communication alive.
-// A session server pubkey is mapped to a specific user address.
+// A session server pubkey is mapped to a specific user address only after the
+// sender signs the session challenge issued to it, proving that it controls
+// the address.
 func (s *ServerConfig) serverPubkey(w http.ResponseWriter, req *http.Request) {
 	var msg servertypes.RPCMessage
-	methodType := decodeRequestBody(req, &msg, false)
+	var method utils.Method
+	defer func(start time.Time) { observeRPC(method, &msg, start) }(time.Now())
+
+	req.Body = http.MaxBytesReader(w, req.Body, maxRequestSize)
+	methodType, method := decodeRequestBody(req, &msg, false)
 	if msg.Error != nil {
 		writeResponse(w, msg)
 		return
@@ -152,6 +267,26 @@ func (s *ServerConfig) serverPubkey(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if msg.Method == utils.GetSessionChallenge {
+		s.sessionChallenge(&msg, remoteHost(req))
+		writeResponse(w, msg)
+		return
+	}
+
+	// Set the sender before packing the result because its zeroed while preparing
+	// the client response.
+	sender := msg.Sender.Address
+
+	message, err := s.challenges.consume(sender, msg.Params[1].(string))
+	if err == nil {
+		err = verifyChallengeSig(sender, message, msg.Params[2].(string))
+	}
+	if err != nil {
+		msg.PackServerError(utils.ErrInvalidChallenge, err)
+		writeResponse(w, msg)
+		return
+	}
+
 	// Pass nil so that the default rand reader can be used.
 	privKey, err := utils.GeneratePrivKey(nil)
 	if err != nil {
@@ -168,17 +303,21 @@ func (s *ServerConfig) serverPubkey(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	token, err := randomHex(sessionTokenSize)
+	if err != nil {
+		msg.PackServerError(utils.ErrInternalFailure, nil)
+		writeResponse(w, msg)
+		return
+	}
+
 	// server public key is valid for 10 minutes after which a new public key
 	// must be requested.
 	data := servertypes.ServerKeyResp{
-		Pubkey: privKey.PubKeyToHexString(),
-		Expiry: uint64(time.Now().UTC().Add(sessionTime).Unix()),
+		Pubkey:       privKey.PubKeyToHexString(),
+		Expiry:       uint64(time.Now().UTC().Add(sessionTime).Unix()),
+		SessionToken: token,
 	}
 
-	// Set the sender before packing the result because its zeroed while preparing
-	// the client response.
-	sender := msg.Sender.Address
-
 	msg.PackServerResult(data)
 	writeResponse(w, msg)
 
@@ -186,77 +325,230 @@ func (s *ServerConfig) serverPubkey(w http.ResponseWriter, req *http.Request) {
 	// what the POA (Point Of Access) client should use to encrypt information
 	// shared with the server.
 	data.SharedKey = sharedkey
-	s.sessionKeys.Store(sender, data)
+	if err = s.sessions.Store(sender, data); err != nil {
+		log.Errorf("storing the session of %v failed: %v", sender, err)
+	}
+}
+
+// sessionChallenge issues a new session challenge to the sender requesting it
+// from the remote address and packs it into the message.
+func (s *ServerConfig) sessionChallenge(msg *servertypes.RPCMessage, remoteAddr string) {
+	nonce, challenge, err := s.challenges.issue(msg.Sender.Address, remoteAddr)
+	switch {
+	case errors.Is(err, errTooManyChallenges), errors.Is(err, errChallengeRateLimited):
+		msg.PackServerError(utils.ErrInvalidReq, err)
+		return
+	case err != nil:
+		msg.PackServerError(utils.ErrInternalFailure, nil)
+		return
+	}
+
+	msg.PackServerResult(servertypes.SessionChallengeResp{
+		Nonce:   nonce,
+		Message: challenge.message,
+		Expiry:  uint64(challenge.expiry.Unix()),
+	})
 }
 
-// backendQueryFunc recieves all the requests made to the contracts.
+// remoteHost returns the host of the request's remote address, dropping the
+// port that changes between the connections of the same client.
+func remoteHost(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// backendQueryFunc recieves all the requests made to the contracts. A JSON-RPC
+// 2.0 batch of requests is also supported, where each message in the batch is
+// validated, authorised and executed on its own.
 func (s *ServerConfig) backendQueryFunc(w http.ResponseWriter, req *http.Request) {
 	var msg servertypes.RPCMessage
 
-	methodType := decodeRequestBody(req, &msg, true)
-	if msg.Error != nil {
+	req.Body = http.MaxBytesReader(w, req.Body, maxRequestSize)
+	isBatch, err := isBatchRequest(req)
+	switch {
+	case err != nil:
+		msg.PackServerError(utils.ErrInvalidReq, err)
 		writeResponse(w, msg)
 		return
+
+	case isBatch:
+		s.backendBatchQuery(w, req)
+		return
 	}
 
-	// Only allow contract and local type methods to be executed.
-	if methodType != utils.ContractType && methodType != utils.LocalType {
-		err := fmt.Errorf("unsupported method %s found for this route", msg.Method)
-		msg.PackServerError(utils.ErrUnknownMethod, err)
-		writeResponse(w, msg)
+	start := time.Now()
+
+	methodType, method := decodeRequestBody(req, &msg, !s.signingMode.ClientSigns())
+	if msg.Error == nil {
+		s.executeBackendMsg(req.Context(), &msg, methodType)
+	}
+
+	observeRPC(method, &msg, start)
+	writeResponse(w, msg)
+}
+
+// backendBatchQuery executes each of the messages in the batch request and
+// writes back the array of responses in the order the messages were recieved.
+func (s *ServerConfig) backendBatchQuery(w http.ResponseWriter, req *http.Request) {
+	batch, errMsg := decodeBatchRequestBody(req)
+	if errMsg != nil {
+		writeResponse(w, errMsg)
 		return
 	}
 
+	responses := make([]*servertypes.RPCMessage, 0, len(batch))
+	for _, rawMsg := range batch {
+		msg := new(servertypes.RPCMessage)
+		responses = append(responses, msg)
+		start := time.Now()
+
+		if err := json.Unmarshal(rawMsg, msg); err != nil {
+			msg.PackServerError(utils.ErrInvalidReq, err)
+			observeRPC("", msg, start)
+			continue
+		}
+
+		method := msg.Method
+		methodType := validateRequestMsg(msg, !s.signingMode.ClientSigns())
+		if msg.Error == nil {
+			msg.Sender.Org = requestOrg(req)
+			s.executeBackendMsg(req.Context(), msg, methodType)
+		}
+		observeRPC(method, msg, start)
+	}
+
+	writeResponse(w, responses)
+}
+
+// loadSessionKey returns the shared key of the session associated with the
+// message sender. If the session is missing or has already expired, the error
+// is packed into the message.
+func (s *ServerConfig) loadSessionKey(msg *servertypes.RPCMessage) []byte {
 	sender := msg.Sender.Address
 	// Check if the server keys exists.
-	data, ok := s.sessionKeys.Load(sender)
+	session, ok, err := s.sessions.Load(sender)
+	if err != nil {
+		msg.PackServerError(utils.ErrInternalFailure, err)
+		return nil
+	}
+
 	if !ok {
-		err := errors.New("no server keys found associated with the sender")
+		err = errors.New("no server keys found associated with the sender")
 		msg.PackServerError(utils.ErrMissingServerKey, err)
-		writeResponse(w, msg)
-		return
+		return nil
+	}
+
+	// The session token ties the sender to the address verified when the
+	// session was created.
+	if subtle.ConstantTimeCompare([]byte(session.SessionToken), []byte(msg.Sender.SessionToken)) != 1 {
+		err = errors.New("session token doesn't match the sender's session")
+		msg.PackServerError(utils.ErrInvalidSession, err)
+		return nil
 	}
 
 	// check for the server keys expiry.
-	expiryTime := time.Unix(int64(data.(servertypes.ServerKeyResp).Expiry), 0).UTC()
+	expiryTime := time.Unix(int64(session.Expiry), 0).UTC()
 	if time.Now().UTC().After(expiryTime) {
 		msg.PackServerError(utils.ErrExpiredServerKey, nil)
-		writeResponse(w, msg)
 
 		// Delete expired keys
-		s.sessionKeys.Delete(sender)
-		return
+		if err = s.sessions.Delete(sender); err != nil {
+			log.Errorf("deleting the expired session of %v failed: %v", sender, err)
+		}
+		return nil
 	}
 
-	sharedKey := data.(servertypes.ServerKeyResp).SharedKey
+	sharedKey := session.SharedKey
 	if len(sharedKey) == 0 {
 		msg.PackServerError(utils.ErrInvalidSigningKey, nil)
-		writeResponse(w, msg)
+		return nil
+	}
+
+	return sharedKey
+}
+
+// executeBackendMsg authorises the sender of the validated message against
+// their session key before executing the method requested. The result or the
+// error returned is packed into the message. The node is queried with the
+// request's context so that the work is abandoned once the client goes away.
+func (s *ServerConfig) executeBackendMsg(ctx context.Context, msg *servertypes.RPCMessage,
+	methodType utils.MethodType,
+) {
+	// Only allow contract, local, signed tx and admin type methods to be executed.
+	if methodType != utils.ContractType && methodType != utils.LocalType &&
+		methodType != utils.SignedTxType && methodType != utils.AdminType {
+		err := fmt.Errorf("unsupported method %s found for this route", msg.Method)
+		msg.PackServerError(utils.ErrUnknownMethod, err)
 		return
 	}
 
-	// extracts the private key from the signing key sent. The private key is
-	// required to sign all tx by the current sender.
-	privKey, err := utils.DecryptAES(sharedKey, msg.Sender.SigningKey)
-	if err != nil {
-		msg.PackServerError(utils.ErrInvalidSigningKey, err)
-		writeResponse(w, msg)
+	sender := msg.Sender.Address
+	sharedKey := s.loadSessionKey(msg)
+	if msg.Error != nil {
 		return
 	}
 
-	s.backend.SetClientSigningKey(privKey)
+	// Users vouched for by suspended or revoked trust organisations are denied access.
+	s.checkSenderOrg(msg)
+	if msg.Error != nil {
+		return
+	}
 
-	// Create an authorized transactor.
-	auth := s.backend.Transactor(sender)
+	if methodType == utils.AdminType {
+		s.executeAdminMsg(msg)
+		return
+	}
+
+	var privKey []byte
+	var err error
+
+	switch {
+	case msg.Sender.SigningKey != "" && !s.signingMode.ServerSigns():
+		err = errors.New("private keys are not accepted, sign the transactions instead")
+		msg.PackServerError(utils.ErrSigningDisabled, err)
+		return
+
+	case msg.Sender.SigningKey != "":
+		// extracts the private key from the signing key sent. The private key is
+		// required to sign all tx by the current sender.
+		privKey, err = utils.DecryptAES(sharedKey, msg.Sender.SigningKey)
+		if err != nil {
+			msg.PackServerError(utils.ErrInvalidSigningKey, err)
+			return
+		}
+	}
+
+	// Create a transactor authorized to sign with the sender's key only.
+	auth := s.backend.Transactor(ctx, sender, privKey)
 	transactor := contracts.ChatRaw{Contract: s.bondChat}
 
 	var res interface{}
 
 	switch methodType {
 	case utils.ContractType:
+		if privKey == nil {
+			// Return the unsigned tx for the client to sign.
+			res, err = s.buildUnsignedTx(ctx, msg)
+			break
+		}
+
+		var input []byte
+		input, err = packInput(msg)
+		if err != nil {
+			break
+		}
+
 		var tx *types.Transaction
-		tx, err = transactor.Transact(auth, string(msg.Method), msg.Params...)
-		if err != nil && tx != nil {
+		tx, err = s.backend.SubmitTx(ctx, auth, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return transactor.Transact(opts, string(msg.Method), msg.Params...)
+		})
+		observeTx(msg.Method, err)
+		if err == nil && tx != nil {
+			s.txTracker.track(msg.Sender, msg.Method, tx, input)
+
 			// Return the tx hash for contract backend methods executed successfully.
 			res = struct {
 				TxHash string `json:"tx_hash"`
@@ -265,6 +557,29 @@ func (s *ServerConfig) backendQueryFunc(w http.ResponseWriter, req *http.Request
 			}
 		}
 
+	case utils.SignedTxType:
+		if msg.Method == utils.SendSignedCall {
+			res, err = s.sendSignedCall(ctx, msg)
+			if msg.Error != nil {
+				return
+			}
+			break
+		}
+
+		var txHash common.Hash
+		txHash, err = s.sendSignedTx(ctx, msg)
+		observeTx(msg.Method, err)
+		if msg.Error != nil {
+			return
+		}
+		if err == nil {
+			res = struct {
+				TxHash string `json:"tx_hash"`
+			}{
+				TxHash: txHash.String(),
+			}
+		}
+
 	case utils.LocalType:
 		switch msg.Method {
 		case utils.GetBonds:
@@ -272,18 +587,68 @@ func (s *ServerConfig) backendQueryFunc(w http.ResponseWriter, req *http.Request
 				msg.Sender.Address.String(), msg.Params...)
 
 		case utils.GetBondByAddress:
+			var include bool
+			if include, err = s.includePending(msg); err != nil {
+				msg.PackServerError(utils.ErrUnknownParam, err)
+				return
+			}
+
 			var arrayData []interface{}
 			arrayData, err = s.db.QueryLocalData(msg.Method, new(servertypes.BondByAddressResp),
-				msg.Sender.Address.String(), msg.Params...)
+				msg.Sender.Address.String(), msg.Params[:1]...)
 			// data response expected is just one record here.
 			if len(arrayData) > 0 {
-				res = arrayData[0]
+				bond := arrayData[0].(*servertypes.BondByAddressResp)
+				if include {
+					bond.PendingEvents = s.pending.list(bond.BondAddress)
+				}
+				res = bond
 			}
 
 		case utils.GetChats:
-			res, err = s.db.QueryLocalData(msg.Method, new(servertypes.ChatMsgsResp),
+			var include bool
+			if include, err = s.includePending(msg); err != nil {
+				msg.PackServerError(utils.ErrUnknownParam, err)
+				return
+			}
+
+			var chats []interface{}
+			chats, err = s.db.QueryLocalData(msg.Method, new(servertypes.ChatMsgsResp),
+				msg.Sender.Address.String(), msg.Params[:3]...)
+
+			// Only the first page is preceded by the pending chats.
+			bondAddress := msg.Params[0].(common.Address)
+			if include && msg.Params[2].(uint16) == 0 && s.isBondVisible(sender, bondAddress) {
+				chats = append(s.pending.chats(bondAddress), chats...)
+			}
+			res = chats
+
+		case utils.GetTxStatus:
+			// Hashes are persisted in their lowercase hex format.
+			txHash := common.HexToHash(msg.Params[0].(string)).String()
+
+			var arrayData []interface{}
+			arrayData, err = s.db.QueryLocalData(msg.Method, new(servertypes.TxStatusResp),
+				msg.Sender.Address.String(), txHash)
+			// data response expected is just one record here.
+			if len(arrayData) > 0 {
+				res = arrayData[0]
+			}
+
+		case utils.GetMyPendingTxs:
+			res, err = s.db.QueryLocalData(msg.Method, new(servertypes.TxStatusResp),
 				msg.Sender.Address.String(), msg.Params...)
 
+		case utils.GetBondSecureDetails:
+			res, err = s.getBondSecureDetails(ctx, msg, privKey)
+			if msg.Error != nil {
+				return
+			}
+
+		case utils.ReferUser, utils.GetUserProfile:
+			s.executeReferralMsg(msg)
+			return
+
 		default:
 			err = fmt.Errorf("missing implementation for method %s", msg.Method)
 		}
@@ -295,13 +660,237 @@ func (s *ServerConfig) backendQueryFunc(w http.ResponseWriter, req *http.Request
 	}
 
 	if err != nil {
-		msg.PackServerError(utils.ErrInternalFailure, err)
-		writeResponse(w, msg)
+		packExecError(msg, err)
 		return
 	}
 
 	msg.PackServerResult(res)
-	writeResponse(w, msg)
+}
+
+// includePending returns the optional last param requesting the events yet to
+// be confirmed. An error is returned if they are requested while the pending
+// events view is disabled.
+func (s *ServerConfig) includePending(msg *servertypes.RPCMessage) (bool, error) {
+	include := msg.Params[len(msg.Params)-1].(bool)
+	if include && s.pending == nil {
+		return false, errors.New("pending events view is disabled")
+	}
+	return include, nil
+}
+
+// packExecError packs the error returned while executing the method into the
+// message. Failed Sapphire calls are mapped to their own error codes so that
+// the contract revert reasons reach the client.
+func packExecError(msg *servertypes.RPCMessage, err error) {
+	var callErr *sapphire.CallError
+	switch {
+	case errors.As(err, &callErr) && callErr.IsReverted():
+		desc := error(callErr)
+		if callErr.Reason != "" {
+			desc = errors.New(callErr.Reason)
+		}
+		msg.PackServerError(utils.ErrContractReverted, desc)
+
+	case errors.As(err, &callErr):
+		msg.PackServerError(utils.ErrSapphireCall, callErr)
+
+	default:
+		msg.PackServerError(utils.ErrInternalFailure, err)
+	}
+}
+
+// buildUnsignedTx returns the unsigned transaction executing the contract
+// method requested, for the sender to sign.
+func (s *ServerConfig) buildUnsignedTx(ctx context.Context, msg *servertypes.RPCMessage,
+) (*servertypes.UnsignedTxResp, error) {
+	input, err := packInput(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, signingHash, err := s.backend.UnsignedTx(ctx, msg.Sender.Address, s.contractAddr, input)
+	if err != nil {
+		return nil, err
+	}
+
+	rawTx, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &servertypes.UnsignedTxResp{
+		UnsignedTx:  hexutil.Encode(rawTx),
+		SigningHash: signingHash.String(),
+		ChainID:     s.backend.ChainID().String(),
+	}, nil
+}
+
+// packInput returns the contract input of the method requested.
+func packInput(msg *servertypes.RPCMessage) ([]byte, error) {
+	chatABI, err := contracts.ChatMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := chatABI.Pack(string(msg.Method), msg.Params...)
+	if err != nil {
+		return nil, fmt.Errorf("packing the %s method params failed: %v", msg.Method, err)
+	}
+	return input, nil
+}
+
+// sendSignedTx attaches the sender's signature to the unsigned transaction
+// provided and broadcasts it. Invalid transactions and signatures are packed
+// into the message as errors.
+func (s *ServerConfig) sendSignedTx(ctx context.Context, msg *servertypes.RPCMessage) (common.Hash, error) {
+	if !s.signingMode.ClientSigns() {
+		err := errors.New("client signed transactions are not accepted")
+		msg.PackServerError(utils.ErrSigningDisabled, err)
+		return common.Hash{}, err
+	}
+
+	tx, err := decodeUnsignedTx(msg.Params[0].(string))
+	if err != nil {
+		msg.PackServerError(utils.ErrInvalidSignedTx, err)
+		return common.Hash{}, err
+	}
+
+	// Only transactions sent to the server's contract are broadcasted.
+	if tx.To() == nil || *tx.To() != s.contractAddr {
+		err = fmt.Errorf("expected tx to be sent to contract %v", s.contractAddr)
+		msg.PackServerError(utils.ErrInvalidSignedTx, err)
+		return common.Hash{}, err
+	}
+
+	signature, err := hexutil.Decode(msg.Params[1].(string))
+	if err != nil {
+		msg.PackServerError(utils.ErrInvalidSignedTx, fmt.Errorf("invalid signature: %v", err))
+		return common.Hash{}, err
+	}
+
+	signedTx, err := s.backend.WithClientSignature(tx, signature, msg.Sender.Address)
+	if err != nil {
+		msg.PackServerError(utils.ErrInvalidSignedTx, err)
+		return common.Hash{}, err
+	}
+
+	if err = s.backend.SendTransaction(ctx, signedTx); err != nil {
+		return common.Hash{}, err
+	}
+
+	s.backend.Nonces().Observe(msg.Sender.Address, signedTx.Nonce())
+	// The client signed tx data is encrypted, its plain input is unknown.
+	s.txTracker.track(msg.Sender, msg.Method, signedTx, nil)
+	return signedTx.Hash(), nil
+}
+
+// getBondSecureDetails reads the bond security and appendix details from the
+// contract using a call signed with the sender's private key. Without the
+// private key, the signed call payload is returned for the client to sign.
+func (s *ServerConfig) getBondSecureDetails(ctx context.Context, msg *servertypes.RPCMessage,
+	privKey []byte,
+) (interface{}, error) {
+	if privKey == nil {
+		if !s.signingMode.ClientSigns() {
+			err := errors.New("signed calls require the sender's signing key")
+			msg.PackServerError(utils.ErrSignerKeyMissing, err)
+			return nil, err
+		}
+		return s.buildSignableCall(ctx, msg, nil)
+	}
+
+	opts := s.backend.CallOpts(ctx, msg.Sender.Address, privKey)
+	return s.readBondSecureDetails(msg, opts)
+}
+
+// buildSignableCall returns the EIP-712 signed call payload reading the bond
+// secure details at the provided block number, or the latest if not set, for
+// the sender to sign.
+func (s *ServerConfig) buildSignableCall(ctx context.Context, msg *servertypes.RPCMessage,
+	blockNumber *big.Int,
+) (*sapphire.UnsignedCall, error) {
+	chatABI, err := contracts.ChatMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := chatABI.Pack(string(utils.GetBondSecureDetails), msg.Params[0])
+	if err != nil {
+		return nil, fmt.Errorf("packing the %s method params failed: %v",
+			utils.GetBondSecureDetails, err)
+	}
+
+	// The call message matches the one made by the contract bindings once
+	// the signature is sent back.
+	call := ethereum.CallMsg{From: msg.Sender.Address, To: &s.contractAddr, Data: input}
+	return s.backend.SignableCall(ctx, call, blockNumber)
+}
+
+// sendSignedCall reads the bond secure details using the signed call payload
+// returned by getBondSecureDetails, once signed by the sender. Invalid
+// signatures are packed into the message as errors.
+func (s *ServerConfig) sendSignedCall(ctx context.Context, msg *servertypes.RPCMessage,
+) (*servertypes.BondSecureDetailsResp, error) {
+	if !s.signingMode.ClientSigns() {
+		err := errors.New("client signed calls are not accepted")
+		msg.PackServerError(utils.ErrSigningDisabled, err)
+		return nil, err
+	}
+
+	signature, err := hexutil.Decode(msg.Params[2].(string))
+	if err != nil {
+		msg.PackServerError(utils.ErrInvalidSignedCall, fmt.Errorf("invalid signature: %v", err))
+		return nil, err
+	}
+
+	// The payload signed is rebuilt at the block number it was returned with.
+	blockNumber := new(big.Int).SetUint64(msg.Params[1].(uint64))
+	unsignedCall, err := s.buildSignableCall(ctx, msg, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = unsignedCall.VerifySigner(signature, msg.Sender.Address); err != nil {
+		msg.PackServerError(utils.ErrInvalidSignedCall, err)
+		return nil, err
+	}
+
+	opts := s.backend.ClientSignedCallOpts(ctx, msg.Sender.Address, blockNumber, signature)
+	return s.readBondSecureDetails(msg, opts)
+}
+
+// readBondSecureDetails reads the bond secure details from the contract using
+// the signed call options provided.
+func (s *ServerConfig) readBondSecureDetails(msg *servertypes.RPCMessage,
+	opts *bind.CallOpts,
+) (*servertypes.BondSecureDetailsResp, error) {
+	bondAddress := msg.Params[0].(common.Address)
+	details, err := s.bondChat.GetBondSecureDetails(opts, bondAddress)
+	if err != nil {
+		if decodeRevertReason(err) == notBondPartyReason {
+			msg.PackServerError(utils.ErrNotBondParty, err)
+		}
+		return nil, err
+	}
+
+	return &servertypes.BondSecureDetailsResp{
+		Security: details.Security,
+		Appendix: details.Appendix,
+	}, nil
+}
+
+// decodeUnsignedTx decodes the hex encoded unsigned transaction.
+func decodeUnsignedTx(rawTx string) (*types.Transaction, error) {
+	data, err := hexutil.Decode(rawTx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unsigned tx: %v", err)
+	}
+
+	tx := new(types.Transaction)
+	if err = tx.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("invalid unsigned tx: %v", err)
+	}
+	return tx, nil
 }
 
 // castType returns the parameter cast to the required parameter type.
@@ -313,6 +902,12 @@ func castType(param interface{}, pType utils.ParamType) (v interface{}, err erro
 	typeFound := "unsupported"
 
 	switch t := param.(type) {
+	case bool:
+		if pType == utils.OptionalBoolType {
+			v = t
+		} else {
+			typeFound = "bool"
+		}
 	case string:
 		switch pType {
 		case utils.AddressType:
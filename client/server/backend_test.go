@@ -3,12 +3,16 @@ package server
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -18,14 +22,21 @@ import (
 	"github.com/dmigwi/dhamana-protocol/client/servertypes"
 	"github.com/dmigwi/dhamana-protocol/client/utils"
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 var (
+	serverSessions = newMemSessions()
+
 	serverConf = &ServerConfig{
-		sessionKeys: new(sync.Map),
+		sessions:    serverSessions,
+		challenges:  newChallengeStore("https://0.0.0.0:30443", big.NewInt(1337), serverSessions),
+		signingMode: utils.ServerSigning,
+		ctx:         context.Background(),
 	}
 
 	sampleHexAddress = common.HexToAddress("0x3396FD816Dd81100477c8ea3853039822f36B7ed")
@@ -44,6 +55,8 @@ var (
 	sharedKey1 = "0x1e933d207f79abbfe644471e3a5e2d1907091f01a282dbf0861c89c8c79bb925"
 	sharedKey2 = "0xef0f3ee401b103a5b535673a1d5f7638903e59774d204d6784faeb674a6fb068"
 	sharedKey3 = "0x43915b03de29fe619623309517993af22c71b0bdf94b9994700337f07a4682f6"
+
+	sampleSessionToken = "0x5f1c0e2ab7b8d3c0a6e1e5f36e1d0c1b9a7d2f4e8c3b6a5d4e9f0a1b2c3d4e5f"
 )
 
 type input struct {
@@ -72,19 +85,21 @@ func TestMain(m *testing.M) {
 
 		// Store expired keys
 		expiredKey := servertypes.ServerKeyResp{
-			Pubkey:    pubkey1,
-			Expiry:    uint64(time.Now().UTC().Unix()),
-			SharedKey: key1,
+			Pubkey:       pubkey1,
+			Expiry:       uint64(time.Now().UTC().Unix()),
+			SharedKey:    key1,
+			SessionToken: sampleSessionToken,
 		}
-		serverConf.sessionKeys.Store(sampleHexAddress1, expiredKey)
+		serverConf.sessions.Store(sampleHexAddress1, expiredKey)
 
 		// store fresh keys with an expiry of 2 minutes
 		freshKey := servertypes.ServerKeyResp{
-			Pubkey:    pubkey2,
-			Expiry:    uint64(time.Now().UTC().Add(10 * time.Minute).Unix()),
-			SharedKey: key2,
+			Pubkey:       pubkey2,
+			Expiry:       uint64(time.Now().UTC().Add(10 * time.Minute).Unix()),
+			SharedKey:    key2,
+			SessionToken: sampleSessionToken,
 		}
-		serverConf.sessionKeys.Store(sampleHexAddress2, freshKey)
+		serverConf.sessions.Store(sampleHexAddress2, freshKey)
 
 		m.Run()
 	} else {
@@ -242,7 +257,7 @@ func TestDecodeRequestBody(t *testing.T) {
 			val: output{
 				errCode:    1007,
 				shortErr:   utils.ErrMissingParams,
-				longErr:    "method getServerPubKey requires 1 params found 2 params",
+				longErr:    "method getServerPubKey requires 3 params found 2 params",
 				methodType: utils.UnknownType,
 			},
 		},
@@ -257,7 +272,7 @@ func TestDecodeRequestBody(t *testing.T) {
 					Sender: &servertypes.SenderInfo{
 						Address: sampleHexAddress,
 					},
-					Params: []interface{}{int(200)},
+					Params: []interface{}{int(200), "nonce", "signature"},
 				},
 			},
 			val: output{
@@ -267,6 +282,27 @@ func TestDecodeRequestBody(t *testing.T) {
 				methodType: utils.UnknownType,
 			},
 		},
+		{
+			data: input{
+				testName:   "Test-for-optional-param-type-mismatch",
+				method:     http.MethodPost,
+				needSigner: false,
+				body: servertypes.RPCMessage{
+					Version: "2.0",
+					Method:  utils.GetBondByAddress,
+					Sender: &servertypes.SenderInfo{
+						Address: sampleHexAddress,
+					},
+					Params: []interface{}{sampleHexAddress1, "true"},
+				},
+			},
+			val: output{
+				errCode:    1009,
+				shortErr:   utils.ErrUnknownParam,
+				longErr:    "expected param true to be of type bool_OPTIONAL but found it to be string",
+				methodType: utils.UnknownType,
+			},
+		},
 		{
 			data: input{
 				testName:   "Test-for-successful-serverkey-method",
@@ -278,7 +314,7 @@ func TestDecodeRequestBody(t *testing.T) {
 					Sender: &servertypes.SenderInfo{
 						Address: sampleHexAddress,
 					},
-					Params: []interface{}{"client-pub-key"},
+					Params: []interface{}{"client-pub-key", "nonce", "signature"},
 				},
 			},
 			val: output{
@@ -344,7 +380,7 @@ func TestDecodeRequestBody(t *testing.T) {
 			req := httptest.NewRequest(string(v.data.method), "/random-path", &buf)
 
 			msg := servertypes.RPCMessage{}
-			retType := decodeRequestBody(req, &msg, v.data.needSigner)
+			retType, _ := decodeRequestBody(req, &msg, v.data.needSigner)
 
 			if retType != v.val.methodType {
 				t.Fatalf("expected returned method type to be %v but found %v",
@@ -448,10 +484,18 @@ func (m *mockWrapper) SubscribeFilterLogs(ctx context.Context, query ethereum.Fi
 	return nil, nil
 }
 
+// mockSigner signs the digest with the hex encoded private key provided.
+func mockSigner(digest [32]byte, privateKey []byte) ([]byte, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(string(privateKey), "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Sign(digest[:], key)
+}
+
 func genMockWrapper(ctx context.Context) error {
 	conn := &mockWrapper{}
-	backend, err := sapphire.WrapClient(ctx, conn, utils.LocalTesting,
-		func(digest [32]byte, _ []byte) ([]byte, error) { return digest[:], nil })
+	backend, err := sapphire.WrapClient(ctx, conn, utils.LocalTesting, mockSigner)
 	if err != nil {
 		return err
 	}
@@ -464,12 +508,39 @@ func genMockWrapper(ctx context.Context) error {
 
 	serverConf.backend = backend
 	serverConf.bondChat = chatInstance
+	serverConf.contractAddr = sampleHexAddress
 
 	return nil
 }
 
 // TestServerPubkey tests unique functionality implemented in serverPubkey method.
 func TestServerPubkey(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	otherSigner := crypto.PubkeyToAddress(otherKey.PublicKey)
+	defer serverConf.sessions.Delete(sender)
+
+	// challenge returns the nonce of a new challenge of the sender provided
+	// and its signature by the provided key.
+	challenge := func(sender common.Address, key *ecdsa.PrivateKey) (string, string) {
+		nonce, c, err := serverConf.challenges.issue(sender, remoteAddr)
+		if err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+
+		sig, err := crypto.Sign(accounts.TextHash([]byte(c.message)), key)
+		if err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+		return nonce, hexutil.Encode(sig)
+	}
+
+	// Each sender holds a single pending challenge.
+	nonce, sig := challenge(sender, key)
+	otherNonce, otherSig := challenge(sampleHexAddress1, otherKey)
+
 	testdata := []struct {
 		data input
 		val  output
@@ -494,6 +565,60 @@ func TestServerPubkey(t *testing.T) {
 				longErr:  "unsupported method getBondByAddress found for this route",
 			},
 		},
+		{
+			data: input{
+				testName: "Test-for-successful-access-to-session-challenge",
+				method:   http.MethodPost,
+				body: servertypes.RPCMessage{
+					ID:      20,
+					Version: "2.0",
+					Method:  utils.GetSessionChallenge,
+					Sender: &servertypes.SenderInfo{
+						Address: sampleHexAddress2,
+					},
+				},
+			},
+		},
+		{
+			data: input{
+				testName: "Test-for-unknown-challenge-nonce",
+				method:   http.MethodPost,
+				body: servertypes.RPCMessage{
+					ID:      20,
+					Version: "2.0",
+					Method:  utils.GetServerPubKey,
+					Sender: &servertypes.SenderInfo{
+						Address: sender,
+					},
+					Params: []interface{}{pubkey2, "0x01", sig},
+				},
+			},
+			val: output{
+				errCode:  1017,
+				shortErr: utils.ErrInvalidChallenge,
+				longErr:  "no session challenge found for the sender",
+			},
+		},
+		{
+			data: input{
+				testName: "Test-for-challenge-signed-by-another-key",
+				method:   http.MethodPost,
+				body: servertypes.RPCMessage{
+					ID:      20,
+					Version: "2.0",
+					Method:  utils.GetServerPubKey,
+					Sender: &servertypes.SenderInfo{
+						Address: sampleHexAddress1,
+					},
+					Params: []interface{}{pubkey2, otherNonce, otherSig},
+				},
+			},
+			val: output{
+				errCode:  1017,
+				shortErr: utils.ErrInvalidChallenge,
+				longErr:  fmt.Sprintf("challenge signed by %v instead of the sender", otherSigner),
+			},
+		},
 		{
 			data: input{
 				testName: "Test-for-successful-access-to-serverkey-method",
@@ -503,11 +628,31 @@ func TestServerPubkey(t *testing.T) {
 					Version: "2.0",
 					Method:  utils.GetServerPubKey,
 					Sender: &servertypes.SenderInfo{
-						Address: sampleHexAddress,
+						Address: sender,
+					},
+					Params: []interface{}{pubkey2, nonce, sig},
+				},
+			},
+		},
+		{
+			data: input{
+				testName: "Test-for-reused-challenge",
+				method:   http.MethodPost,
+				body: servertypes.RPCMessage{
+					ID:      20,
+					Version: "2.0",
+					Method:  utils.GetServerPubKey,
+					Sender: &servertypes.SenderInfo{
+						Address: sender,
 					},
-					Params: []interface{}{pubkey2},
+					Params: []interface{}{pubkey2, nonce, sig},
 				},
 			},
+			val: output{
+				errCode:  1017,
+				shortErr: utils.ErrInvalidChallenge,
+				longErr:  "no session challenge found for the sender",
+			},
 		},
 	}
 
@@ -534,6 +679,19 @@ func TestServerPubkey(t *testing.T) {
 					int(v.val.methodType))
 			}
 
+			if msg.Error == nil && v.data.body.(servertypes.RPCMessage).Method == utils.GetSessionChallenge {
+				var result servertypes.SessionChallengeResp
+				_ = json.Unmarshal(msg.Result, &result)
+
+				if result.Nonce == "" || !strings.Contains(result.Message, result.Nonce) {
+					t.Fatalf("expected the challenge message %q to hold the nonce %q",
+						result.Message, result.Nonce)
+				}
+
+				// No error was expected, prevent further error check.
+				return
+			}
+
 			if msg.Error == nil {
 				var result servertypes.ServerKeyResp
 				_ = json.Unmarshal(msg.Result, &result)
@@ -548,6 +706,13 @@ func TestServerPubkey(t *testing.T) {
 					t.Fatalf("expected the server pubkey expiry %q to be before %q", expired, now)
 				}
 
+				session, _, _ := serverConf.sessions.Load(sender)
+				if token := session.SessionToken; token == "" ||
+					token != result.SessionToken {
+					t.Fatalf("expected the session token %q to be stored but found %q",
+						result.SessionToken, token)
+				}
+
 				// No error was expected, prevent further error check.
 				return
 			}
@@ -584,18 +749,18 @@ func TestBackendQueryFunc(t *testing.T) {
 				body: servertypes.RPCMessage{
 					ID:      20,
 					Version: "2.0",
-					Method:  utils.GetServerPubKey,
+					Method:  utils.GetSessionChallenge,
 					Sender: &servertypes.SenderInfo{
-						Address:    sampleHexAddress2,
-						SigningKey: sampleSigningKey,
+						Address:      sampleHexAddress2,
+						SessionToken: sampleSessionToken,
+						SigningKey:   sampleSigningKey,
 					},
-					Params: []interface{}{pubkey2},
 				},
 			},
 			val: output{
 				errCode:  1008,
 				shortErr: utils.ErrUnknownMethod,
-				longErr:  "unsupported method getServerPubKey found for this route",
+				longErr:  "unsupported method getSessionChallenge found for this route",
 			},
 		},
 		{
@@ -619,6 +784,28 @@ func TestBackendQueryFunc(t *testing.T) {
 				longErr:  "no server keys found associated with the sender",
 			},
 		},
+		{
+			data: input{
+				testName: "Test-for-invalid-session-token",
+				method:   http.MethodPost,
+				body: servertypes.RPCMessage{
+					ID:      20,
+					Version: "2.0",
+					Method:  utils.SignBondStatus,
+					Sender: &servertypes.SenderInfo{
+						Address:      sampleHexAddress2,
+						SessionToken: "0x01",
+						SigningKey:   sampleSigningKey,
+					},
+					Params: []interface{}{sampleHexAddress},
+				},
+			},
+			val: output{
+				errCode:  1018,
+				shortErr: utils.ErrInvalidSession,
+				longErr:  "session token doesn't match the sender's session",
+			},
+		},
 		{
 			data: input{
 				testName: "Test-for-expired-server-keys",
@@ -628,8 +815,9 @@ func TestBackendQueryFunc(t *testing.T) {
 					Version: "2.0",
 					Method:  utils.SignBondStatus,
 					Sender: &servertypes.SenderInfo{
-						Address:    sampleHexAddress1,
-						SigningKey: sampleSigningKey,
+						Address:      sampleHexAddress1,
+						SessionToken: sampleSessionToken,
+						SigningKey:   sampleSigningKey,
 					},
 					Params: []interface{}{sampleHexAddress},
 				},
@@ -640,6 +828,28 @@ func TestBackendQueryFunc(t *testing.T) {
 				longErr:  "",
 			},
 		},
+		{
+			data: input{
+				testName: "Test-for-disabled-pending-events-view",
+				method:   http.MethodPost,
+				body: servertypes.RPCMessage{
+					ID:      20,
+					Version: "2.0",
+					Method:  utils.GetChats,
+					Sender: &servertypes.SenderInfo{
+						Address:      sampleHexAddress2,
+						SessionToken: sampleSessionToken,
+						SigningKey:   sampleSigningKey,
+					},
+					Params: []interface{}{sampleHexAddress, 10, 0, true},
+				},
+			},
+			val: output{
+				errCode:  1009,
+				shortErr: utils.ErrUnknownParam,
+				longErr:  "pending events view is disabled",
+			},
+		},
 		{
 			data: input{
 				testName: "Test-for-successful-access-to-contract-method-with-no-param",
@@ -649,8 +859,9 @@ func TestBackendQueryFunc(t *testing.T) {
 					Version: "2.0",
 					Method:  utils.CreateBond,
 					Sender: &servertypes.SenderInfo{
-						Address:    sampleHexAddress2,
-						SigningKey: sampleSigningKey,
+						Address:      sampleHexAddress2,
+						SessionToken: sampleSessionToken,
+						SigningKey:   sampleSigningKey,
 					},
 				},
 			},
@@ -664,8 +875,9 @@ func TestBackendQueryFunc(t *testing.T) {
 					Version: "2.0",
 					Method:  utils.SignBondStatus,
 					Sender: &servertypes.SenderInfo{
-						Address:    sampleHexAddress2,
-						SigningKey: sampleSigningKey,
+						Address:      sampleHexAddress2,
+						SessionToken: sampleSessionToken,
+						SigningKey:   sampleSigningKey,
 					},
 					Params: []interface{}{sampleHexAddress},
 				},
@@ -680,8 +892,9 @@ func TestBackendQueryFunc(t *testing.T) {
 					Version: "2.0",
 					Method:  utils.UpdateBondStatus,
 					Sender: &servertypes.SenderInfo{
-						Address:    sampleHexAddress2,
-						SigningKey: sampleSigningKey,
+						Address:      sampleHexAddress2,
+						SessionToken: sampleSessionToken,
+						SigningKey:   sampleSigningKey,
 					},
 					Params: []interface{}{sampleHexAddress, 2},
 				},
@@ -739,3 +952,854 @@ func TestBackendQueryFunc(t *testing.T) {
 		})
 	}
 }
+
+// TestBackendQueryFuncBatch tests the JSON-RPC 2.0 batch requests support
+// implemented in backendQueryFunc method.
+func TestBackendQueryFuncBatch(t *testing.T) {
+	testdata := []struct {
+		testName string
+		body     interface{}
+		val      []output
+	}{
+		{
+			testName: "Test-for-empty-batch",
+			body:     []servertypes.RPCMessage{},
+			val: []output{
+				{
+					errCode:  1001,
+					shortErr: utils.ErrInvalidReq,
+					longErr:  "empty batch request found",
+				},
+			},
+		},
+		{
+			testName: "Test-for-excess-batch-messages",
+			body:     make([]servertypes.RPCMessage, maxBatchSize+1),
+			val: []output{
+				{
+					errCode:  1001,
+					shortErr: utils.ErrInvalidReq,
+					longErr:  "expected a max of 20 batch messages but found 21",
+				},
+			},
+		},
+		{
+			testName: "Test-for-per-item-results-and-errors-in-order",
+			body: []interface{}{
+				servertypes.RPCMessage{
+					ID:      1,
+					Version: "2.0",
+					Method:  utils.CreateBond,
+					Sender: &servertypes.SenderInfo{
+						Address:      sampleHexAddress2,
+						SessionToken: sampleSessionToken,
+						SigningKey:   sampleSigningKey,
+					},
+				},
+				servertypes.RPCMessage{
+					ID:      2,
+					Version: "2.0",
+					Method:  utils.SignBondStatus,
+					Sender: &servertypes.SenderInfo{
+						Address:    sampleHexAddress3,
+						SigningKey: sampleSigningKey,
+					},
+					Params: []interface{}{sampleHexAddress},
+				},
+				"invalid-message",
+				servertypes.RPCMessage{
+					ID:      4,
+					Version: "2.0",
+					Method:  utils.UpdateBondStatus,
+					Sender: &servertypes.SenderInfo{
+						Address:      sampleHexAddress2,
+						SessionToken: sampleSessionToken,
+						SigningKey:   sampleSigningKey,
+					},
+					Params: []interface{}{sampleHexAddress, 2},
+				},
+			},
+			val: []output{
+				{},
+				{
+					errCode:  1011,
+					shortErr: utils.ErrMissingServerKey,
+					longErr:  "no server keys found associated with the sender",
+				},
+				{
+					errCode:  1001,
+					shortErr: utils.ErrInvalidReq,
+					longErr:  "json: cannot unmarshal string into Go value of type servertypes.RPCMessage",
+				},
+				{},
+			},
+		},
+	}
+
+	for _, v := range testdata {
+		t.Run(v.testName, func(t *testing.T) {
+			var buf bytes.Buffer
+			_ = json.NewEncoder(&buf).Encode(v.body) // error ignored since its not being tested.
+
+			responseWritter := httptest.NewRecorder()
+
+			serverConf.backendQueryFunc(responseWritter,
+				httptest.NewRequest(http.MethodPost, "/backend", &buf))
+
+			data, err := io.ReadAll(responseWritter.Body)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			var msgs []servertypes.RPCMessage
+			if err = json.Unmarshal(data, &msgs); err != nil {
+				// Errors affecting the whole batch are returned in a single message.
+				msgs = make([]servertypes.RPCMessage, 1)
+				_ = json.Unmarshal(data, &msgs[0])
+			}
+
+			if len(msgs) != len(v.val) {
+				t.Fatalf("expected %d responses but found %d", len(v.val), len(msgs))
+			}
+
+			for i, msg := range msgs {
+				if msg.Error == nil && v.val[i].shortErr != nil {
+					t.Fatalf("expected response %d to return an error but found none", i)
+				}
+
+				if msg.Error == nil {
+					if len(msg.Result) == 0 {
+						t.Fatalf("expected the Result data of response %d not to be empty", i)
+					}
+					continue
+				}
+
+				if msg.Error.Code != v.val[i].errCode {
+					t.Fatalf("expected returned error code of response %d to be %d but found %d",
+						i, v.val[i].errCode, msg.Error.Code)
+				}
+
+				errStr, _ := msg.Error.Data.(string)
+				if errStr != v.val[i].longErr {
+					t.Fatalf("expected returned long error of response %d to be %q but found %q",
+						i, v.val[i].longErr, errStr)
+				}
+			}
+		})
+	}
+}
+
+// TestBackendQueryFuncRequestSize tests that the single and the batch requests
+// whose body exceeds the request size limit are rejected.
+func TestBackendQueryFuncRequestSize(t *testing.T) {
+	oversized := strings.Repeat("a", maxRequestSize)
+
+	testdata := []struct {
+		testName string
+		body     interface{}
+	}{
+		{
+			testName: "Test-for-oversized-message",
+			body: servertypes.RPCMessage{
+				ID:      1,
+				Version: "2.0",
+				Method:  utils.CreateBond,
+				Params:  []interface{}{oversized},
+			},
+		},
+		{
+			testName: "Test-for-oversized-batch",
+			body: []servertypes.RPCMessage{
+				{ID: 1, Version: "2.0", Method: utils.CreateBond},
+				{ID: 2, Version: "2.0", Method: utils.CreateBond, Params: []interface{}{oversized}},
+			},
+		},
+	}
+
+	for _, v := range testdata {
+		t.Run(v.testName, func(t *testing.T) {
+			var buf bytes.Buffer
+			_ = json.NewEncoder(&buf).Encode(v.body) // error ignored since its not being tested.
+
+			responseWritter := httptest.NewRecorder()
+			serverConf.backendQueryFunc(responseWritter,
+				httptest.NewRequest(http.MethodPost, "/backend", &buf))
+
+			msg := servertypes.RPCMessage{}
+			if err := json.NewDecoder(responseWritter.Body).Decode(&msg); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if msg.Error == nil || msg.Error.Code != utils.GetErrorCode(utils.ErrInvalidReq) {
+				t.Fatalf("expected error %q but found %v", utils.ErrInvalidReq, msg.Error)
+			}
+
+			expected := fmt.Sprintf("expected a max of %d bytes request body", maxRequestSize)
+			if errStr, _ := msg.Error.Data.(string); errStr != expected {
+				t.Fatalf("expected returned long error to be %q but found %q", expected, errStr)
+			}
+		})
+	}
+}
+
+// TestBackendQueryFuncConcurrentSigners tests that the transactions submitted
+// concurrently by distinct senders are each signed by their own sender's key.
+func TestBackendQueryFuncConcurrentSigners(t *testing.T) {
+	const senders = 50
+
+	network, err := utils.GetNetworkConfig(utils.LocalTesting)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	input, err := contracts.ChatMetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	data, err := input.Pack(string(utils.CreateBond))
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	signer := types.LatestSignerForChainID(&network.ChainID)
+
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+
+		sharedKey := make([]byte, 32)
+		_, _ = rand.Read(sharedKey)
+
+		signingKey, err := utils.EncryptAES(sharedKey, []byte(hexutil.Encode(crypto.FromECDSA(key))))
+		if err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+
+		sender := crypto.PubkeyToAddress(key.PublicKey)
+		serverConf.sessions.Store(sender, servertypes.ServerKeyResp{
+			Expiry:       uint64(time.Now().UTC().Add(sessionTime).Unix()),
+			SharedKey:    sharedKey,
+			SessionToken: sampleSessionToken,
+		})
+		defer serverConf.sessions.Delete(sender)
+
+		// The tx expected if signed by the current sender.
+		expectedTx, err := types.SignNewTx(key, signer, &types.LegacyTx{
+			To:       &sampleHexAddress,
+			Value:    new(big.Int),
+			GasPrice: big.NewInt(sapphire.DefaultGasPrice),
+			Gas:      sapphire.DefaultGasLimit,
+			Data:     data,
+		})
+		if err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+
+		body := servertypes.RPCMessage{
+			ID:      uint16(i),
+			Version: "2.0",
+			Method:  utils.CreateBond,
+			Sender: &servertypes.SenderInfo{
+				Address:      sender,
+				SessionToken: sampleSessionToken,
+				SigningKey:   signingKey,
+			},
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var buf bytes.Buffer
+			_ = json.NewEncoder(&buf).Encode(body) // error ignored since its not being tested.
+
+			responseWritter := httptest.NewRecorder()
+			serverConf.backendQueryFunc(responseWritter,
+				httptest.NewRequest(http.MethodPost, "/backend", &buf))
+
+			msg := servertypes.RPCMessage{}
+			if err := json.NewDecoder(responseWritter.Body).Decode(&msg); err != nil {
+				t.Errorf("expected no error but found %q", err)
+				return
+			}
+
+			if msg.Error != nil {
+				t.Errorf("sender %v: expected no error but found %v", sender, msg.Error)
+				return
+			}
+
+			var res struct {
+				TxHash string `json:"tx_hash"`
+			}
+			_ = json.Unmarshal(msg.Result, &res)
+
+			if res.TxHash != expectedTx.Hash().String() {
+				t.Errorf("sender %v: expected tx %s signed by its own key but found %s",
+					sender, expectedTx.Hash(), res.TxHash)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// requestCtxKey is the context key identifying the request's context.
+type requestCtxKey struct{}
+
+// nonceCtxWrapper records the context the sender nonce was fetched with.
+type nonceCtxWrapper struct {
+	mockWrapper
+	ctx context.Context
+}
+
+func (m *nonceCtxWrapper) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	m.ctx = ctx
+	return 0, nil
+}
+
+// TestBackendQueryFuncRequestContext tests that the transactions are submitted
+// with the request's context rather than the server's.
+func TestBackendQueryFuncRequestContext(t *testing.T) {
+	conn := new(nonceCtxWrapper)
+	backend, err := sapphire.WrapClient(context.Background(), conn, utils.LocalTesting, mockSigner)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	chatInstance, err := contracts.NewChat(sampleHexAddress, backend)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	conf := &ServerConfig{
+		sessions:     newMemSessions(),
+		signingMode:  utils.ServerSigning,
+		ctx:          context.Background(),
+		backend:      backend,
+		bondChat:     chatInstance,
+		contractAddr: sampleHexAddress,
+	}
+
+	sharedKey, _ := hexutil.Decode(sharedKey2)
+	conf.sessions.Store(sampleHexAddress2, servertypes.ServerKeyResp{
+		Expiry:       uint64(time.Now().UTC().Add(sessionTime).Unix()),
+		SharedKey:    sharedKey,
+		SessionToken: sampleSessionToken,
+	})
+
+	var buf bytes.Buffer
+	_ = json.NewEncoder(&buf).Encode(servertypes.RPCMessage{
+		ID:      7,
+		Version: "2.0",
+		Method:  utils.CreateBond,
+		Sender: &servertypes.SenderInfo{
+			Address:      sampleHexAddress2,
+			SessionToken: sampleSessionToken,
+			SigningKey:   sampleSigningKey,
+		},
+	})
+
+	ctx := context.WithValue(context.Background(), requestCtxKey{}, "request")
+	req := httptest.NewRequest(http.MethodPost, "/backend", &buf).WithContext(ctx)
+
+	responseWritter := httptest.NewRecorder()
+	conf.backendQueryFunc(responseWritter, req)
+
+	msg := servertypes.RPCMessage{}
+	if err := json.NewDecoder(responseWritter.Body).Decode(&msg); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if msg.Error != nil {
+		t.Fatalf("expected no error but found %v", msg.Error)
+	}
+
+	if conn.ctx == nil || conn.ctx.Value(requestCtxKey{}) != "request" {
+		t.Fatal("expected the tx to be submitted with the request's context")
+	}
+}
+
+// devChainWrapper mocks a Development network client.
+type devChainWrapper struct {
+	mockWrapper
+}
+
+func (m *devChainWrapper) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100)}, nil
+}
+
+// TestBackendQueryFuncClientSigning tests the building of the unsigned
+// transactions and the broadcasting of the transactions signed by the client.
+func TestBackendQueryFuncClientSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+
+	sharedKey, _ := hexutil.Decode(sharedKey2)
+
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	serverConf.sessions.Store(sender, servertypes.ServerKeyResp{
+		Expiry:       uint64(time.Now().UTC().Add(sessionTime).Unix()),
+		SharedKey:    sharedKey,
+		SessionToken: sampleSessionToken,
+	})
+	defer serverConf.sessions.Delete(sender)
+
+	defer func() { serverConf.signingMode = utils.ServerSigning }()
+
+	// The senders of the client signed transactions are recovered using the
+	// chain ID of the Development network.
+	backend, err := sapphire.WrapClient(context.Background(), &devChainWrapper{},
+		utils.Development, mockSigner)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	defaultBackend := serverConf.backend
+	serverConf.backend = backend
+	defer func() { serverConf.backend = defaultBackend }()
+
+	query := func(t *testing.T, method utils.Method, signingKey string,
+		params ...interface{},
+	) servertypes.RPCMessage {
+		var buf bytes.Buffer
+		_ = json.NewEncoder(&buf).Encode(servertypes.RPCMessage{
+			ID:      20,
+			Version: "2.0",
+			Method:  method,
+			Sender: &servertypes.SenderInfo{
+				Address:      sender,
+				SessionToken: sampleSessionToken,
+				SigningKey:   signingKey,
+			},
+			Params: params,
+		})
+
+		responseWritter := httptest.NewRecorder()
+		serverConf.backendQueryFunc(responseWritter,
+			httptest.NewRequest(http.MethodPost, "/backend", &buf))
+
+		msg := servertypes.RPCMessage{}
+		if err := json.NewDecoder(responseWritter.Body).Decode(&msg); err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+		return msg
+	}
+
+	// buildTx returns the unsigned tx and its signature by the provided key.
+	buildTx := func(t *testing.T, key *ecdsa.PrivateKey) (string, string) {
+		msg := query(t, utils.SignBondStatus, "", sampleHexAddress.String())
+		if msg.Error != nil {
+			t.Fatalf("expected no error but found %v", msg.Error)
+		}
+
+		var res servertypes.UnsignedTxResp
+		if err := json.Unmarshal(msg.Result, &res); err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+
+		sig, err := crypto.Sign(common.HexToHash(res.SigningHash).Bytes(), key)
+		if err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+		return res.UnsignedTx, hexutil.Encode(sig)
+	}
+
+	t.Run("Test-for-missing-signer-key-in-server-signing-mode", func(t *testing.T) {
+		serverConf.signingMode = utils.ServerSigning
+
+		msg := query(t, utils.CreateBond, "")
+		if msg.Error == nil || msg.Error.Message != utils.ErrSignerKeyMissing.Error() {
+			t.Fatalf("expected error %q but found %v", utils.ErrSignerKeyMissing, msg.Error)
+		}
+	})
+
+	t.Run("Test-for-signed-tx-in-server-signing-mode", func(t *testing.T) {
+		serverConf.signingMode = utils.ServerSigning
+
+		msg := query(t, utils.SendSignedTx, sampleSigningKey, "0x00", "0x00")
+		if msg.Error == nil || msg.Error.Message != utils.ErrSigningDisabled.Error() {
+			t.Fatalf("expected error %q but found %v", utils.ErrSigningDisabled, msg.Error)
+		}
+	})
+
+	t.Run("Test-for-private-key-in-client-signing-mode", func(t *testing.T) {
+		serverConf.signingMode = utils.ClientSigning
+
+		msg := query(t, utils.CreateBond, sampleSigningKey)
+		if msg.Error == nil || msg.Error.Message != utils.ErrSigningDisabled.Error() {
+			t.Fatalf("expected error %q but found %v", utils.ErrSigningDisabled, msg.Error)
+		}
+	})
+
+	t.Run("Test-for-tx-signed-by-another-key", func(t *testing.T) {
+		serverConf.signingMode = utils.ClientSigning
+
+		unsignedTx, sig := buildTx(t, otherKey)
+		msg := query(t, utils.SendSignedTx, "", unsignedTx, sig)
+		if msg.Error == nil || msg.Error.Message != utils.ErrInvalidSignedTx.Error() {
+			t.Fatalf("expected error %q but found %v", utils.ErrInvalidSignedTx, msg.Error)
+		}
+	})
+
+	t.Run("Test-for-tx-signed-by-the-sender", func(t *testing.T) {
+		serverConf.signingMode = utils.AnySigning
+
+		unsignedTx, sig := buildTx(t, key)
+		msg := query(t, utils.SendSignedTx, "", unsignedTx, sig)
+		if msg.Error != nil {
+			t.Fatalf("expected no error but found %v", msg.Error)
+		}
+
+		var res struct {
+			TxHash string `json:"tx_hash"`
+		}
+		_ = json.Unmarshal(msg.Result, &res)
+
+		if res.TxHash == "" {
+			t.Fatal("expected the tx hash to be returned")
+		}
+	})
+}
+
+// secureDetailsWrapper mocks a client whose bond details are only readable
+// by the bond issuer.
+type secureDetailsWrapper struct {
+	mockWrapper
+	issuer  common.Address
+	details []byte
+	revert  error
+}
+
+func (m *secureDetailsWrapper) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100)}, nil
+}
+
+func (m *secureDetailsWrapper) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if call.From != m.issuer {
+		return nil, m.revert
+	}
+	return m.details, nil
+}
+
+// TestGetBondSecureDetails tests that the bond secure details are only
+// returned to the bond parties.
+func TestGetBondSecureDetails(t *testing.T) {
+	chatABI, err := contracts.ChatMetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	details, err := chatABI.Methods["getBondSecureDetails"].Outputs.Pack("Land title", "Valuation report")
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	issuerKey, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	issuer := crypto.PubkeyToAddress(issuerKey.PublicKey)
+
+	conn := &secureDetailsWrapper{
+		issuer:  issuer,
+		details: details,
+		revert:  revertErr{"execution reverted", packRevert(t, notBondPartyReason)},
+	}
+
+	backend, err := sapphire.WrapClient(context.Background(), conn, utils.LocalTesting, mockSigner)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	chatInstance, err := contracts.NewChat(sampleHexAddress, backend)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	conf := &ServerConfig{
+		sessions:     newMemSessions(),
+		signingMode:  utils.ServerSigning,
+		ctx:          context.Background(),
+		backend:      backend,
+		bondChat:     chatInstance,
+		contractAddr: sampleHexAddress,
+	}
+
+	sharedKey, _ := hexutil.Decode(sharedKey2)
+
+	// session returns the sender's signing key after storing their session.
+	session := func(t *testing.T, key *ecdsa.PrivateKey) (common.Address, string) {
+		signingKey, err := utils.EncryptAES(sharedKey, []byte(hexutil.Encode(crypto.FromECDSA(key))))
+		if err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+
+		sender := crypto.PubkeyToAddress(key.PublicKey)
+		conf.sessions.Store(sender, servertypes.ServerKeyResp{
+			Expiry:       uint64(time.Now().UTC().Add(sessionTime).Unix()),
+			SharedKey:    sharedKey,
+			SessionToken: sampleSessionToken,
+		})
+		return sender, signingKey
+	}
+
+	issuerAddr, issuerSigningKey := session(t, issuerKey)
+	otherAddr, otherSigningKey := session(t, otherKey)
+
+	td := []struct {
+		testName   string
+		sender     common.Address
+		signingKey string
+		err        error
+		expected   servertypes.BondSecureDetailsResp
+	}{
+		{
+			"Test-for-bond-issuer", issuerAddr, issuerSigningKey, nil,
+			servertypes.BondSecureDetailsResp{Security: "Land title", Appendix: "Valuation report"},
+		},
+		{
+			"Test-for-non-bond-party", otherAddr, otherSigningKey, utils.ErrNotBondParty,
+			servertypes.BondSecureDetailsResp{},
+		},
+		{
+			"Test-for-missing-signing-key", issuerAddr, "", utils.ErrSignerKeyMissing,
+			servertypes.BondSecureDetailsResp{},
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			var buf bytes.Buffer
+			_ = json.NewEncoder(&buf).Encode(servertypes.RPCMessage{
+				ID:      30,
+				Version: "2.0",
+				Method:  utils.GetBondSecureDetails,
+				Sender: &servertypes.SenderInfo{
+					Address:      v.sender,
+					SessionToken: sampleSessionToken,
+					SigningKey:   v.signingKey,
+				},
+				Params: []interface{}{sampleHexAddress.String()},
+			})
+
+			responseWritter := httptest.NewRecorder()
+			conf.backendQueryFunc(responseWritter,
+				httptest.NewRequest(http.MethodPost, "/backend", &buf))
+
+			msg := servertypes.RPCMessage{}
+			if err := json.NewDecoder(responseWritter.Body).Decode(&msg); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if v.err != nil {
+				if msg.Error == nil || msg.Error.Code != utils.GetErrorCode(v.err) {
+					t.Fatalf("expected error %q but found %v", v.err, msg.Error)
+				}
+				return
+			}
+
+			if msg.Error != nil {
+				t.Fatalf("expected no error but found %v", msg.Error)
+			}
+
+			var res servertypes.BondSecureDetailsResp
+			if err := json.Unmarshal(msg.Result, &res); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if res != v.expected {
+				t.Fatalf("expected bond details %+v but found %+v", v.expected, res)
+			}
+		})
+	}
+}
+
+// TestSendSignedCall tests that the bond secure details are only returned to
+// the bond parties signing the signed call payload returned to them.
+func TestSendSignedCall(t *testing.T) {
+	chatABI, err := contracts.ChatMetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	details, err := chatABI.Methods["getBondSecureDetails"].Outputs.Pack("Land title", "Valuation report")
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	issuerKey, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	issuer := crypto.PubkeyToAddress(issuerKey.PublicKey)
+	other := crypto.PubkeyToAddress(otherKey.PublicKey)
+
+	conn := &secureDetailsWrapper{
+		issuer:  issuer,
+		details: details,
+		revert:  revertErr{"execution reverted", packRevert(t, notBondPartyReason)},
+	}
+
+	backend, err := sapphire.WrapClient(context.Background(), conn, utils.LocalTesting, mockSigner)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	chatInstance, err := contracts.NewChat(sampleHexAddress, backend)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	conf := &ServerConfig{
+		sessions:     newMemSessions(),
+		signingMode:  utils.ClientSigning,
+		ctx:          context.Background(),
+		backend:      backend,
+		bondChat:     chatInstance,
+		contractAddr: sampleHexAddress,
+	}
+
+	sharedKey, _ := hexutil.Decode(sharedKey2)
+	for _, sender := range []common.Address{issuer, other} {
+		conf.sessions.Store(sender, servertypes.ServerKeyResp{
+			Expiry:       uint64(time.Now().UTC().Add(sessionTime).Unix()),
+			SharedKey:    sharedKey,
+			SessionToken: sampleSessionToken,
+		})
+	}
+
+	query := func(t *testing.T, sender common.Address, method utils.Method,
+		params ...interface{},
+	) servertypes.RPCMessage {
+		var buf bytes.Buffer
+		_ = json.NewEncoder(&buf).Encode(servertypes.RPCMessage{
+			ID:      31,
+			Version: "2.0",
+			Method:  method,
+			Sender: &servertypes.SenderInfo{
+				Address:      sender,
+				SessionToken: sampleSessionToken,
+			},
+			Params: params,
+		})
+
+		responseWritter := httptest.NewRecorder()
+		conf.backendQueryFunc(responseWritter,
+			httptest.NewRequest(http.MethodPost, "/backend", &buf))
+
+		msg := servertypes.RPCMessage{}
+		if err := json.NewDecoder(responseWritter.Body).Decode(&msg); err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+		return msg
+	}
+
+	td := []struct {
+		testName   string
+		sender     common.Address
+		key        *ecdsa.PrivateKey
+		recoveryID byte
+		err        error
+		expected   servertypes.BondSecureDetailsResp
+	}{
+		{
+			"Test-for-bond-issuer", issuer, issuerKey, 0, nil,
+			servertypes.BondSecureDetailsResp{Security: "Land title", Appendix: "Valuation report"},
+		},
+		{
+			"Test-for-wallet-high-recovery-id", issuer, issuerKey, 27, nil,
+			servertypes.BondSecureDetailsResp{Security: "Land title", Appendix: "Valuation report"},
+		},
+		{
+			"Test-for-payload-signed-by-another-key", issuer, otherKey, 0, utils.ErrInvalidSignedCall,
+			servertypes.BondSecureDetailsResp{},
+		},
+		{
+			"Test-for-non-bond-party", other, otherKey, 0, utils.ErrNotBondParty,
+			servertypes.BondSecureDetailsResp{},
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			msg := query(t, v.sender, utils.GetBondSecureDetails, sampleHexAddress.String())
+			if msg.Error != nil {
+				t.Fatalf("expected no error but found %v", msg.Error)
+			}
+
+			var unsignedCall sapphire.UnsignedCall
+			if err := json.Unmarshal(msg.Result, &unsignedCall); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			sig, err := crypto.Sign(unsignedCall.Digest.Bytes(), v.key)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+			sig[64] += v.recoveryID
+
+			msg = query(t, v.sender, utils.SendSignedCall, sampleHexAddress.String(),
+				unsignedCall.BlockNumber, hexutil.Encode(sig))
+
+			if v.err != nil {
+				if msg.Error == nil || msg.Error.Code != utils.GetErrorCode(v.err) {
+					t.Fatalf("expected error %q but found %v", v.err, msg.Error)
+				}
+				return
+			}
+
+			if msg.Error != nil {
+				t.Fatalf("expected no error but found %v", msg.Error)
+			}
+
+			var res servertypes.BondSecureDetailsResp
+			if err := json.Unmarshal(msg.Result, &res); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if res != v.expected {
+				t.Fatalf("expected bond details %+v but found %+v", v.expected, res)
+			}
+		})
+	}
+}
+
+// TestPackExecError tests the error codes the failed Sapphire calls map to.
+func TestPackExecError(t *testing.T) {
+	td := []struct {
+		testName string
+		err      error
+		shortErr error
+		data     string
+	}{
+		{
+			"Test-for-contract-revert",
+			&sapphire.CallError{Module: "evm", Code: 8, Reason: "Edits disabled on finalized Bond"},
+			utils.ErrContractReverted, "Edits disabled on finalized Bond",
+		},
+		{
+			"Test-for-paratime-failure",
+			&sapphire.CallError{Module: "core", Code: 12, Message: "out of gas"},
+			utils.ErrSapphireCall, "call failed in module core with code 12: out of gas",
+		},
+		{
+			"Test-for-other-errors",
+			errors.New("connection refused"),
+			utils.ErrInternalFailure, "submitting tx failed: connection refused",
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			msg := new(servertypes.RPCMessage)
+			packExecError(msg, fmt.Errorf("submitting tx failed: %w", v.err))
+
+			if msg.Error.Code != utils.GetErrorCode(v.shortErr) {
+				t.Fatalf("expected error %q but found %q", v.shortErr, msg.Error.Message)
+			}
+
+			if msg.Error.Data != v.data {
+				t.Fatalf("expected error data %q but found %q", v.data, msg.Error.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// minFilterInterval defines the smallest number of blocks a backfill
+	// window can be shrunk to.
+	minFilterInterval int64 = 10
+
+	// maxFilterInterval defines the largest number of blocks a backfill
+	// window can be grown to.
+	maxFilterInterval int64 = 10000
+
+	// targetWindowLogs defines the number of logs a backfill window should
+	// ideally return. Windows returning more logs are shrunk while those
+	// returning far fewer logs are grown.
+	targetWindowLogs = 500
+)
+
+// tooManyResultsErrs defines the error messages returned by the rpc nodes
+// when a filter query matches more logs than they are willing to return.
+var tooManyResultsErrs = []string{
+	"too many", "more than", "limit exceeded", "response size", "range is too large",
+}
+
+// filterWindow defines the range of blocks whose logs are fetched by a single
+// backfill worker.
+type filterWindow struct {
+	index    int
+	from, to int64
+
+	logs []types.Log
+	err  error
+}
+
+// windowSizer adapts the backfill window size to the logs density of the
+// recently fetched windows.
+type windowSizer struct {
+	mtx  sync.Mutex
+	size int64
+}
+
+// newWindowSizer returns a window sizer starting with the provided size.
+func newWindowSizer(size int64) *windowSizer {
+	w := &windowSizer{size: minFilterInterval}
+	w.set(size)
+	return w
+}
+
+// current returns the current window size.
+func (w *windowSizer) current() int64 {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return w.size
+}
+
+// set updates the window size while keeping it within the supported range.
+func (w *windowSizer) set(size int64) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	switch {
+	case size < minFilterInterval:
+		size = minFilterInterval
+	case size > maxFilterInterval:
+		size = maxFilterInterval
+	}
+	w.size = size
+}
+
+// adjust grows or shrinks the window size depending on the number of logs
+// the window of the provided size returned.
+func (w *windowSizer) adjust(size int64, logsCount int) {
+	switch {
+	case logsCount > targetWindowLogs:
+		w.set(size / 2)
+	case logsCount < targetWindowLogs/4 && size >= w.current():
+		w.set(size * 2)
+	}
+}
+
+// shrink halves the window size. It is invoked once the rpc node rejects a
+// filter query for returning too many results.
+func (w *windowSizer) shrink() {
+	w.set(w.current() / 2)
+}
+
+// isTooManyResultsErr returns true if the provided error was returned because
+// the filter query matched too many logs.
+func isTooManyResultsErr(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, e := range tooManyResultsErrs {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// backfill syncs all the historical events from the start block to the target
+// block. Several windows are fetched at once using upto the configured number
+// of workers but the fetched events are persisted in strict block order. It
+// returns the number of events processed.
+func (s *Syncer) backfill(filterOpts ethereum.FilterQuery, startBlock,
+	targetBlock int64,
+) (int, error) {
+	workers := s.backfillWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	// Stops the workers still fetching if the backfill exits early.
+	defer cancel()
+
+	sizer := newWindowSizer(blocksFilterInterval)
+
+	// results is buffered so that the workers never block even if the
+	// backfill exits before their results are read.
+	results := make(chan *filterWindow, workers)
+	pending := make(map[int]*filterWindow)
+
+	ticker := time.NewTicker(loggingInterval)
+	defer ticker.Stop()
+
+	var dispatched, applied, inFlight, eventCounter, totalEvents int
+	nextBlock := startBlock
+
+	for nextBlock <= targetBlock || inFlight > 0 {
+		// Dispatch new windows till all the workers are busy.
+		for nextBlock <= targetBlock && inFlight < workers {
+			window := &filterWindow{
+				index: dispatched,
+				from:  nextBlock,
+				to:    nextBlock + sizer.current() - 1,
+			}
+			if window.to > targetBlock {
+				window.to = targetBlock
+			}
+
+			go func(w *filterWindow) {
+				query := filterOpts
+				query.FromBlock, query.ToBlock = big.NewInt(w.from), big.NewInt(w.to)
+
+				w.logs, w.err = s.fetchWindowLogs(ctx, query, sizer)
+				results <- w
+			}(window)
+
+			nextBlock = window.to + 1
+			dispatched++
+			inFlight++
+		}
+
+		select {
+		case <-s.quit:
+			// shutdown request was received, so exit.
+			return totalEvents + eventCounter, nil
+		case <-s.ctx.Done():
+			// If context is shut during the looping, exit
+			return totalEvents + eventCounter, nil
+		case <-ticker.C:
+			log.Infof("Syncing data from block=%d To target block=%d, events processed=%d",
+				nextBlock, targetBlock, eventCounter)
+
+			totalEvents += eventCounter
+			eventCounter = 0 // reset the events counter.
+
+		case w := <-results:
+			inFlight--
+			if w.err != nil {
+				return totalEvents + eventCounter, w.err
+			}
+
+			sizer.adjust(w.to-w.from+1, len(w.logs))
+			pending[w.index] = w
+
+			// Persist the fetched windows in the order they were dispatched.
+			for {
+				w, ok := pending[applied]
+				if !ok {
+					break
+				}
+
+				events, err := s.parseEvents(w.logs)
+				if err != nil {
+					return totalEvents + eventCounter, err
+				}
+
+				if err = s.persistEvents(events, w.logs, w.to); err != nil {
+					return totalEvents + eventCounter, err
+				}
+
+				delete(pending, applied)
+				eventCounter += len(w.logs)
+				applied++
+			}
+		}
+	}
+
+	return totalEvents + eventCounter, nil
+}
+
+// fetchWindowLogs returns the logs matching the provided filter query. If the
+// rpc node rejects the query for matching too many logs, the window is split
+// into two halves that are fetched separately and the window size shrunk.
+func (s *Syncer) fetchWindowLogs(ctx context.Context,
+	query ethereum.FilterQuery, sizer *windowSizer,
+) ([]types.Log, error) {
+	from, to := query.FromBlock.Int64(), query.ToBlock.Int64()
+
+	logs, err := s.backend.FilterLogs(ctx, query)
+	if err == nil {
+		return logs, nil
+	}
+
+	if !isTooManyResultsErr(err) || from >= to {
+		return nil, fmt.Errorf("syncing between block %d and %d failed: %v",
+			from, to, err)
+	}
+
+	sizer.shrink()
+
+	mid := from + (to-from)/2
+
+	lower := query
+	lower.ToBlock = big.NewInt(mid)
+	lowerLogs, err := s.fetchWindowLogs(ctx, lower, sizer)
+	if err != nil {
+		return nil, err
+	}
+
+	upper := query
+	upper.FromBlock = big.NewInt(mid + 1)
+	upperLogs, err := s.fetchWindowLogs(ctx, upper, sizer)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(lowerLogs, upperLogs...), nil
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestWindowSizer tests if the backfill window size adapts to the logs density
+// while remaining within the supported range.
+func TestWindowSizer(t *testing.T) {
+	td := []struct {
+		testName  string
+		start     int64
+		size      int64
+		logsCount int
+		shrink    bool
+		expSize   int64
+	}{
+		{"sparse_logs_window_grows", 100, 100, 0, false, 200},
+		{"dense_logs_window_shrinks", 100, 100, targetWindowLogs + 1, false, 50},
+		{"target_logs_window_unchanged", 100, 100, targetWindowLogs, false, 100},
+		{"stale_small_window_not_grown", 400, 100, 0, false, 400},
+		{"window_not_grown_past_max", maxFilterInterval, maxFilterInterval, 0, false, maxFilterInterval},
+		{"window_not_shrunk_past_min", minFilterInterval, minFilterInterval, targetWindowLogs * 2, false, minFilterInterval},
+		{"too_many_results_shrinks", 100, 100, 0, true, 50},
+		{"start_size_capped", maxFilterInterval * 2, maxFilterInterval * 2, targetWindowLogs, false, maxFilterInterval},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			sizer := newWindowSizer(v.start)
+			if v.shrink {
+				sizer.shrink()
+			} else {
+				sizer.adjust(v.size, v.logsCount)
+			}
+
+			if size := sizer.current(); size != v.expSize {
+				t.Fatalf("expected window size %d but found %d", v.expSize, size)
+			}
+		})
+	}
+}
+
+// TestIsTooManyResultsErr tests if the rpc node errors returned on queries
+// matching too many logs are detected.
+func TestIsTooManyResultsErr(t *testing.T) {
+	td := []struct {
+		err error
+		exp bool
+	}{
+		{errors.New("query returned more than 10000 results"), true},
+		{errors.New("Log response size exceeded"), true},
+		{errors.New("too many logs requested"), true},
+		{errors.New("connection refused"), false},
+	}
+
+	for _, v := range td {
+		t.Run(v.err.Error(), func(t *testing.T) {
+			if isTooManyResultsErr(v.err) != v.exp {
+				t.Fatalf("expected error %q detection to be %v", v.err, v.exp)
+			}
+		})
+	}
+}
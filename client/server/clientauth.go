@@ -0,0 +1,248 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// errMissingOrg is returned if the verified client certificate doesn't
+// identify the trust organisation it was issued to.
+var errMissingOrg = errors.New("client certificate missing the organisation name")
+
+// orgVerifier defines the method used to verify the client certificates
+// chain against the registered trust organisations.
+type orgVerifier interface {
+	VerifyChain(org string, chain []*x509.Certificate) error
+}
+
+// clientAuth verifies the client certificates presented by the POAs. Only the
+// certificates issued by the trust organisations CAs, not revoked by their CRLs
+// and whose organisation isn't denied access are accepted.
+type clientAuth struct {
+	mtx   sync.RWMutex
+	roots *x509.CertPool
+
+	// orgs if set, verifies the chains against the registered trust
+	// organisations.
+	orgs orgVerifier
+
+	// revoked holds the serial numbers of the revoked certificates indexed by
+	// their issuer's raw subject.
+	revoked map[string]map[string]struct{}
+
+	// deniedOrgs holds the organisations denied access to the server.
+	deniedOrgs map[string]struct{}
+}
+
+// newClientAuth loads the trust organisations CA certificates from the caFile
+// and the optional CRLs from the crlFile. Each CRL must be signed by one of
+// the trust organisations CAs.
+func newClientAuth(caFile, crlFile string, deniedOrgs []string) (*clientAuth, error) {
+	caData, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading the trust organisations CA file failed: %v", err)
+	}
+
+	cas, err := parsePEMBlocks(caData, "CERTIFICATE", func(der []byte) (*x509.Certificate, error) {
+		return x509.ParseCertificate(der)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust organisations CA certificate: %v", err)
+	}
+
+	if len(cas) == 0 {
+		return nil, errors.New("no trust organisations CA certificate found")
+	}
+
+	auth := &clientAuth{
+		roots:      x509.NewCertPool(),
+		revoked:    make(map[string]map[string]struct{}),
+		deniedOrgs: make(map[string]struct{}, len(deniedOrgs)),
+	}
+
+	for _, ca := range cas {
+		auth.roots.AddCert(ca)
+	}
+
+	for _, org := range deniedOrgs {
+		auth.deniedOrgs[strings.TrimSpace(org)] = struct{}{}
+	}
+
+	if crlFile == "" {
+		return auth, nil
+	}
+
+	crlData, err := os.ReadFile(crlFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading the trust organisations CRL file failed: %v", err)
+	}
+
+	crls, err := parsePEMBlocks(crlData, "X509 CRL", x509.ParseRevocationList)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust organisations CRL: %v", err)
+	}
+
+	for _, crl := range crls {
+		if err = checkCRLIssuer(crl, cas); err != nil {
+			return nil, err
+		}
+
+		issuer := string(crl.RawIssuer)
+		if auth.revoked[issuer] == nil {
+			auth.revoked[issuer] = make(map[string]struct{})
+		}
+
+		for _, entry := range crl.RevokedCertificateEntries {
+			auth.revoked[issuer][entry.SerialNumber.String()] = struct{}{}
+		}
+	}
+
+	return auth, nil
+}
+
+// parsePEMBlocks decodes all the PEM blocks of the provided type using the
+// parse function.
+func parsePEMBlocks[T any](data []byte, blockType string, parse func([]byte) (T, error)) ([]T, error) {
+	var items []T
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return items, nil
+		}
+
+		if block.Type != blockType {
+			continue
+		}
+
+		item, err := parse(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+// checkCRLIssuer confirms that the CRL was signed by one of the CAs.
+func checkCRLIssuer(crl *x509.RevocationList, cas []*x509.Certificate) error {
+	for _, ca := range cas {
+		if string(ca.RawSubject) != string(crl.RawIssuer) {
+			continue
+		}
+
+		if err := crl.CheckSignatureFrom(ca); err != nil {
+			return fmt.Errorf("invalid CRL signature by %s: %v", ca.Subject, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("CRL issuer %s isn't a trust organisation CA", crl.Issuer)
+}
+
+// pool returns the trust organisations CAs pool.
+func (c *clientAuth) pool() *x509.CertPool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.roots
+}
+
+// addCA adds the CA certificate of a newly registered trust organisation to
+// the pool used by the subsequent TLS handshakes.
+func (c *clientAuth) addCA(ca *x509.Certificate) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// The pool in use by the ongoing handshakes isn't modified.
+	roots := c.roots.Clone()
+	roots.AddCert(ca)
+	c.roots = roots
+}
+
+// configure sets the TLS config to require the client certificates verified
+// by the trust organisations CAs. The latest CAs pool is used on every
+// handshake. The config certificates must be set before it is configured.
+func (c *clientAuth) configure(cfg *tls.Config) {
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	cfg.ClientCAs = c.pool()
+	cfg.VerifyPeerCertificate = c.verifyPeerCertificate
+
+	base := cfg.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		clientCfg := base.Clone()
+		clientCfg.ClientCAs = c.pool()
+		return clientCfg, nil
+	}
+}
+
+// verifyPeerCertificate rejects the client certificates revoked or issued to
+// the organisations denied access. It is called after the certificate chains
+// were verified against the trust organisations CAs.
+func (c *clientAuth) verifyPeerCertificate(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) == 0 {
+		return errors.New("no verified client certificate chain found")
+	}
+
+	var err error
+	for _, chain := range verifiedChains {
+		if err = c.verifyChain(chain); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// verifyChain confirms that none of the chain certificates was revoked and
+// that the organisation of the leaf certificate isn't denied access.
+func (c *clientAuth) verifyChain(chain []*x509.Certificate) error {
+	for i := 0; i < len(chain)-1; i++ {
+		issuer := string(chain[i+1].RawSubject)
+		if _, ok := c.revoked[issuer][chain[i].SerialNumber.String()]; ok {
+			return fmt.Errorf("certificate %s was revoked", chain[i].Subject)
+		}
+	}
+
+	org, err := certOrg(chain[0])
+	if err != nil {
+		return err
+	}
+
+	if _, ok := c.deniedOrgs[org]; ok {
+		return fmt.Errorf("organisation %q denied access", org)
+	}
+
+	if c.orgs != nil {
+		return c.orgs.VerifyChain(org, chain)
+	}
+	return nil
+}
+
+// certOrg returns the organisation the certificate was issued to.
+func certOrg(cert *x509.Certificate) (string, error) {
+	if len(cert.Subject.Organization) == 0 || cert.Subject.Organization[0] == "" {
+		return "", errMissingOrg
+	}
+	return cert.Subject.Organization[0], nil
+}
+
+// requestOrg returns the trust organisation identified by the verified client
+// certificate of the request. An empty string is returned if the request
+// wasn't made with a verified client certificate.
+func requestOrg(req *http.Request) string {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 ||
+		len(req.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	org, _ := certOrg(req.TLS.VerifiedChains[0][0])
+	return org
+}
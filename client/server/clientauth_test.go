@@ -0,0 +1,207 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA holds a trust organisation CA used to issue the client certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCA returns a self-signed trust organisation CA.
+func newTestCA(t *testing.T, org string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{org}, CommonName: org + " CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns a client certificate issued to the organisation.
+func (ca *testCA) issue(t *testing.T, serial int64, org string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	var orgs []string
+	if org != "" {
+		orgs = []string{org}
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{Organization: orgs, CommonName: "POA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+	return cert
+}
+
+// crl returns the PEM encoded CRL revoking the provided serial numbers.
+func (ca *testCA) crl(t *testing.T, serials ...int64) []byte {
+	entries := make([]x509.RevocationListEntry, 0, len(serials))
+	for _, serial := range serials {
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now(),
+		})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now(),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+// writeFile writes the data into a file in the directory and returns its path.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+	return path
+}
+
+// TestNewClientAuth tests the loading of the trust organisations CAs and CRLs.
+func TestNewClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "Org A")
+	unknownCA := newTestCA(t, "Org X")
+
+	caFile := writeFile(t, dir, "trustorgs.crt",
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+
+	td := []struct {
+		testName string
+		caFile   string
+		crlFile  string
+		isValid  bool
+	}{
+		{"Test-for-ca-without-crl", caFile, "", true},
+		{"Test-for-ca-with-crl", caFile, writeFile(t, dir, "valid.crl", ca.crl(t, 3)), true},
+		{"Test-for-missing-ca-file", filepath.Join(dir, "missing.crt"), "", false},
+		{"Test-for-empty-ca-file", writeFile(t, dir, "empty.crt", nil), "", false},
+		{"Test-for-crl-of-unknown-ca", caFile, writeFile(t, dir, "unknown.crl", unknownCA.crl(t, 3)), false},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			_, err := newClientAuth(v.caFile, v.crlFile, nil)
+			if (err == nil) != v.isValid {
+				t.Fatalf("expected the config validity to be %v but found error %v",
+					v.isValid, err)
+			}
+		})
+	}
+}
+
+// TestVerifyPeerCertificate tests that only the client certificates issued by
+// the trust organisations that are neither revoked nor denied are accepted.
+func TestVerifyPeerCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "Org A")
+	unknownCA := newTestCA(t, "Org X")
+
+	caFile := writeFile(t, dir, "trustorgs.crt",
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+	crlFile := writeFile(t, dir, "trustorgs.crl", ca.crl(t, 3))
+
+	auth, err := newClientAuth(caFile, crlFile, []string{"Org B"})
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	td := []struct {
+		testName string
+		cert     *x509.Certificate
+		isValid  bool
+	}{
+		{"Test-for-vetted-organisation", ca.issue(t, 2, "Org A"), true},
+		{"Test-for-revoked-certificate", ca.issue(t, 3, "Org A"), false},
+		{"Test-for-denied-organisation", ca.issue(t, 4, "Org B"), false},
+		{"Test-for-missing-organisation", ca.issue(t, 5, ""), false},
+		{"Test-for-unknown-ca", unknownCA.issue(t, 2, "Org A"), false},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			// The TLS handshake verifies the chains before the peer certificate.
+			chains, err := v.cert.Verify(x509.VerifyOptions{
+				Roots:     auth.pool(),
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+			if err == nil {
+				err = auth.verifyPeerCertificate(nil, chains)
+			}
+
+			if (err == nil) != v.isValid {
+				t.Fatalf("expected the certificate validity to be %v but found error %v",
+					v.isValid, err)
+			}
+
+			if !v.isValid {
+				return
+			}
+
+			req := httptest.NewRequest("POST", "/backend", nil)
+			req.TLS = &tls.ConnectionState{VerifiedChains: chains}
+
+			if org := requestOrg(req); org != "Org A" {
+				t.Fatalf("expected the request organisation %q but found %q", "Org A", org)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/dmigwi/dhamana-protocol/client/deployment/development"
 	"github.com/dmigwi/dhamana-protocol/client/deployment/sapphirelocalnet"
 	"github.com/dmigwi/dhamana-protocol/client/deployment/sapphiremainnet"
 	"github.com/dmigwi/dhamana-protocol/client/deployment/sapphiretestnet"
@@ -24,6 +25,8 @@ func isDeployedNetMatching(net utils.NetworkType) bool {
 		return net == utils.ToNetType(sapphirelocalnet.GetNetwork())
 	case utils.SapphireMainnet:
 		return net == utils.ToNetType(sapphiremainnet.GetNetwork())
+	case utils.Development:
+		return net == utils.ToNetType(development.GetNetwork())
 	default:
 		return false
 	}
@@ -39,6 +42,8 @@ func getContractAddress(net utils.NetworkType) (address common.Address) {
 		address = common.HexToAddress(sapphirelocalnet.GetContractAddress())
 	case utils.SapphireMainnet:
 		address = common.HexToAddress(sapphiremainnet.GetContractAddress())
+	case utils.Development:
+		address = common.HexToAddress(development.GetContractAddress())
 	}
 	return
 }
@@ -52,6 +57,8 @@ func getChainID(net utils.NetworkType) (chainID *big.Int) {
 		chainID = big.NewInt(int64(sapphirelocalnet.GetChainID()))
 	case utils.SapphireMainnet:
 		chainID = big.NewInt(int64(sapphiremainnet.GetChainID()))
+	case utils.Development:
+		chainID = big.NewInt(int64(development.GetChainID()))
 	}
 	return
 }
@@ -65,6 +72,8 @@ func getDeploymentTime(net utils.NetworkType) (timestamp time.Time) {
 		timestamp = time.Unix(int64(sapphirelocalnet.GetDeploymentTime()), 0)
 	case utils.SapphireMainnet:
 		timestamp = time.Unix(int64(sapphiremainnet.GetDeploymentTime()), 0)
+	case utils.Development:
+		timestamp = time.Unix(int64(development.GetDeploymentTime()), 0)
 	}
 	return
 }
@@ -78,6 +87,8 @@ func getDeployedTxHash(net utils.NetworkType) (tx common.Hash) {
 		tx = common.HexToHash(sapphirelocalnet.GetTransactionHash())
 	case utils.SapphireMainnet:
 		tx = common.HexToHash(sapphiremainnet.GetTransactionHash())
+	case utils.Development:
+		tx = common.HexToHash(development.GetTransactionHash())
 	}
 	return
 }
@@ -91,6 +102,8 @@ func getDeployedBlock(net utils.NetworkType) (block uint64) {
 		block = sapphirelocalnet.GetDeploymentBlock()
 	case utils.SapphireMainnet:
 		block = sapphiremainnet.GetDeploymentBlock()
+	case utils.Development:
+		block = development.GetDeploymentBlock()
 	}
 	return
 }
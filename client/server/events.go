@@ -0,0 +1,188 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/contracts"
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// pendingEvents holds the events data yet to get the required number of
+// confirmations before being persisted. The whole view is replaced on every
+// poll since the unconfirmed events may change.
+type pendingEvents struct {
+	mtx    sync.RWMutex
+	events map[common.Address][]*servertypes.EventResp
+}
+
+// newPendingEvents returns an empty pending events view.
+func newPendingEvents() *pendingEvents {
+	return &pendingEvents{
+		events: make(map[common.Address][]*servertypes.EventResp),
+	}
+}
+
+// replace sets the provided events as the current unconfirmed events.
+func (p *pendingEvents) replace(events []*servertypes.EventResp) {
+	data := make(map[common.Address][]*servertypes.EventResp)
+	for _, e := range events {
+		data[e.BondAddress] = append(data[e.BondAddress], e)
+	}
+
+	p.mtx.Lock()
+	p.events = data
+	p.mtx.Unlock()
+}
+
+// list returns the unconfirmed events of the provided bond address.
+func (p *pendingEvents) list(bondAddress common.Address) []*servertypes.EventResp {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	return p.events[bondAddress]
+}
+
+// chats returns the unconfirmed chat messages of the provided bond address,
+// the newest first.
+func (p *pendingEvents) chats(bondAddress common.Address) []interface{} {
+	events := p.list(bondAddress)
+
+	chats := make([]interface{}, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		if chat, ok := events[i].Data.(servertypes.ChatMsgsResp); ok {
+			chat.Pending = true
+			chats = append(chats, &chat)
+		}
+	}
+	return chats
+}
+
+// decodeEventResp attempts to match the provided log with one of the event
+// parsers and packs the event data into the response sent to the clients.
+func (s *Syncer) decodeEventResp(eventLog types.Log) (*servertypes.EventResp, error) {
+	if data, _ := s.bondChat.ChatFilterer.ParseNewBondCreated(eventLog); data != nil {
+		return newBondCreatedResp(data), nil
+	}
+
+	if data, _ := s.bondChat.ChatFilterer.ParseNewChatMessage(eventLog); data != nil {
+		return newChatMessageResp(data), nil
+	}
+
+	if data, _ := s.bondChat.ChatFilterer.ParseStatusChange(eventLog); data != nil {
+		return statusChangeResp(data), nil
+	}
+
+	if data, _ := s.bondChat.ChatFilterer.ParseStatusSigned(eventLog); data != nil {
+		return statusSignedResp(data), nil
+	}
+
+	if data, _ := s.bondChat.ChatFilterer.ParseBondBodyTerms(eventLog); data != nil {
+		return bondBodyTermsResp(data), nil
+	}
+
+	if data, _ := s.bondChat.ChatFilterer.ParseBondMotivation(eventLog); data != nil {
+		return bondMotivationResp(data), nil
+	}
+
+	if data, _ := s.bondChat.ChatFilterer.ParseHolderUpdate(eventLog); data != nil {
+		return holderUpdateResp(data), nil
+	}
+
+	return nil, fmt.Errorf("unsupported event at contract address: %v and Block No: %v ",
+		eventLog.Address, eventLog.BlockNumber)
+}
+
+// newBondCreatedResp packs the NewBondCreated event data.
+func newBondCreatedResp(data *contracts.ChatNewBondCreated) *servertypes.EventResp {
+	return &servertypes.EventResp{
+		Event:       "NewBondCreated",
+		BondAddress: data.BondAddress,
+		BlockNo:     data.Raw.BlockNumber,
+		Data:        servertypes.NewBondResp{Issuer: data.Sender},
+	}
+}
+
+// newChatMessageResp packs the NewChatMessage event data.
+func newChatMessageResp(data *contracts.ChatNewChatMessage) *servertypes.EventResp {
+	return &servertypes.EventResp{
+		Event:       "NewChatMessage",
+		BondAddress: data.BondAddress,
+		BlockNo:     data.Raw.BlockNumber,
+		Data: servertypes.ChatMsgsResp{
+			Sender:          data.Sender,
+			BondAddress:     data.BondAddress,
+			Message:         data.Message,
+			CreatedTime:     time.Now().UTC(),
+			LastSyncedBlock: data.Raw.BlockNumber,
+		},
+	}
+}
+
+// statusChangeResp packs the StatusChange event data.
+func statusChangeResp(data *contracts.ChatStatusChange) *servertypes.EventResp {
+	return &servertypes.EventResp{
+		Event:       "StatusChange",
+		BondAddress: data.BondAddress,
+		BlockNo:     data.Raw.BlockNumber,
+		Data: servertypes.StatusResp{
+			Sender: data.Sender,
+			Status: data.Status,
+		},
+	}
+}
+
+// statusSignedResp packs the StatusSigned event data.
+func statusSignedResp(data *contracts.ChatStatusSigned) *servertypes.EventResp {
+	return &servertypes.EventResp{
+		Event:       "StatusSigned",
+		BondAddress: data.BondAddress,
+		BlockNo:     data.Raw.BlockNumber,
+		Data: servertypes.StatusResp{
+			Sender: data.Sender,
+			Status: data.Status,
+		},
+	}
+}
+
+// bondBodyTermsResp packs the BondBodyTerms event data.
+func bondBodyTermsResp(data *contracts.ChatBondBodyTerms) *servertypes.EventResp {
+	return &servertypes.EventResp{
+		Event:       "BondBodyTerms",
+		BondAddress: data.BondAddress,
+		BlockNo:     data.Raw.BlockNumber,
+		Data: servertypes.BondBodyTermsResp{
+			Principal:    uint64(data.Principal),
+			CouponRate:   data.CouponRate,
+			CouponDate:   data.CouponDate,
+			MaturityDate: time.Unix(int64(data.MaturityDate), 0).UTC(),
+			Currency:     data.Currency,
+		},
+	}
+}
+
+// bondMotivationResp packs the BondMotivation event data.
+func bondMotivationResp(data *contracts.ChatBondMotivation) *servertypes.EventResp {
+	return &servertypes.EventResp{
+		Event:       "BondMotivation",
+		BondAddress: data.BondAddress,
+		BlockNo:     data.Raw.BlockNumber,
+		Data:        servertypes.MotivationResp{Message: data.Message},
+	}
+}
+
+// holderUpdateResp packs the HolderUpdate event data.
+func holderUpdateResp(data *contracts.ChatHolderUpdate) *servertypes.EventResp {
+	return &servertypes.EventResp{
+		Event:       "HolderUpdate",
+		BondAddress: data.BondAddress,
+		BlockNo:     data.Raw.BlockNumber,
+		Data:        servertypes.HolderResp{Holder: data.Holder},
+	}
+}
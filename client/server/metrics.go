@@ -0,0 +1,114 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsNamespace prefixes all the metrics exported.
+const metricsNamespace = "dhamana"
+
+var (
+	// rpcRequestDuration tracks the rate and latency of the JSON-RPC messages
+	// executed per method.
+	rpcRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "rpc",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the JSON-RPC messages executed per method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// rpcErrors tracks the JSON-RPC errors returned per error code.
+	rpcErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "rpc",
+		Name:      "errors_total",
+		Help:      "Number of the JSON-RPC errors returned per error code.",
+	}, []string{"code"})
+
+	// txSubmitted tracks the transactions submitted to the contract per method.
+	txSubmitted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "rpc",
+		Name:      "transactions_submitted_total",
+		Help:      "Number of the contract transactions submitted per method and status.",
+	}, []string{"method", "status"})
+
+	// syncEvents tracks the events persisted per network and event name.
+	syncEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "sync",
+		Name:      "events_processed_total",
+		Help:      "Number of the contract events persisted per event name.",
+	}, []string{"network", "event"})
+
+	// syncLagBlocks tracks the number of blocks the persisted events lag
+	// behind the chain tip per network.
+	syncLagBlocks = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "sync",
+		Name:      "lag_blocks",
+		Help:      "Number of blocks the persisted events lag behind the chain tip.",
+	}, []string{"network"})
+)
+
+// observeRPC records the duration of the executed message of the requested
+// method and the error returned if any. The method is passed separately since
+// it is cleared from the packed message. Unsupported methods are grouped
+// together.
+func observeRPC(method utils.Method, msg *servertypes.RPCMessage, start time.Time) {
+	label := string(method)
+	if methodType, _ := utils.GetMethodParams(method); methodType == utils.UnknownType {
+		label = "unknown"
+	}
+
+	rpcRequestDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+
+	if msg.Error != nil {
+		rpcErrors.WithLabelValues(strconv.Itoa(int(msg.Error.Code))).Inc()
+	}
+}
+
+// observeTx records the contract transaction submitted.
+func observeTx(method utils.Method, err error) {
+	status := "success"
+	if err != nil {
+		status = "failed"
+	}
+	txSubmitted.WithLabelValues(string(method), status).Inc()
+}
+
+// sessionKeysGauge returns the gauge reporting the number of the session keys
+// currently held by the session store of the provided network.
+func sessionKeysGauge(network utils.NetworkType, sessions SessionStore) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "rpc",
+		Name:        "active_session_keys",
+		Help:        "Number of the session keys currently held by the server.",
+		ConstLabels: prometheus.Labels{"network": network.String()},
+	}, func() float64 {
+		count, err := sessions.Count()
+		if err != nil {
+			log.Errorf("counting the sessions failed: %v", err)
+		}
+		return float64(count)
+	})
+}
+
+// metricsHandler returns the handler exposing all the metrics in the
+// prometheus text format.
+func metricsHandler() http.Handler {
+	return promhttp.Handler()
+}
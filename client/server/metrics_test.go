@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// rpcObservations returns the number of the messages recorded for the method.
+func rpcObservations(t *testing.T, method string) uint64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "dhamana_rpc_request_duration_seconds" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "method" && label.GetValue() == method {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// TestObserveRPC tests if the messages executed via the backend route are
+// recorded per requested method and the returned errors per error code.
+func TestObserveRPC(t *testing.T) {
+	msg := servertypes.RPCMessage{
+		Version: "2.0",
+		Method:  utils.GetBonds,
+		Sender: &servertypes.SenderInfo{
+			Address:    sampleHexAddress3,
+			SigningKey: sampleSigningKey,
+		},
+		Params: []interface{}{10, 0},
+	}
+
+	td := []struct {
+		testName string
+		body     interface{}
+		count    uint64
+	}{
+		{"single_message", msg, 1},
+		{"batch_messages", []servertypes.RPCMessage{msg, msg}, 2},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			code := strconv.Itoa(int(utils.GetErrorCode(utils.ErrMissingServerKey)))
+			errCount := testutil.ToFloat64(rpcErrors.WithLabelValues(code))
+			methodCount := rpcObservations(t, string(utils.GetBonds))
+			unknownCount := rpcObservations(t, "unknown")
+
+			var buf bytes.Buffer
+			_ = json.NewEncoder(&buf).Encode(v.body)
+
+			responseWritter := httptest.NewRecorder()
+			serverConf.backendQueryFunc(responseWritter,
+				httptest.NewRequest(http.MethodPost, "/backend", &buf))
+
+			if count := rpcObservations(t, string(utils.GetBonds)); count != methodCount+v.count {
+				t.Fatalf("expected %v getBonds messages but found %v", methodCount+v.count, count)
+			}
+
+			if count := rpcObservations(t, "unknown"); count != unknownCount {
+				t.Fatalf("expected %v unknown messages but found %v", unknownCount, count)
+			}
+
+			if count := testutil.ToFloat64(rpcErrors.WithLabelValues(code)); count != errCount+float64(v.count) {
+				t.Fatalf("expected error count %v but found %v", errCount+float64(v.count), count)
+			}
+		})
+	}
+}
+
+// TestObserveTx tests if the submitted transactions are recorded per status.
+func TestObserveTx(t *testing.T) {
+	success := testutil.ToFloat64(txSubmitted.WithLabelValues(string(utils.CreateBond), "success"))
+	failed := testutil.ToFloat64(txSubmitted.WithLabelValues(string(utils.CreateBond), "failed"))
+
+	observeTx(utils.CreateBond, nil)
+	observeTx(utils.CreateBond, errors.New("execution reverted"))
+
+	if count := testutil.ToFloat64(txSubmitted.WithLabelValues(string(utils.CreateBond), "success")); count != success+1 {
+		t.Fatalf("expected successful tx count %v but found %v", success+1, count)
+	}
+
+	if count := testutil.ToFloat64(txSubmitted.WithLabelValues(string(utils.CreateBond), "failed")); count != failed+1 {
+		t.Fatalf("expected failed tx count %v but found %v", failed+1, count)
+	}
+}
+
+// TestSessionKeysGauge tests if the gauge reports the session keys held.
+func TestSessionKeysGauge(t *testing.T) {
+	keys := newMemSessions()
+	keys.Store(sampleHexAddress1, servertypes.ServerKeyResp{})
+	keys.Store(sampleHexAddress2, servertypes.ServerKeyResp{})
+
+	gauge := sessionKeysGauge(utils.LocalTesting, keys)
+	if count := testutil.ToFloat64(gauge); count != 2 {
+		t.Fatalf("expected 2 session keys but found %v", count)
+	}
+}
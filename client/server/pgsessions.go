@@ -0,0 +1,262 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/storage"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// masterKeySize defines the size of the AES-256 master key encrypting the
+// sessions secrets at rest.
+const masterKeySize = 32
+
+// sessionsDB defines the db methods used to persist the sessions.
+type sessionsDB interface {
+	QueryLocalData(method utils.Method, r storage.Reader, sender string,
+		params ...interface{}) ([]interface{}, error)
+	SetLocalData(method utils.Method, params ...interface{}) error
+}
+
+// pgSessions is a SessionStore persisting the sessions in the postgres db so
+// that they survive restarts and are shared by all the server instances. The
+// shared keys and the session tokens are encrypted under the server master key.
+type pgSessions struct {
+	db   sessionsDB
+	aead cipher.AEAD
+}
+
+// newPGSessions returns a postgres session store encrypting the sessions
+// secrets using the provided master key.
+func newPGSessions(db sessionsDB, masterKey []byte) (*pgSessions, error) {
+	if len(masterKey) != masterKeySize {
+		return nil, fmt.Errorf("expected a %d bytes master key but found %d bytes",
+			masterKeySize, len(masterKey))
+	}
+
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %v", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %v", err)
+	}
+
+	return &pgSessions{db: db, aead: aead}, nil
+}
+
+// loadMasterKey reads the hex encoded master key from the provided file.
+func loadMasterKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the sessions master key file failed: %v", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex encoded sessions master key: %v", err)
+	}
+	return key, nil
+}
+
+// sessionRecord holds a session as persisted in the db.
+type sessionRecord struct {
+	pubkey       string
+	sharedKey    string
+	sessionToken string
+	expiry       time.Time
+}
+
+// Read is the reader interface implementation for type sessionRecord.
+func (r *sessionRecord) Read(fn func(fields ...any) error) (interface{}, error) {
+	var record sessionRecord
+	err := fn(&record.pubkey, &record.sharedKey, &record.sessionToken, &record.expiry)
+	return &record, err
+}
+
+// sessionsCount is the reader of the number of the sessions held.
+type sessionsCount int
+
+// Read is the reader interface implementation for type sessionsCount.
+func (c *sessionsCount) Read(fn func(fields ...any) error) (interface{}, error) {
+	var count sessionsCount
+	err := fn(&count)
+	return &count, err
+}
+
+// expiredSession is the reader of the sender of a purged session.
+type expiredSession string
+
+// Read is the reader interface implementation for type expiredSession.
+func (e *expiredSession) Read(fn func(fields ...any) error) (interface{}, error) {
+	var sender expiredSession
+	err := fn(&sender)
+	return &sender, err
+}
+
+// challengeRecord holds a session challenge as persisted in the db.
+type challengeRecord struct {
+	message string
+	expiry  time.Time
+}
+
+// Read is the reader interface implementation for type challengeRecord.
+func (r *challengeRecord) Read(fn func(fields ...any) error) (interface{}, error) {
+	var record challengeRecord
+	err := fn(&record.message, &record.expiry)
+	return &record, err
+}
+
+// seal encrypts the session secret. The sender and the field name are
+// authenticated so that the secrets can't be swapped between the sessions or
+// their fields.
+func (p *pgSessions) seal(sender common.Address, field string, secret []byte) (string, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating the nonce failed: %v", err)
+	}
+
+	sealed := p.aead.Seal(nonce, nonce, secret, sessionAD(sender, field))
+	return hex.EncodeToString(sealed), nil
+}
+
+// open decrypts the session secret sealed for the sender's field.
+func (p *pgSessions) open(sender common.Address, field, sealed string) ([]byte, error) {
+	data, err := hex.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sealed session %s: %v", field, err)
+	}
+
+	nonceSize := p.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("invalid sealed session %s size", field)
+	}
+
+	secret, err := p.aead.Open(nil, data[:nonceSize], data[nonceSize:], sessionAD(sender, field))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting the session %s failed: %v", field, err)
+	}
+	return secret, nil
+}
+
+// sessionAD returns the additional data authenticated with the sender's
+// session field.
+func sessionAD(sender common.Address, field string) []byte {
+	return append(sender.Bytes(), field...)
+}
+
+// Store saves the sender's session replacing any existing one.
+func (p *pgSessions) Store(sender common.Address, session servertypes.ServerKeyResp) error {
+	sharedKey, err := p.seal(sender, "shared_key", session.SharedKey)
+	if err != nil {
+		return err
+	}
+
+	token, err := p.seal(sender, "session_token", []byte(session.SessionToken))
+	if err != nil {
+		return err
+	}
+
+	expiry := time.Unix(int64(session.Expiry), 0).UTC()
+	return p.db.SetLocalData(utils.InsertSession, sender.Hex(), session.Pubkey,
+		sharedKey, token, expiry)
+}
+
+// Load returns the sender's session. False is returned if the sender has no
+// session.
+func (p *pgSessions) Load(sender common.Address) (servertypes.ServerKeyResp, bool, error) {
+	var session servertypes.ServerKeyResp
+
+	data, err := p.db.QueryLocalData(utils.GetSession, new(sessionRecord), "", sender.Hex())
+	if err != nil || len(data) == 0 {
+		return session, false, err
+	}
+
+	record := data[0].(*sessionRecord)
+
+	sharedKey, err := p.open(sender, "shared_key", record.sharedKey)
+	if err != nil {
+		return session, false, err
+	}
+
+	token, err := p.open(sender, "session_token", record.sessionToken)
+	if err != nil {
+		return session, false, err
+	}
+
+	session.Pubkey = record.pubkey
+	session.Expiry = uint64(record.expiry.Unix())
+	session.SessionToken = string(token)
+	session.SharedKey = sharedKey
+	return session, true, nil
+}
+
+// Delete removes the sender's session.
+func (p *pgSessions) Delete(sender common.Address) error {
+	return p.db.SetLocalData(utils.DeleteSession, sender.Hex())
+}
+
+// PurgeExpired removes the sessions and the challenges expired before the
+// provided time and returns the number of the sessions removed.
+func (p *pgSessions) PurgeExpired(now time.Time) (int, error) {
+	if err := p.db.SetLocalData(utils.DeleteExpiredChallenges, now); err != nil {
+		return 0, err
+	}
+
+	data, err := p.db.QueryLocalData(utils.DeleteExpiredSessions, new(expiredSession), "", now)
+	return len(data), err
+}
+
+// Count returns the number of the sessions held.
+func (p *pgSessions) Count() (int, error) {
+	data, err := p.db.QueryLocalData(utils.CountSessions, new(sessionsCount), "")
+	if err != nil {
+		return 0, err
+	}
+
+	if len(data) == 0 {
+		return 0, errors.New("missing the sessions count")
+	}
+	return int(*data[0].(*sessionsCount)), nil
+}
+
+// StoreChallenge saves the sender's pending session challenge replacing any
+// existing one.
+func (p *pgSessions) StoreChallenge(sender common.Address, challenge sessionChallenge) error {
+	return p.db.SetLocalData(utils.InsertSessionChallenge, sender.Hex(), challenge.nonce,
+		challenge.message, challenge.expiry)
+}
+
+// TakeChallenge removes and returns the sender's pending session challenge
+// with the provided nonce. False is returned if no such challenge exists. The
+// challenge is removed and returned in a single statement so that it can only
+// be used once across the server instances.
+func (p *pgSessions) TakeChallenge(sender common.Address, nonce string) (sessionChallenge, bool, error) {
+	data, err := p.db.QueryLocalData(utils.TakeSessionChallenge, new(challengeRecord), "",
+		sender.Hex(), nonce)
+	if err != nil || len(data) == 0 {
+		return sessionChallenge{}, false, err
+	}
+
+	record := data[0].(*challengeRecord)
+	return sessionChallenge{
+		nonce:   nonce,
+		message: record.message,
+		expiry:  record.expiry,
+	}, true, nil
+}
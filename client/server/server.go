@@ -6,19 +6,21 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"net/http"
 	"net/url"
 	"path/filepath"
-	"sync"
 	"time"
 
 	"github.com/dmigwi/dhamana-protocol/client/contracts"
 	"github.com/dmigwi/dhamana-protocol/client/sapphire"
 	"github.com/dmigwi/dhamana-protocol/client/storage"
+	"github.com/dmigwi/dhamana-protocol/client/trustorg"
 	"github.com/dmigwi/dhamana-protocol/client/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // ServerConfig defines the configuration needed to run a TLS enabled server
@@ -28,6 +30,7 @@ type ServerConfig struct {
 	datadir      string
 	tlsCertFile  string
 	tlsKeyFile   string
+	clientAuth   *clientAuth
 	contractAddr common.Address
 	network      utils.NetworkType
 	ctx          context.Context
@@ -35,18 +38,105 @@ type ServerConfig struct {
 	backend  *sapphire.WrappedBackend
 	bondChat *contracts.Chat
 
-	// sessionKeys holds the sessional access keys associated with a given user.
-	sessionKeys *sync.Map
-	db          *storage.DB
+	// sessions holds the sessional access keys associated with a given user.
+	sessions SessionStore
+	db       *storage.DB
+
+	// challenges holds the session challenges yet to be signed.
+	challenges *challengeStore
+
+	// trustOrgs if set, registers the trust organisations and the users they
+	// vouch for.
+	trustOrgs *trustorg.Registry
+
+	// admins holds the addresses allowed to manage the trust organisations.
+	admins map[common.Address]struct{}
+
+	// subscriptions holds the websocket clients subscribed to the bond events.
+	subscriptions *subscriptions
+
+	// pending if set holds the events yet to be confirmed.
+	pending *pendingEvents
+
+	// syncer syncs the contract events into the db.
+	syncer *Syncer
+
+	// txTracker tracks the lifecycle of the submitted transactions.
+	txTracker *txTracker
+
+	// metrics if set, exposes the prometheus metrics.
+	metrics bool
+	// opsListen is the address of the plain HTTP listener serving the health,
+	// sync status and metrics routes.
+	opsListen string
+
+	// signingMode defines who signs the contract transactions.
+	signingMode utils.SigningMode
+}
+
+// Config defines the configuration used to create the server.
+type Config struct {
+	// Network is the network the contract is deployed on.
+	Network string
+	// ServerURL is the address the server listens on.
+	ServerURL string
+	// KeyManagerPubKey is the base64 encoded public key of the network's key
+	// manager, required to encrypt the calls on the confidential networks.
+	KeyManagerPubKey string
+	// DataDir is the directory holding the server files.
+	DataDir string
+	// TLSCertFile and TLSKeyFile are the server's TLS certificate and key
+	// file names in the data directory.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// DbHost, DbPort, DbName, DbUser and DbPassword define the db connection.
+	DbHost     string
+	DbPort     uint16
+	DbName     string
+	DbUser     string
+	DbPassword string
+
+	// Confirmations is the number of blocks an event must be buried under
+	// before it is persisted.
+	Confirmations uint64
+	// PendingEvents if set, holds the events yet to be confirmed so that they
+	// can be returned if requested.
+	PendingEvents bool
+	// BackfillWorkers is the maximum number of historical events block
+	// windows fetched at once.
+	BackfillWorkers int
+
+	// Metrics if set, exposes the prometheus metrics.
+	Metrics bool
+	// OpsListen is the address of the plain HTTP listener serving the health,
+	// sync status and metrics routes, which can't require client certificates.
+	OpsListen string
+
+	// SigningMode defines who signs the contract transactions.
+	SigningMode string
+
+	// TrustOrgCAFile and TrustOrgCRLFile are the trust organisations CAs and
+	// CRLs file names in the data directory. The CRLs file is optional.
+	TrustOrgCAFile  string
+	TrustOrgCRLFile string
+	// DeniedOrgs are the trust organisations denied access to the server.
+	DeniedOrgs []string
+	// Admins are the addresses allowed to manage the trust organisations.
+	Admins []string
+
+	// SessionStore is the store holding the sessions and session challenges.
+	SessionStore string
+	// SessionKeyFile is the file name of the master key encrypting the
+	// sessions persisted by the postgres store.
+	SessionKeyFile string
 }
 
 // NewServer validates the deployment configuration information before
 // creating a sapphire client wrapped around an eth client.
-func NewServer(ctx context.Context, port uint16, certfile, keyfile, datadir,
-	network, serverURL, dbHost, dbName, dbUser, dbPassword string,
-) (*ServerConfig, error) {
+func NewServer(ctx context.Context, cfg *Config) (*ServerConfig, error) {
 	// Validate deployment information first.
-	net := utils.ToNetType(network)
+	net := utils.ToNetType(cfg.Network)
 	if !isDeployedNetMatching(net) {
 		log.Error("Network mismatch")
 		return nil, utils.ErrCorruptedConfig // network mismatch
@@ -54,6 +144,35 @@ func NewServer(ctx context.Context, port uint16, certfile, keyfile, datadir,
 
 	log.Infof("Running on the network=%s", net)
 
+	mode := utils.ToSigningMode(cfg.SigningMode)
+	if mode == "" {
+		log.Errorf("unsupported signing mode %q found", cfg.SigningMode)
+		return nil, utils.ErrCorruptedConfig
+	}
+
+	log.Infof("Transactions signing mode=%s", mode)
+
+	// Only the POAs with client certificates issued by the trust organisations
+	// can access the server.
+	var crlPath string
+	if cfg.TrustOrgCRLFile != "" {
+		crlPath = filepath.Join(cfg.DataDir, cfg.TrustOrgCRLFile)
+	}
+
+	auth, err := newClientAuth(filepath.Join(cfg.DataDir, cfg.TrustOrgCAFile), crlPath,
+		cfg.DeniedOrgs)
+	if err != nil {
+		log.Errorf("loading the trust organisations certificates failed: %v", err)
+		return nil, err
+	}
+
+	log.Infof("Client certificates verified using the trust organisations CAs=%s",
+		cfg.TrustOrgCAFile)
+
+	if len(cfg.DeniedOrgs) > 0 {
+		log.Infof("Trust organisations denied access=%v", cfg.DeniedOrgs)
+	}
+
 	address := getContractAddress(net)
 	if address == common.HexToAddress("") {
 		log.Error("Empty Address found")
@@ -106,6 +225,13 @@ func NewServer(ctx context.Context, port uint16, certfile, keyfile, datadir,
 		return nil, err
 	}
 
+	if cfg.KeyManagerPubKey != "" {
+		if err := utils.SetKeyManagerPubKey(net, cfg.KeyManagerPubKey); err != nil {
+			log.Errorf("setting the key manager public key failed: %v", err)
+			return nil, err
+		}
+	}
+
 	log.Info("Creating a sapphire client wrapped over an eth client")
 
 	backend, err := sapphire.WrapClient(ctx, conn, net,
@@ -130,38 +256,147 @@ func NewServer(ctx context.Context, port uint16, certfile, keyfile, datadir,
 	}
 
 	db, err := storage.NewDB(ctx,
-		storage.ConnectionString(port, dbHost, dbUser, dbPassword, dbName))
+		storage.ConnectionString(cfg.DbPort, cfg.DbHost, cfg.DbUser, cfg.DbPassword, cfg.DbName))
 	if err != nil {
 		return nil, err
 	}
 
-	return &ServerConfig{
+	var sessions SessionStore
+	switch cfg.SessionStore {
+	case MemorySessions:
+		sessions = newMemSessions()
+
+	case PostgresSessions:
+		masterKey, err := loadMasterKey(filepath.Join(cfg.DataDir, cfg.SessionKeyFile))
+		if err != nil {
+			log.Errorf("loading the sessions master key failed: %v", err)
+			return nil, err
+		}
+
+		if sessions, err = newPGSessions(db, masterKey); err != nil {
+			log.Errorf("creating the postgres session store failed: %v", err)
+			return nil, err
+		}
+
+	default:
+		log.Errorf("unsupported session store %q found", cfg.SessionStore)
+		return nil, utils.ErrCorruptedConfig
+	}
+
+	log.Infof("Sessions held in the store=%s", cfg.SessionStore)
+
+	// The registered trust organisations are verified on every handshake.
+	registry := trustorg.NewRegistry(db)
+	if err = loadTrustOrgs(registry, auth); err != nil {
+		log.Errorf("loading the registered trust organisations failed: %v", err)
+		return nil, err
+	}
+
+	adminAddrs := make(map[common.Address]struct{}, len(cfg.Admins))
+	for _, admin := range cfg.Admins {
+		adminAddrs[common.HexToAddress(admin)] = struct{}{}
+	}
+
+	log.Infof("Trust organisations admins count=%d", len(adminAddrs))
+
+	log.Infof("Events are persisted after confirmations=%d", cfg.Confirmations)
+
+	var pending *pendingEvents
+	if cfg.PendingEvents {
+		pending = newPendingEvents()
+	}
+
+	s := &ServerConfig{
 		ctx:          ctx,
 		network:      net,
 		contractAddr: address,
-		serverURL:    serverURL,
-		datadir:      datadir,
-		tlsCertFile:  certfile,
-		tlsKeyFile:   keyfile,
-
-		backend:     backend,
-		bondChat:    chatInstance,
-		sessionKeys: new(sync.Map),
-		db:          db,
-	}, nil
+		serverURL:    cfg.ServerURL,
+		datadir:      cfg.DataDir,
+		tlsCertFile:  cfg.TLSCertFile,
+		tlsKeyFile:   cfg.TLSKeyFile,
+		clientAuth:   auth,
+
+		backend:       backend,
+		bondChat:      chatInstance,
+		sessions:      sessions,
+		challenges:    newChallengeStore(cfg.ServerURL, backend.ChainID(), sessions),
+		trustOrgs:     registry,
+		admins:        adminAddrs,
+		db:            db,
+		subscriptions: newSubscriptions(),
+		pending:       pending,
+		metrics:       cfg.Metrics,
+		opsListen:     cfg.OpsListen,
+		signingMode:   mode,
+		txTracker: &txTracker{
+			contractAddr: address,
+			backend:      backend,
+			bondChat:     chatInstance,
+			db:           db,
+		},
+	}
+
+	if cfg.Metrics {
+		if err = prometheus.Register(sessionKeysGauge(net, s.sessions)); err != nil {
+			log.Errorf("registering the session keys metric failed: %v", err)
+			return nil, err
+		}
+	}
+
+	s.syncer = &Syncer{
+		network:         net,
+		contractAddr:    address,
+		backend:         backend,
+		bondChat:        chatInstance,
+		db:              db,
+		confirmations:   cfg.Confirmations,
+		backfillWorkers: cfg.BackfillWorkers,
+		pending:         pending,
+		notify:          s.notifySubscribers,
+	}
+
+	return s, nil
 }
 
-// Run the actual TLS server instance using mTLS where both server and client
-// must share their certificates.
-func (s *ServerConfig) Run() error {
+// Syncer returns the syncer persisting the events of the server's contract.
+func (s *ServerConfig) Syncer() *Syncer {
+	return s.syncer
+}
+
+// routes returns the routes served to the POA clients over mTLS.
+func (s *ServerConfig) routes() *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.welcomeTextFunc)
 	mux.HandleFunc("/backend", s.backendQueryFunc)
 	mux.HandleFunc("/serverpubkey", s.serverPubkey)
+	mux.HandleFunc("/subscribe", s.subscribeFunc)
+	return mux
+}
+
+// opsRoutes returns the health, sync status and metrics routes polled by the
+// orchestrators and the monitoring tools, which hold no client certificates.
+func (s *ServerConfig) opsRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.healthzFunc)
+	mux.HandleFunc("/syncstatus", s.syncStatusFunc)
+
+	if s.metrics {
+		mux.Handle("/metrics", metricsHandler())
+	}
+	return mux
+}
+
+// Run the actual TLS server instance using mTLS where both server and client
+// must share their certificates. The client certificates must be verified by
+// the trust organisations CAs. The ops routes are served on a separate plain
+// HTTP listener.
+func (s *ServerConfig) Run() error {
+	go s.txTracker.run(s.ctx)
+	go s.reapSessions(s.ctx)
+	go s.serveOps()
 
 	cfg := &tls.Config{
 		MinVersion: tls.VersionTLS12,
-		ClientAuth: tls.RequireAnyClientCert,
 		CipherSuites: []uint16{
 			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
 			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
@@ -171,20 +406,46 @@ func (s *ServerConfig) Run() error {
 		},
 	}
 
+	// Generate the complete path to the cert and key files.
+	certPath := filepath.Join(s.datadir, s.tlsCertFile)
+	keyPath := filepath.Join(s.datadir, s.tlsKeyFile)
+
+	// The certificates are loaded before the config is cloned for every
+	// client handshake.
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return fmt.Errorf("loading the server certificate failed: %v", err)
+	}
+	cfg.Certificates = []tls.Certificate{cert}
+
+	// The client certificates must be issued by the trust organisations.
+	s.clientAuth.configure(cfg)
+
 	// Ignore the error because the url has already been validated.
 	serverURL, _ := url.Parse(s.serverURL)
 	srv := &http.Server{
 		Addr:         serverURL.Host,
-		Handler:      mux,
+		Handler:      s.routes(),
 		TLSConfig:    cfg,
 		TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler), 0),
 	}
 
-	// Generate the complete path to the cert and key files.
-	certPath := filepath.Join(s.datadir, s.tlsCertFile)
-	keyPath := filepath.Join(s.datadir, s.tlsKeyFile)
-
 	log.Infof("Initiating the server on=%s", s.serverURL)
 
-	return srv.ListenAndServeTLS(certPath, keyPath)
+	return srv.ListenAndServeTLS("", "")
+}
+
+// serveOps serves the ops routes on a separate plain HTTP listener.
+func (s *ServerConfig) serveOps() {
+	srv := &http.Server{
+		Addr:              s.opsListen,
+		Handler:           s.opsRoutes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Infof("Serving the ops routes on=http://%s", s.opsListen)
+
+	if err := srv.ListenAndServe(); err != nil {
+		log.Errorf("ops server failed: %v", err)
+	}
 }
@@ -0,0 +1,212 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// challengeTime defines the duration within which a session challenge
+	// must be signed and sent back.
+	challengeTime = 2 * time.Minute
+
+	// maxPendingChallenges defines the maximum number of session challenges
+	// the memory store holds at the same time. It also bounds the number of
+	// the remote addresses whose challenge requests are counted.
+	maxPendingChallenges = 10000
+
+	// maxChallengesPerWindow defines the maximum number of session challenges
+	// a remote address can request within a challengeRateWindow.
+	maxChallengesPerWindow = 10
+
+	// challengeRateWindow defines the period over which the session challenges
+	// requested by a remote address are counted.
+	challengeRateWindow = time.Minute
+
+	// challengeNonceSize defines the size in bytes of the challenge nonce.
+	challengeNonceSize = 32
+
+	// sessionTokenSize defines the size in bytes of the session token.
+	sessionTokenSize = 32
+
+	// challengeTemplate is the message the client signs using the EIP-191
+	// personal_sign scheme to prove it controls the sender address. The server
+	// and the chain ID bind the signature to this deployment.
+	challengeTemplate = "Dhamana protocol session request\n" +
+		"Server: %s\nChain ID: %s\nAddress: %s\nNonce: %s\nExpiry: %d"
+)
+
+var (
+	// errTooManyChallenges is returned if the pending session challenges limit
+	// has been reached.
+	errTooManyChallenges = errors.New("too many pending session challenges")
+
+	// errChallengeRateLimited is returned if the remote address requested too
+	// many session challenges within the rate window.
+	errChallengeRateLimited = errors.New("too many session challenges requested")
+)
+
+// sessionChallenge defines a challenge issued to a sender that is yet to be
+// signed.
+type sessionChallenge struct {
+	nonce   string
+	message string
+	expiry  time.Time
+}
+
+// challengeRate counts the session challenges requested by a remote address
+// within the current rate window.
+type challengeRate struct {
+	count       int
+	windowStart time.Time
+}
+
+// challengeStore issues the session challenges and rate limits the remote
+// addresses requesting them. The pending challenges are held in the session
+// store, indexed by their sender. Each sender holds a single pending
+// challenge, replaced by any new one issued.
+type challengeStore struct {
+	// server and chainID identify the deployment the challenges are signed for.
+	server  string
+	chainID string
+
+	store SessionStore
+
+	mtx   sync.Mutex
+	rates map[string]*challengeRate
+}
+
+// newChallengeStore returns a challenges store issuing the challenges of the
+// provided server and chain ID into the session store.
+func newChallengeStore(server string, chainID *big.Int, store SessionStore) *challengeStore {
+	return &challengeStore{
+		server:  server,
+		chainID: chainID.String(),
+		store:   store,
+		rates:   make(map[string]*challengeRate),
+	}
+}
+
+// issue creates a new challenge for the sender requested from the remote
+// address and returns its nonce and the message to be signed. Any pending
+// challenge of the sender is replaced.
+func (c *challengeStore) issue(sender common.Address, remoteAddr string) (string, sessionChallenge, error) {
+	now := time.Now().UTC()
+	if !c.allow(remoteAddr, now) {
+		return "", sessionChallenge{}, errChallengeRateLimited
+	}
+
+	nonce, err := randomHex(challengeNonceSize)
+	if err != nil {
+		return "", sessionChallenge{}, err
+	}
+
+	expiry := now.Add(challengeTime)
+	challenge := sessionChallenge{
+		nonce:   nonce,
+		message: fmt.Sprintf(challengeTemplate, c.server, c.chainID, sender, nonce, expiry.Unix()),
+		expiry:  expiry,
+	}
+
+	if err = c.store.StoreChallenge(sender, challenge); err != nil {
+		return "", sessionChallenge{}, err
+	}
+	return nonce, challenge, nil
+}
+
+// allow returns true if the remote address can be issued another challenge
+// within its rate window.
+func (c *challengeStore) allow(remoteAddr string, now time.Time) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	rate, ok := c.rates[remoteAddr]
+	if !ok || now.Sub(rate.windowStart) >= challengeRateWindow {
+		if !ok && len(c.rates) >= maxPendingChallenges {
+			c.pruneRates(now)
+		}
+
+		c.rates[remoteAddr] = &challengeRate{count: 1, windowStart: now}
+		return true
+	}
+
+	if rate.count >= maxChallengesPerWindow {
+		return false
+	}
+
+	rate.count++
+	return true
+}
+
+// consume removes the sender's challenge with the provided nonce and returns
+// the message that should have been signed. A challenge can only be used once.
+func (c *challengeStore) consume(sender common.Address, nonce string) (string, error) {
+	challenge, ok, err := c.store.TakeChallenge(sender, nonce)
+	if err != nil {
+		return "", fmt.Errorf("fetching the session challenge failed: %v", err)
+	}
+
+	if !ok {
+		return "", errors.New("no session challenge found for the sender")
+	}
+
+	if time.Now().UTC().After(challenge.expiry) {
+		return "", errors.New("session challenge expired")
+	}
+	return challenge.message, nil
+}
+
+// pruneRates deletes the rate windows that have elapsed. It must be called
+// with the store mutex held.
+func (c *challengeStore) pruneRates(now time.Time) {
+	for remoteAddr, rate := range c.rates {
+		if now.Sub(rate.windowStart) >= challengeRateWindow {
+			delete(c.rates, remoteAddr)
+		}
+	}
+}
+
+// verifyChallengeSig confirms that the EIP-191 signature of the challenge
+// message was created by the sender's private key.
+func verifyChallengeSig(sender common.Address, message, signature string) error {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return errors.New("invalid challenge signature encoding")
+	}
+
+	// Wallets set the recovery id as 27 or 28 as required by the yellow paper.
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubkey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return fmt.Errorf("invalid challenge signature: %v", err)
+	}
+
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != sender {
+		return fmt.Errorf("challenge signed by %v instead of the sender", signer)
+	}
+	return nil
+}
+
+// randomHex returns a hex encoded random value of the provided size.
+func randomHex(size int) (string, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hexutil.Encode(data), nil
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// remoteAddr is the remote address requesting the session challenges.
+const remoteAddr = "192.0.2.1"
+
+// TestVerifyChallengeSig tests the verification of the signed session challenges.
+func TestVerifyChallengeSig(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	message := "Dhamana protocol session request"
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	// Wallets return the recovery id as 27 or 28.
+	walletSig := append([]byte{}, sig...)
+	walletSig[crypto.RecoveryIDOffset] += 27
+
+	td := []struct {
+		testName  string
+		message   string
+		signature string
+		isValid   bool
+	}{
+		{"Test-for-recovery-id-0-or-1", message, hexutil.Encode(sig), true},
+		{"Test-for-recovery-id-27-or-28", message, hexutil.Encode(walletSig), true},
+		{"Test-for-another-message", "another message", hexutil.Encode(sig), false},
+		{"Test-for-invalid-encoding", message, "signature", false},
+		{"Test-for-short-signature", message, hexutil.Encode(sig[:64]), false},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			err := verifyChallengeSig(sender, v.message, v.signature)
+			if (err == nil) != v.isValid {
+				t.Fatalf("expected the signature validity to be %v but found error %v",
+					v.isValid, err)
+			}
+		})
+	}
+}
+
+// TestChallengeStore tests that the session challenges can only be used once
+// by their own sender before they expire.
+func TestChallengeStore(t *testing.T) {
+	sessions := newMemSessions()
+	store := newChallengeStore("https://0.0.0.0:30443", big.NewInt(1337), sessions)
+
+	nonce, challenge, err := store.issue(sampleHexAddress1, remoteAddr)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	for _, field := range []string{"Server: https://0.0.0.0:30443", "Chain ID: 1337"} {
+		if !strings.Contains(challenge.message, field) {
+			t.Fatalf("expected the challenge message %q to hold %q", challenge.message, field)
+		}
+	}
+
+	if _, err = store.consume(sampleHexAddress2, nonce); err == nil {
+		t.Fatal("expected the challenge of another sender to be rejected")
+	}
+
+	if _, err = store.consume(sampleHexAddress1, nonce); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if _, err = store.consume(sampleHexAddress1, nonce); err == nil {
+		t.Fatal("expected the used challenge to be rejected")
+	}
+
+	// A new challenge replaces the sender's pending one.
+	replacedNonce, _, err := store.issue(sampleHexAddress1, remoteAddr)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	expiredNonce, challenge, err := store.issue(sampleHexAddress1, remoteAddr)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if len(sessions.challenges) != 1 {
+		t.Fatalf("expected a single pending challenge but found %d", len(sessions.challenges))
+	}
+
+	if _, err = store.consume(sampleHexAddress1, replacedNonce); err == nil {
+		t.Fatal("expected the replaced challenge to be rejected")
+	}
+
+	challenge.expiry = time.Now().UTC().Add(-time.Second)
+	sessions.challenges[sampleHexAddress1] = challenge
+
+	if _, err = store.consume(sampleHexAddress1, expiredNonce); err == nil {
+		t.Fatal("expected the expired challenge to be rejected")
+	}
+}
+
+// TestChallengeStoreRateLimit tests that the challenges requested by a remote
+// address are limited within the rate window.
+func TestChallengeStoreRateLimit(t *testing.T) {
+	store := newChallengeStore("https://0.0.0.0:30443", big.NewInt(1337), newMemSessions())
+
+	for i := 0; i < maxChallengesPerWindow; i++ {
+		if _, _, err := store.issue(sampleHexAddress1, remoteAddr); err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+	}
+
+	if _, _, err := store.issue(sampleHexAddress2, remoteAddr); !errors.Is(err, errChallengeRateLimited) {
+		t.Fatalf("expected error %q but found %v", errChallengeRateLimited, err)
+	}
+
+	if _, _, err := store.issue(sampleHexAddress2, "192.0.2.2"); err != nil {
+		t.Fatalf("expected another remote address to be allowed but found %q", err)
+	}
+
+	// The limit is lifted once the rate window elapses.
+	store.rates[remoteAddr].windowStart = time.Now().UTC().Add(-challengeRateWindow)
+	if _, _, err := store.issue(sampleHexAddress2, remoteAddr); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// sessionReapInterval describes the intervals at which the expired
+	// sessions are purged.
+	sessionReapInterval = time.Minute
+
+	// MemorySessions holds the sessions and the session challenges in memory.
+	// They are lost on restart and aren't shared between the server instances.
+	MemorySessions = "memory"
+
+	// PostgresSessions persists the sessions and the session challenges in the
+	// postgres db shared by the server instances.
+	PostgresSessions = "postgres"
+)
+
+// SessionStore holds the sessions established by the POA clients once they
+// sign the session challenge, and the challenges pending their signature. A
+// challenge issued by a server instance can be signed on any other instance
+// sharing the store.
+type SessionStore interface {
+	// Store saves the sender's session replacing any existing one.
+	Store(sender common.Address, session servertypes.ServerKeyResp) error
+
+	// Load returns the sender's session. False is returned if the sender has
+	// no session.
+	Load(sender common.Address) (servertypes.ServerKeyResp, bool, error)
+
+	// Delete removes the sender's session.
+	Delete(sender common.Address) error
+
+	// PurgeExpired removes the sessions and the challenges expired before the
+	// provided time and returns the number of the sessions removed.
+	PurgeExpired(now time.Time) (int, error)
+
+	// Count returns the number of the sessions held.
+	Count() (int, error)
+
+	// StoreChallenge saves the sender's pending session challenge replacing
+	// any existing one.
+	StoreChallenge(sender common.Address, challenge sessionChallenge) error
+
+	// TakeChallenge removes and returns the sender's pending session challenge
+	// with the provided nonce. False is returned if no such challenge exists.
+	TakeChallenge(sender common.Address, nonce string) (sessionChallenge, bool, error)
+}
+
+// memSessions is a SessionStore holding the sessions in memory.
+type memSessions struct {
+	sessions sync.Map
+
+	mtx        sync.Mutex
+	challenges map[common.Address]sessionChallenge
+}
+
+// newMemSessions returns an empty in-memory session store.
+func newMemSessions() *memSessions {
+	return &memSessions{challenges: make(map[common.Address]sessionChallenge)}
+}
+
+// Store saves the sender's session replacing any existing one.
+func (m *memSessions) Store(sender common.Address, session servertypes.ServerKeyResp) error {
+	m.sessions.Store(sender, session)
+	return nil
+}
+
+// Load returns the sender's session. False is returned if the sender has no
+// session.
+func (m *memSessions) Load(sender common.Address) (servertypes.ServerKeyResp, bool, error) {
+	data, ok := m.sessions.Load(sender)
+	if !ok {
+		return servertypes.ServerKeyResp{}, false, nil
+	}
+	return data.(servertypes.ServerKeyResp), true, nil
+}
+
+// Delete removes the sender's session.
+func (m *memSessions) Delete(sender common.Address) error {
+	m.sessions.Delete(sender)
+	return nil
+}
+
+// PurgeExpired removes the sessions and the challenges expired before the
+// provided time and returns the number of the sessions removed.
+func (m *memSessions) PurgeExpired(now time.Time) (int, error) {
+	m.mtx.Lock()
+	m.pruneChallenges(now)
+	m.mtx.Unlock()
+
+	var count int
+	m.sessions.Range(func(sender, data any) bool {
+		expiry := time.Unix(int64(data.(servertypes.ServerKeyResp).Expiry), 0)
+		if now.After(expiry) {
+			m.sessions.Delete(sender)
+			count++
+		}
+		return true
+	})
+	return count, nil
+}
+
+// Count returns the number of the sessions held.
+func (m *memSessions) Count() (int, error) {
+	var count int
+	m.sessions.Range(func(_, _ any) bool {
+		count++
+		return true
+	})
+	return count, nil
+}
+
+// StoreChallenge saves the sender's pending session challenge replacing any
+// existing one. At most maxPendingChallenges are held at the same time.
+func (m *memSessions) StoreChallenge(sender common.Address, challenge sessionChallenge) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.challenges[sender]; !ok && len(m.challenges) >= maxPendingChallenges {
+		m.pruneChallenges(time.Now().UTC())
+
+		if len(m.challenges) >= maxPendingChallenges {
+			return errTooManyChallenges
+		}
+	}
+
+	m.challenges[sender] = challenge
+	return nil
+}
+
+// TakeChallenge removes and returns the sender's pending session challenge
+// with the provided nonce. False is returned if no such challenge exists.
+func (m *memSessions) TakeChallenge(sender common.Address, nonce string) (sessionChallenge, bool, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	challenge, ok := m.challenges[sender]
+	if !ok || challenge.nonce != nonce {
+		return sessionChallenge{}, false, nil
+	}
+
+	delete(m.challenges, sender)
+	return challenge, true, nil
+}
+
+// pruneChallenges deletes the challenges expired before the provided time. It
+// must be called with the challenges mutex held.
+func (m *memSessions) pruneChallenges(now time.Time) {
+	for sender, challenge := range m.challenges {
+		if now.After(challenge.expiry) {
+			delete(m.challenges, sender)
+		}
+	}
+}
+
+// reapSessions purges the expired sessions and challenges at every
+// sessionReapInterval until the context is cancelled. Without it, the sessions
+// of the clients that never return are held forever.
+func (s *ServerConfig) reapSessions(ctx context.Context) {
+	ticker := time.NewTicker(sessionReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.sessions.PurgeExpired(time.Now().UTC())
+			if err != nil {
+				log.Errorf("purging the expired sessions failed: %v", err)
+				continue
+			}
+
+			if count > 0 {
+				log.Debugf("Purged %d expired sessions", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/storage"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// sessionsTable is an in-memory table of the persisted sessions.
+type sessionsTable struct {
+	rows       map[string]sessionRecord
+	challenges map[string]sessionChallenge
+}
+
+// newSessionsTable returns an empty sessions table.
+func newSessionsTable() *sessionsTable {
+	return &sessionsTable{
+		rows:       make(map[string]sessionRecord),
+		challenges: make(map[string]sessionChallenge),
+	}
+}
+
+func (s *sessionsTable) QueryLocalData(method utils.Method, r storage.Reader, _ string,
+	params ...interface{},
+) ([]interface{}, error) {
+	var data []interface{}
+	read := func(fn func(fields ...any) error) error {
+		row, err := r.Read(fn)
+		data = append(data, row)
+		return err
+	}
+
+	var err error
+	switch method {
+	case utils.GetSession:
+		record, ok := s.rows[params[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+
+		err = read(func(fields ...any) error {
+			*fields[0].(*string) = record.pubkey
+			*fields[1].(*string) = record.sharedKey
+			*fields[2].(*string) = record.sessionToken
+			*fields[3].(*time.Time) = record.expiry
+			return nil
+		})
+
+	case utils.CountSessions:
+		err = read(func(fields ...any) error {
+			*fields[0].(*sessionsCount) = sessionsCount(len(s.rows))
+			return nil
+		})
+
+	case utils.TakeSessionChallenge:
+		challenge, ok := s.challenges[params[0].(string)]
+		if !ok || challenge.nonce != params[1].(string) {
+			return nil, nil
+		}
+
+		delete(s.challenges, params[0].(string))
+		err = read(func(fields ...any) error {
+			*fields[0].(*string) = challenge.message
+			*fields[1].(*time.Time) = challenge.expiry
+			return nil
+		})
+
+	case utils.DeleteExpiredSessions:
+		for sender, record := range s.rows {
+			if !record.expiry.Before(params[0].(time.Time)) {
+				continue
+			}
+
+			delete(s.rows, sender)
+			err = read(func(fields ...any) error {
+				*fields[0].(*expiredSession) = expiredSession(sender)
+				return nil
+			})
+		}
+	}
+	return data, err
+}
+
+func (s *sessionsTable) SetLocalData(method utils.Method, params ...interface{}) error {
+	switch method {
+	case utils.InsertSession:
+		s.rows[params[0].(string)] = sessionRecord{
+			pubkey:       params[1].(string),
+			sharedKey:    params[2].(string),
+			sessionToken: params[3].(string),
+			expiry:       params[4].(time.Time),
+		}
+	case utils.DeleteSession:
+		delete(s.rows, params[0].(string))
+	case utils.InsertSessionChallenge:
+		s.challenges[params[0].(string)] = sessionChallenge{
+			nonce:   params[1].(string),
+			message: params[2].(string),
+			expiry:  params[3].(time.Time),
+		}
+	case utils.DeleteExpiredChallenges:
+		for sender, challenge := range s.challenges {
+			if challenge.expiry.Before(params[0].(time.Time)) {
+				delete(s.challenges, sender)
+			}
+		}
+	}
+	return nil
+}
+
+// TestSessionStores tests that the session stores hold the sessions until they
+// are deleted or purged once expired.
+func TestSessionStores(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{7}, masterKeySize)
+	pgStore, err := newPGSessions(newSessionsTable(), masterKey)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	fresh := servertypes.ServerKeyResp{
+		Pubkey:       pubkey1,
+		Expiry:       uint64(time.Now().UTC().Add(sessionTime).Unix()),
+		SessionToken: sampleSessionToken,
+		SharedKey:    []byte(sharedKey1),
+	}
+
+	expired := fresh
+	expired.Expiry = uint64(time.Now().UTC().Add(-time.Minute).Unix())
+
+	td := []struct {
+		testName string
+		store    SessionStore
+	}{
+		{"Test-for-memory-store", newMemSessions()},
+		{"Test-for-postgres-store", pgStore},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			if err := v.store.Store(sampleHexAddress1, fresh); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if err := v.store.Store(sampleHexAddress2, expired); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			session, ok, err := v.store.Load(sampleHexAddress1)
+			if err != nil || !ok {
+				t.Fatalf("expected the session to be found but found error %v", err)
+			}
+
+			if session.Pubkey != fresh.Pubkey || session.Expiry != fresh.Expiry ||
+				session.SessionToken != fresh.SessionToken ||
+				!bytes.Equal(session.SharedKey, fresh.SharedKey) {
+				t.Fatalf("expected the session %+v but found %+v", fresh, session)
+			}
+
+			count, err := v.store.PurgeExpired(time.Now().UTC())
+			if err != nil || count != 1 {
+				t.Fatalf("expected 1 expired session purged but found %d with error %v",
+					count, err)
+			}
+
+			if _, ok, _ = v.store.Load(sampleHexAddress2); ok {
+				t.Fatal("expected the expired session to be purged")
+			}
+
+			if err = v.store.Delete(sampleHexAddress1); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if count, err = v.store.Count(); err != nil || count != 0 {
+				t.Fatalf("expected no sessions held but found %d with error %v", count, err)
+			}
+		})
+	}
+}
+
+// TestSessionStoresChallenges tests that the session stores hold a single
+// pending challenge per sender which can only be taken once.
+func TestSessionStoresChallenges(t *testing.T) {
+	pgStore, err := newPGSessions(newSessionsTable(), bytes.Repeat([]byte{7}, masterKeySize))
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	expiry := time.Now().UTC().Add(challengeTime).Truncate(time.Second)
+
+	td := []struct {
+		testName string
+		store    SessionStore
+	}{
+		{"Test-for-memory-store", newMemSessions()},
+		{"Test-for-postgres-store", pgStore},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			replaced := sessionChallenge{nonce: "0x01", message: "message-1", expiry: expiry}
+			pending := sessionChallenge{nonce: "0x02", message: "message-2", expiry: expiry}
+
+			for _, challenge := range []sessionChallenge{replaced, pending} {
+				if err := v.store.StoreChallenge(sampleHexAddress1, challenge); err != nil {
+					t.Fatalf("expected no error but found %q", err)
+				}
+			}
+
+			if _, ok, _ := v.store.TakeChallenge(sampleHexAddress1, replaced.nonce); ok {
+				t.Fatal("expected the replaced challenge to be missing")
+			}
+
+			challenge, ok, err := v.store.TakeChallenge(sampleHexAddress1, pending.nonce)
+			if err != nil || !ok {
+				t.Fatalf("expected the pending challenge to be found but found error %v", err)
+			}
+
+			if challenge.message != pending.message || !challenge.expiry.Equal(pending.expiry) {
+				t.Fatalf("expected the challenge %+v but found %+v", pending, challenge)
+			}
+
+			if _, ok, _ = v.store.TakeChallenge(sampleHexAddress1, pending.nonce); ok {
+				t.Fatal("expected the taken challenge to be missing")
+			}
+
+			expired := sessionChallenge{nonce: "0x03", message: "message-3",
+				expiry: time.Now().UTC().Add(-time.Minute)}
+			if err = v.store.StoreChallenge(sampleHexAddress2, expired); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if _, err = v.store.PurgeExpired(time.Now().UTC()); err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if _, ok, _ = v.store.TakeChallenge(sampleHexAddress2, expired.nonce); ok {
+				t.Fatal("expected the expired challenge to be purged")
+			}
+		})
+	}
+}
+
+// TestPGSessionsEncryption tests that the sessions secrets are only readable
+// using the master key they were encrypted with.
+func TestPGSessionsEncryption(t *testing.T) {
+	table := newSessionsTable()
+	masterKey := bytes.Repeat([]byte{7}, masterKeySize)
+
+	if _, err := newPGSessions(table, masterKey[:16]); err == nil {
+		t.Fatal("expected a short master key to be rejected")
+	}
+
+	store, err := newPGSessions(table, masterKey)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	err = store.Store(sampleHexAddress1, servertypes.ServerKeyResp{
+		Pubkey:       pubkey1,
+		Expiry:       uint64(time.Now().UTC().Add(sessionTime).Unix()),
+		SessionToken: sampleSessionToken,
+		SharedKey:    []byte(sharedKey1),
+	})
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	record := table.rows[sampleHexAddress1.Hex()]
+	if strings.Contains(record.sessionToken, strings.TrimPrefix(sampleSessionToken, "0x")) ||
+		strings.Contains(record.sharedKey, strings.TrimPrefix(sharedKey1, "0x")) {
+		t.Fatal("expected the session secrets to be encrypted at rest")
+	}
+
+	otherStore, err := newPGSessions(table, bytes.Repeat([]byte{8}, masterKeySize))
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if _, _, err = otherStore.Load(sampleHexAddress1); err == nil {
+		t.Fatal("expected the session decryption with another master key to fail")
+	}
+
+	// The secrets are bound to the sender's session.
+	table.rows[sampleHexAddress2.Hex()] = record
+	if _, _, err = store.Load(sampleHexAddress2); err == nil {
+		t.Fatal("expected the decryption of another sender's session secrets to fail")
+	}
+
+	if _, _, err = store.Load(common.HexToAddress("0x01")); err != nil {
+		t.Fatalf("expected no error for a missing session but found %q", err)
+	}
+}
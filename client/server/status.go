@@ -0,0 +1,119 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+)
+
+// maxReadyLag defines the number of blocks, beyond the required confirmations,
+// the syncer can lag behind the chain tip while still being considered ready.
+const maxReadyLag int64 = 50
+
+// Status returns the current events sync progress. The chain and db details
+// that could not be fetched are left unset.
+func (s *Syncer) Status(ctx context.Context) *servertypes.SyncStatusResp {
+	s.mtx.Lock()
+	status := &servertypes.SyncStatusResp{
+		Running:         s.running,
+		LastPoll:        s.lastPoll,
+		LastSyncedBlock: -1,
+		BestBlock:       -1,
+	}
+	if s.syncErr != nil {
+		status.SyncError = s.syncErr.Error()
+	}
+	s.mtx.Unlock()
+
+	status.DBConnected = s.db.Ping() == nil
+	if status.DBConnected {
+		data, err := s.db.QueryLocalData(utils.GetLastSyncedBlock,
+			new(servertypes.LastSyncedBlockResp), "", s.contractAddr.Hex(), s.network.String())
+		if err == nil && len(data) > 0 {
+			status.LastSyncedBlock = int64(*data[0].(*servertypes.LastSyncedBlockResp))
+		}
+	}
+
+	if epoch, age, ok := s.backend.RuntimeKeyAge(); ok {
+		status.RuntimeKeyEpoch = epoch
+		status.RuntimeKeyAge = int64(age.Seconds())
+	}
+
+	bestHeader, err := s.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		log.Errorf("fetching the current bestblock failed: %v", err)
+		return status
+	}
+	status.BestBlock = bestHeader.Number.Int64()
+
+	if status.LastSyncedBlock < 0 {
+		return status
+	}
+	status.LagBlocks = status.BestBlock - status.LastSyncedBlock
+
+	syncedHeader, err := s.backend.HeaderByNumber(ctx, big.NewInt(status.LastSyncedBlock))
+	if err != nil {
+		log.Errorf("fetching block %d header failed: %v", status.LastSyncedBlock, err)
+		return status
+	}
+	status.LagSeconds = int64(bestHeader.Time) - int64(syncedHeader.Time)
+
+	status.Ready = s.isReady(status)
+	return status
+}
+
+// isAlive returns true if the sync has not ended. A sync that ended requires
+// the syncer to be restarted.
+func isAlive(status *servertypes.SyncStatusResp) bool {
+	return status.Running && status.SyncError == ""
+}
+
+// isReady returns true if the syncer is alive, the db is reachable and the
+// persisted events are close enough to the chain tip to be served.
+func (s *Syncer) isReady(status *servertypes.SyncStatusResp) bool {
+	if !isAlive(status) || !status.DBConnected {
+		return false
+	}
+
+	// Events are polled every polling interval, allow one missed poll.
+	if time.Since(status.LastPoll) > 2*pollinginterval {
+		return false
+	}
+
+	return status.LagBlocks <= int64(s.confirmations)+maxReadyLag
+}
+
+// healthzFunc reports the syncer liveness. It returns http code 200 if the
+// sync is still running otherwise http code 500 is returned indicating that
+// the syncer needs to be restarted.
+func (s *ServerConfig) healthzFunc(w http.ResponseWriter, req *http.Request) {
+	status := s.syncer.Status(req.Context())
+
+	code := http.StatusOK
+	if !isAlive(status) {
+		code = http.StatusInternalServerError
+	}
+
+	writeResponseWithCode(w, code, status)
+}
+
+// syncStatusFunc reports the syncer readiness. It returns http code 200 if the
+// persisted events are caught up with the chain otherwise http code 503 is
+// returned indicating that the served data may be stale.
+func (s *ServerConfig) syncStatusFunc(w http.ResponseWriter, req *http.Request) {
+	status := s.syncer.Status(req.Context())
+
+	code := http.StatusOK
+	if !status.Ready {
+		code = http.StatusServiceUnavailable
+	}
+
+	writeResponseWithCode(w, code, status)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+)
+
+// TestIsReady tests the syncer liveness and readiness reported for the
+// different sync states.
+func TestIsReady(t *testing.T) {
+	s := &Syncer{confirmations: 10}
+
+	healthy := func() *servertypes.SyncStatusResp {
+		return &servertypes.SyncStatusResp{
+			Running:     true,
+			DBConnected: true,
+			LastPoll:    time.Now(),
+			LagBlocks:   10,
+		}
+	}
+
+	td := []struct {
+		testName string
+		update   func(status *servertypes.SyncStatusResp)
+		expAlive bool
+		expReady bool
+	}{
+		{"caught_up_sync", func(*servertypes.SyncStatusResp) {}, true, true},
+		{"lagging_sync", func(st *servertypes.SyncStatusResp) {
+			st.LagBlocks = int64(s.confirmations) + maxReadyLag + 1
+		}, true, false},
+		{"stalled_sync", func(st *servertypes.SyncStatusResp) {
+			st.LastPoll = time.Now().Add(-3 * pollinginterval)
+		}, true, false},
+		{"db_disconnected", func(st *servertypes.SyncStatusResp) {
+			st.DBConnected = false
+		}, true, false},
+		{"stopped_sync", func(st *servertypes.SyncStatusResp) {
+			st.Running = false
+		}, false, false},
+		{"failed_sync", func(st *servertypes.SyncStatusResp) {
+			st.Running = false
+			st.SyncError = "syncing between block 1 and 100 failed"
+		}, false, false},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			status := healthy()
+			v.update(status)
+
+			if alive := isAlive(status); alive != v.expAlive {
+				t.Fatalf("expected liveness %v but found %v", v.expAlive, alive)
+			}
+
+			if ready := s.isReady(status); ready != v.expReady {
+				t.Fatalf("expected readiness %v but found %v", v.expReady, ready)
+			}
+		})
+	}
+}
+
+// TestOpsRoutes tests that the health, sync status and metrics routes are only
+// served by the plain ops listener and not by the mTLS one.
+func TestOpsRoutes(t *testing.T) {
+	s := &ServerConfig{metrics: true}
+
+	td := []struct {
+		testName string
+		mux      *http.ServeMux
+		path     string
+		served   bool
+	}{
+		{"Test-for-healthz-on-mtls", s.routes(), "/healthz", false},
+		{"Test-for-syncstatus-on-mtls", s.routes(), "/syncstatus", false},
+		{"Test-for-metrics-on-mtls", s.routes(), "/metrics", false},
+		{"Test-for-backend-on-mtls", s.routes(), "/backend", true},
+		{"Test-for-healthz-on-ops", s.opsRoutes(), "/healthz", true},
+		{"Test-for-syncstatus-on-ops", s.opsRoutes(), "/syncstatus", true},
+		{"Test-for-metrics-on-ops", s.opsRoutes(), "/metrics", true},
+		{"Test-for-backend-on-ops", s.opsRoutes(), "/backend", false},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			_, pattern := v.mux.Handler(httptest.NewRequest(http.MethodGet, v.path, nil))
+			if served := pattern == v.path; served != v.served {
+				t.Fatalf("expected route %s served=%v but found pattern %q",
+					v.path, v.served, pattern)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait defines the time allowed to write a message to the client.
+	writeWait = 10 * time.Second
+
+	// pongWait defines the time allowed to read the next pong message from
+	// the client.
+	pongWait = 60 * time.Second
+
+	// pingPeriod defines the intervals at which pings are sent to the client.
+	// It must be less than pongWait.
+	pingPeriod = (pongWait * 9) / 10
+
+	// maxSubscriptions defines the maximum number of bonds a single websocket
+	// connection can subscribe to.
+	maxSubscriptions = 50
+
+	// sendBufferSize defines the number of messages that can be queued for a
+	// slow client before new bond events are dropped.
+	sendBufferSize = 32
+)
+
+// upgrader upgrades the http connection to the websocket protocol.
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// subscriber defines a websocket client connection and the bonds whose events
+// it is interested in.
+type subscriber struct {
+	conn *websocket.Conn
+	// send queues the messages to be written to the client.
+	send chan interface{}
+	// closed is closed once the writer exits and nothing else is written to
+	// the client.
+	closed chan struct{}
+
+	mtx    sync.RWMutex
+	sender common.Address
+	bonds  map[common.Address]struct{}
+}
+
+// isSubscribed confirms that the subscriber is interested in events from the
+// provided bond address. It also returns the sender who made the subscription.
+func (sub *subscriber) isSubscribed(bondAddress common.Address) (common.Address, bool) {
+	sub.mtx.RLock()
+	defer sub.mtx.RUnlock()
+
+	_, ok := sub.bonds[bondAddress]
+	return sub.sender, ok
+}
+
+// subscriptions holds all the websocket clients currently connected.
+type subscriptions struct {
+	mtx         sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+// newSubscriptions returns an empty subscriptions instance.
+func newSubscriptions() *subscriptions {
+	return &subscriptions{
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+func (s *subscriptions) add(sub *subscriber) {
+	s.mtx.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mtx.Unlock()
+}
+
+func (s *subscriptions) remove(sub *subscriber) {
+	s.mtx.Lock()
+	delete(s.subscribers, sub)
+	s.mtx.Unlock()
+}
+
+// list returns a snapshot of the subscribers currently connected.
+func (s *subscriptions) list() []*subscriber {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	subs := make([]*subscriber, 0, len(s.subscribers))
+	for sub := range s.subscribers {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// subscribeFunc upgrades the connection to a websocket one, where clients can
+// subscribe to the events of a specific bond address. Events are only pushed
+// to the client once they are persisted and if the subscribing sender can
+// view the bond, i.e. the bond is still in the negotiation stage or the sender
+// is a party to the bond.
+func (s *ServerConfig) subscribeFunc(w http.ResponseWriter, req *http.Request) {
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.Errorf("upgrading to a websocket connection failed: %v", err)
+		return
+	}
+
+	sub := &subscriber{
+		conn:   conn,
+		send:   make(chan interface{}, sendBufferSize),
+		closed: make(chan struct{}),
+		bonds:  make(map[common.Address]struct{}),
+	}
+
+	s.subscriptions.add(sub)
+
+	// done is closed once the reader exits so that the writer can exit too.
+	done := make(chan struct{})
+	go s.writeSubscriber(sub, done)
+
+	s.readSubscriber(sub)
+
+	close(done)
+	s.subscriptions.remove(sub)
+}
+
+// readSubscriber reads the subscription requests sent by the client till the
+// connection is closed.
+func (s *ServerConfig) readSubscriber(sub *subscriber) {
+	sub.conn.SetReadLimit(4096)
+	_ = sub.conn.SetReadDeadline(time.Now().Add(pongWait))
+	sub.conn.SetPongHandler(func(string) error {
+		return sub.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg servertypes.RPCMessage
+		if err := sub.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway,
+				websocket.CloseNormalClosure) {
+				log.Errorf("reading the websocket message failed: %v", err)
+			}
+			return
+		}
+
+		s.executeSubscriptionMsg(sub, &msg)
+
+		select {
+		case sub.send <- &msg:
+		case <-sub.closed:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// writeSubscriber writes all the queued messages to the client connection
+// while keeping the connection alive using pings.
+func (s *ServerConfig) writeSubscriber(sub *subscriber, done <-chan struct{}) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		sub.conn.Close()
+		close(sub.closed)
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-s.ctx.Done():
+			_ = sub.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+				time.Now().Add(writeWait))
+			return
+
+		case msg := <-sub.send:
+			_ = sub.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := sub.conn.WriteJSON(msg); err != nil {
+				log.Errorf("writing the websocket message failed: %v", err)
+				return
+			}
+
+		case <-ticker.C:
+			_ = sub.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := sub.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// executeSubscriptionMsg validates and authorises the subscription request
+// before updating the bonds the subscriber is interested in. The result or the
+// error returned is packed into the message.
+func (s *ServerConfig) executeSubscriptionMsg(sub *subscriber, msg *servertypes.RPCMessage) {
+	// The method is captured before it is cleared by packing the message.
+	defer observeRPC(msg.Method, msg, time.Now())
+
+	methodType := validateRequestMsg(msg, false)
+	if msg.Error != nil {
+		return
+	}
+
+	if methodType != utils.SubscriptionType {
+		err := fmt.Errorf("unsupported method %s found for this route", msg.Method)
+		msg.PackServerError(utils.ErrUnknownMethod, err)
+		return
+	}
+
+	// Only senders with an active session can subscribe.
+	if s.loadSessionKey(msg); msg.Error != nil {
+		return
+	}
+
+	sender := msg.Sender.Address
+	bondAddress := msg.Params[0].(common.Address)
+
+	sub.mtx.Lock()
+	defer sub.mtx.Unlock()
+
+	// A websocket connection can only be used by one sender.
+	if sub.sender != ZeroAddress && sub.sender != sender {
+		err := fmt.Errorf("connection already in use by sender %s", sub.sender)
+		msg.PackServerError(utils.ErrInvalidReq, err)
+		return
+	}
+
+	switch msg.Method {
+	case utils.SubscribeBond:
+		if len(sub.bonds) >= maxSubscriptions {
+			err := fmt.Errorf("expected a max of %d subscriptions per connection",
+				maxSubscriptions)
+			msg.PackServerError(utils.ErrInvalidReq, err)
+			return
+		}
+		sub.sender = sender
+		sub.bonds[bondAddress] = struct{}{}
+
+	case utils.UnsubscribeBond:
+		delete(sub.bonds, bondAddress)
+	}
+
+	msg.PackServerResult(struct {
+		BondAddress common.Address `json:"bond_address"`
+		Subscribed  bool           `json:"subscribed"`
+	}{
+		BondAddress: bondAddress,
+		Subscribed:  msg.Method == utils.SubscribeBond,
+	})
+}
+
+// notifySubscribers pushes the persisted bond event to all the clients
+// subscribed to it and are allowed to view the bond.
+func (s *ServerConfig) notifySubscribers(event *servertypes.EventResp) {
+	if s.subscriptions == nil {
+		return
+	}
+
+	notification := &servertypes.RPCMessage{
+		Version: utils.JSONRPCVersion,
+		Method:  utils.BondEvent,
+		Params:  []interface{}{event},
+	}
+
+	// The bond parties are loaded once for all the subscribers of the event.
+	var parties *bondParties
+	var loaded bool
+
+	for _, sub := range s.subscriptions.list() {
+		sender, ok := sub.isSubscribed(event.BondAddress)
+		if !ok {
+			continue
+		}
+
+		if !loaded {
+			var err error
+			if parties, err = s.loadBondParties(event.BondAddress); err != nil {
+				log.Errorf("loading bond %s parties failed: %v", event.BondAddress, err)
+				return
+			}
+			loaded = true
+		}
+
+		if !parties.isVisibleTo(sender) {
+			continue
+		}
+
+		select {
+		case sub.send <- notification:
+		default:
+			log.Warnf("Dropping %s event for a slow subscriber=%s", event.Event, sender)
+		}
+	}
+}
+
+// bondParties holds the bond fields deciding who can view the bond.
+type bondParties struct {
+	issuer, holder common.Address
+	lastStatus     uint8
+}
+
+// Read is the reader interface implementation for type bondParties.
+func (p *bondParties) Read(fn func(fields ...any) error) (interface{}, error) {
+	var resp bondParties
+	var issuer, holder string
+
+	err := fn(&issuer, &holder, &resp.lastStatus)
+
+	resp.issuer = common.HexToAddress(issuer)
+	resp.holder = common.HexToAddress(holder)
+	return &resp, err
+}
+
+// isVisibleTo confirms that the sender can view the bond i.e. the bond is
+// still in the negotiation stage or the sender is a party to the bond. A nil
+// bondParties, of an unknown bond, isn't visible.
+func (p *bondParties) isVisibleTo(sender common.Address) bool {
+	return p != nil && (p.lastStatus == 0 || p.issuer == sender || p.holder == sender)
+}
+
+// loadBondParties returns the parties of the provided bond. nil is returned if
+// the bond is unknown.
+func (s *ServerConfig) loadBondParties(bondAddress common.Address) (*bondParties, error) {
+	data, err := s.db.QueryLocalData(utils.GetBondParties, new(bondParties), "",
+		bondAddress.String())
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	return data[0].(*bondParties), nil
+}
+
+// isBondVisible confirms that the sender can view the bond. The same rules
+// applied when fetching the bond by address are used.
+func (s *ServerConfig) isBondVisible(sender, bondAddress common.Address) bool {
+	parties, err := s.loadBondParties(bondAddress)
+	if err != nil {
+		log.Errorf("checking bond %s visibility failed: %v", bondAddress, err)
+		return false
+	}
+	return parties.isVisibleTo(sender)
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TestExecuteSubscriptionMsg tests the functionality implemented in the
+// executeSubscriptionMsg method.
+func TestExecuteSubscriptionMsg(t *testing.T) {
+	sub := &subscriber{
+		bonds: make(map[common.Address]struct{}),
+	}
+
+	// otherSender has an active session but the connection is already in use.
+	otherSender := common.HexToAddress("0x3396FD816Dd81100477c8ea3853039822f36B7cd")
+	serverConf.sessions.Store(otherSender, servertypes.ServerKeyResp{
+		Pubkey:       pubkey3,
+		Expiry:       uint64(time.Now().UTC().Add(10 * time.Minute).Unix()),
+		SharedKey:    []byte(sharedKey3),
+		SessionToken: sampleSessionToken,
+	})
+	defer serverConf.sessions.Delete(otherSender)
+
+	testdata := []struct {
+		testName   string
+		msg        servertypes.RPCMessage
+		val        output
+		subscribed bool
+	}{
+		{
+			testName: "Test-for-access-to-non-subscription-method",
+			msg: servertypes.RPCMessage{
+				Version: "2.0",
+				Method:  utils.GetSessionChallenge,
+				Sender: &servertypes.SenderInfo{
+					Address:      sampleHexAddress2,
+					SessionToken: sampleSessionToken,
+				},
+			},
+			val: output{
+				errCode:  1008,
+				shortErr: utils.ErrUnknownMethod,
+				longErr:  "unsupported method getSessionChallenge found for this route",
+			},
+		},
+		{
+			testName: "Test-for-missing-server-keys",
+			msg: servertypes.RPCMessage{
+				Version: "2.0",
+				Method:  utils.SubscribeBond,
+				Sender:  &servertypes.SenderInfo{Address: sampleHexAddress3},
+				Params:  []interface{}{sampleHexAddress.String()},
+			},
+			val: output{
+				errCode:  1011,
+				shortErr: utils.ErrMissingServerKey,
+				longErr:  "no server keys found associated with the sender",
+			},
+		},
+		{
+			testName: "Test-for-successful-subscription",
+			msg: servertypes.RPCMessage{
+				Version: "2.0",
+				Method:  utils.SubscribeBond,
+				Sender: &servertypes.SenderInfo{
+					Address:      sampleHexAddress2,
+					SessionToken: sampleSessionToken,
+				},
+				Params: []interface{}{sampleHexAddress.String()},
+			},
+			subscribed: true,
+		},
+		{
+			testName: "Test-for-connection-used-by-another-sender",
+			msg: servertypes.RPCMessage{
+				Version: "2.0",
+				Method:  utils.SubscribeBond,
+				Sender: &servertypes.SenderInfo{
+					Address:      otherSender,
+					SessionToken: sampleSessionToken,
+				},
+				Params: []interface{}{sampleHexAddress.String()},
+			},
+			val: output{
+				errCode:  1001,
+				shortErr: utils.ErrInvalidReq,
+				longErr:  "connection already in use by sender " + sampleHexAddress2.String(),
+			},
+			subscribed: true,
+		},
+		{
+			testName: "Test-for-successful-unsubscription",
+			msg: servertypes.RPCMessage{
+				Version: "2.0",
+				Method:  utils.UnsubscribeBond,
+				Sender: &servertypes.SenderInfo{
+					Address:      sampleHexAddress2,
+					SessionToken: sampleSessionToken,
+				},
+				Params: []interface{}{sampleHexAddress.String()},
+			},
+			subscribed: false,
+		},
+	}
+
+	for _, v := range testdata {
+		t.Run(v.testName, func(t *testing.T) {
+			msg := v.msg
+			serverConf.executeSubscriptionMsg(sub, &msg)
+
+			if _, ok := sub.isSubscribed(sampleHexAddress); ok != v.subscribed {
+				t.Fatalf("expected the bond subscription to be %v but found %v",
+					v.subscribed, ok)
+			}
+
+			if msg.Error == nil {
+				if v.val.shortErr != nil {
+					t.Fatalf("expected an error %q but found none", v.val.shortErr)
+				}
+
+				if len(msg.Result) == 0 {
+					t.Fatal("expected the Result data not to be empty")
+				}
+				return
+			}
+
+			if msg.Error.Code != v.val.errCode {
+				t.Fatalf("expected returned error code to be %d but found %d",
+					v.val.errCode, msg.Error.Code)
+			}
+
+			errStr, _ := msg.Error.Data.(string)
+			if errStr != v.val.longErr {
+				t.Fatalf("expected returned long error to be %q but found %q",
+					v.val.longErr, errStr)
+			}
+		})
+	}
+}
+
+// TestBondPartiesVisibility tests the senders allowed to view the bond events.
+func TestBondPartiesVisibility(t *testing.T) {
+	parties := &bondParties{issuer: sampleHexAddress1, holder: sampleHexAddress2, lastStatus: 2}
+
+	td := []struct {
+		testName string
+		parties  *bondParties
+		sender   common.Address
+		visible  bool
+	}{
+		{"unknown_bond", nil, sampleHexAddress1, false},
+		{"bond_issuer", parties, sampleHexAddress1, true},
+		{"bond_holder", parties, sampleHexAddress2, true},
+		{"not_a_bond_party", parties, sampleHexAddress3, false},
+		{"negotiating_bond", &bondParties{issuer: sampleHexAddress1}, sampleHexAddress3, true},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			if visible := v.parties.isVisibleTo(v.sender); visible != v.visible {
+				t.Fatalf("expected the bond visibility to be %v but found %v", v.visible, visible)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/dmigwi/dhamana-protocol/client/contracts"
 	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/storage"
 	"github.com/dmigwi/dhamana-protocol/client/utils"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -19,7 +20,8 @@ import (
 )
 
 const (
-	// blocksFilterInterval defines the number of blocks that can be filtered at ago.
+	// blocksFilterInterval defines the number of blocks filtered at ago by the
+	// first backfill windows before the window size is adapted.
 	blocksFilterInterval int64 = 100
 
 	// loggingInterval describes the intervals at which historical events
@@ -28,32 +30,17 @@ const (
 
 	// pollinginterval describes the intervals at which future events are polled.
 	pollinginterval = 2 * time.Minute
+
+	// maxReorgDepth defines the number of the latest processed block hashes
+	// compared with the chain when checking for a chain reorganisation.
+	maxReorgDepth = 128
+
+	// blockHashesRetention defines the number of blocks behind the chain tip
+	// whose processed block hashes are kept.
+	blockHashesRetention int64 = 1000
 )
 
 var (
-	// bondBodyTermsChan defines the BondBodyTerms event listener channel.
-	bondBodyTermsChan = make(chan *contracts.ChatBondBodyTerms)
-	// bondMotivationChan defines the BondMotivation event listener channel.
-	bondMotivationChan = make(chan *contracts.ChatBondMotivation)
-	// holderUpdateChan defines the HolderUpdate event listener channel.
-	holderUpdateChan = make(chan *contracts.ChatHolderUpdate)
-	// newBondCreatedChan defines the NewBondCreated event listener channel.
-	newBondCreatedChan = make(chan *contracts.ChatNewBondCreated)
-	// newChatMessageChan defines the NewChatMessage event listener channel.
-	newChatMessageChan = make(chan *contracts.ChatNewChatMessage)
-	// statusChangeChan defines the StatusChange event listener channel.
-	statusChangeChan = make(chan *contracts.ChatStatusChange)
-	// statusSignedChan defines the StatusSigned event listener channel.
-	statusSignedChan = make(chan *contracts.ChatStatusSigned)
-
-	// quit is used to indicate that a shutdown request was recieved and the
-	// loop or goroutine should exit too.
-	quit = make(chan struct{})
-
-	// quitWithErr allows the error that initiates listeners and loops shutdown
-	// to be sent via it.
-	quitWithErr = make(chan error)
-
 	// eventNames defines a list of all event names currently supported.
 	// If a new event is introduced, it must be added here otherwise the system
 	// will exit with an error when parsing the logs.
@@ -65,25 +52,46 @@ var (
 
 // eventData contains data packed from each event recieved.
 type eventData struct {
-	method  utils.Method
-	blockNo uint64
-	params  []interface{}
+	method utils.Method
+	params []interface{}
+
+	// event if set, is the name of the event counted once the event data is
+	// persisted. Events packed into several writes only set it once.
+	event string
+
+	// notification if set, is pushed to the subscribed clients once the event
+	// data is persisted.
+	notification *servertypes.EventResp
 }
 
-// SyncData polls for the historical events data in a blocking operation before
-// shifting to poll for future blocks asynchronously.
-func (s *ServerConfig) SyncData() error {
+// syncData polls for the historical events data in a blocking operation before
+// shifting to poll for future blocks asynchronously. The sync run tracked by
+// the syncer's wait group is marked as done once syncData returns without
+// polling or once the polling goroutine exits.
+func (s *Syncer) syncData() error {
+	var polling bool
+	defer func() {
+		if !polling {
+			s.wg.Done()
+		}
+	}()
+
+	// A chain reorganisation may have happened while the syncer was offline.
+	if _, err := s.handleReorg(); err != nil {
+		return err
+	}
+
 	// fetch the block at which the contract was deployed
 	deployedBlock := int64(getDeployedBlock(s.network))
 
 	// fetch the last synced block from the database.
 	lastSyncedBlock, _ := s.db.QueryLocalData(utils.GetLastSyncedBlock,
-		new(servertypes.LastSyncedBlockResp), "")
+		new(servertypes.LastSyncedBlockResp), "", s.contractAddr.Hex(), s.network.String())
 
 	var syncedBlock int64
 	if len(lastSyncedBlock) > 0 {
 		// To start on the next block yet to be synced add 1.
-		syncedBlock = lastSyncedBlock[0].(int64) + 1
+		syncedBlock = int64(*lastSyncedBlock[0].(*servertypes.LastSyncedBlockResp)) + 1
 	}
 
 	// compare the two blocks and pick the latest one.
@@ -97,339 +105,488 @@ func (s *ServerConfig) SyncData() error {
 		return err
 	}
 
-	targetBlock, err := s.bestBlock()
+	targetBlock, err := s.confirmedBlock()
 	if err != nil {
 		// Exit the sync, if fetching the best block failed.
 		return err
 	}
 
-	endBlock := syncedBlock + blocksFilterInterval
 	filterOpts := ethereum.FilterQuery{
-		FromBlock: big.NewInt(syncedBlock),
-		ToBlock:   big.NewInt(endBlock),
 		Addresses: []common.Address{getContractAddress(s.network)},
 		Topics:    topics,
 	}
 
-	// filterLogsFunc requests the filtered logs using the set filter query options.
-	// It returns a count of the processed filtered logs events.
-	filterLogsFunc := func() (int, error) {
-		// extract the current syncing window here.
-		fromBlock, toBlock := "**", "**"
-		if filterOpts.FromBlock != nil {
-			fromBlock = filterOpts.FromBlock.String()
-		}
+	// ---- Process all the historical events data in a blocking operation ----
+	log.Info("Processing all the historical events data in a blocking operation...")
 
-		if filterOpts.ToBlock != nil {
-			toBlock = filterOpts.ToBlock.String()
-		}
+	log.Infof("Starting data sync from block=%d To target block=%d using workers=%d",
+		syncedBlock, targetBlock, s.backfillWorkers)
 
-		logs, err := s.backend.FilterLogs(s.ctx, filterOpts)
-		if err != nil {
-			return 0, fmt.Errorf("syncing between block %s and %s failed: %v",
-				fromBlock, toBlock, err)
-		}
+	// Block till the blocks are synced to the target block.
+	totalEvents, err := s.backfill(filterOpts, syncedBlock, targetBlock)
+	if err != nil {
+		return err
+	}
 
-		return len(logs), s.parseEvents(logs)
+	select {
+	case <-s.quit:
+		// shutdown request was received during the backfill, so exit.
+		s.finish(nil)
+		return nil
+	case <-s.ctx.Done():
+		// If context is shut during the backfill, exit
+		s.finish(nil)
+		return nil
+	default:
 	}
 
-	// Process all the recieved events asynchronously by piping them into their
-	// respectived channel types for further processing.
-	go s.processEvents()
+	log.Infof("Total processed events=%d from start block=%d to target block=%d",
+		totalEvents, syncedBlock, targetBlock)
 
-	// ---- Process all the historical events data in a blocking operation ----
-	log.Info("Processing all the historical events data in a blocking operation...")
+	s.markPolled()
 
-	// Create a logging ticker timer.
-	ticker := time.NewTicker(loggingInterval)
+	// The filter block range is inclusive, start on the next block.
+	filterOpts.FromBlock = big.NewInt(targetBlock + 1)
+	if syncedBlock > targetBlock {
+		filterOpts.FromBlock = big.NewInt(syncedBlock)
+	}
 
-	var eventCounter, totalEvents int
+	// filterLogsFunc requests the filtered logs using the set filter query options.
+	// It returns a count of the processed filtered logs events.
+	filterLogsFunc := func() (int, error) {
+		// extract the current syncing window here.
+		fromBlock, toBlock := filterOpts.FromBlock.String(), filterOpts.ToBlock.String()
 
-	log.Infof("Starting data sync from block=%d To target block=%d",
-		syncedBlock, targetBlock)
-	// Block till the blocks are synced to the target block.
-	for endBlock <= targetBlock {
-		select {
-		case <-quit:
-			// shutdown request was received, so exit.
-			return nil
-		case <-s.ctx.Done():
-			// If context is shut during the looping, exit
-			return nil
-		case <-ticker.C:
-			log.Infof("Syncing data from block=%d To target block=%d, events processed=%d",
-				filterOpts.FromBlock.Int64(), targetBlock, eventCounter)
-
-			totalEvents += eventCounter
-			eventCounter = 0 // reset the events counter.
-		default:
-			// no shutdown or ticker event received.
+		logs, err := s.backend.FilterLogs(s.ctx, filterOpts)
+		if err != nil {
+			return 0, fmt.Errorf("syncing between block %s and %s failed: %v",
+				fromBlock, toBlock, err)
 		}
 
-		counter, err := filterLogsFunc()
+		events, err := s.parseEvents(logs)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
-		filterOpts.FromBlock = big.NewInt(endBlock)
-		endBlock += blocksFilterInterval
-		filterOpts.ToBlock = big.NewInt(endBlock)
-
-		eventCounter += counter
+		return len(logs), s.persistEvents(events, logs, filterOpts.ToBlock.Int64())
 	}
 
-	log.Infof("Total processed events=%d from start block=%d to target block=%d",
-		totalEvents, syncedBlock, targetBlock)
-
 	// ---Process asynchronously all the future events data, till shutdown ----
 	log.Info("Processing asynchronously all the future events data, till shutdown...")
 
-	// Reset the ticker timer to be used in polling the future events data.
-	ticker.Reset(pollinginterval)
-
-	syncedBlock = filterOpts.ToBlock.Int64()
-	filterOpts.FromBlock.SetInt64(syncedBlock)
-
-	// At the end of the polling interval a variable number of blocks could have
-	// been added. ToBlock is set to nil so that the returned logs can have the
-	// latest best block logs.
-	filterOpts.ToBlock = nil
+	// Create the ticker timer to be used in polling the future events data.
+	ticker := time.NewTicker(pollinginterval)
 
+	polling = true
 	go func() {
+		defer s.wg.Done()
+		defer ticker.Stop()
+
 		for {
 			select {
-			case <-quit:
+			case <-s.quit:
 				// shutdown request recieved
 				return
 			case <-s.ctx.Done():
 				// context is already cancelled.
+				s.finish(nil)
 				return
 			case <-ticker.C:
-				counter, err := filterLogsFunc()
+				resumeBlock, err := s.handleReorg()
 				if err != nil {
-					quitWithErr <- err
+					s.finish(err)
 					return
 				}
 
-				currentBestBlock, err := s.bestBlock()
-				if err != nil {
-					quitWithErr <- err
-					return
-				}
-
-				filterOpts.FromBlock.SetInt64(currentBestBlock)
-
-				log.Infof("Processed events=%d upto the current best block=%d",
-					counter, currentBestBlock)
-			}
-		}
-	}()
-
-	return nil
-}
-
-func (s *ServerConfig) processEvents() {
-	// eventsData is a buffered chan that allows each event to submit its data
-	// atleast twice before its considered full.
-	eventsData := make(chan *eventData, len(eventNames)*2)
-
-	go func() {
-		for {
-			select {
-			case err := <-quitWithErr:
-				log.Info("Sync shutdown request recieved")
-
-				// trigger all other loops and listeners to close too.
-				close(quit)
-
-			loop:
-				for {
-					select {
-					case <-eventsData:
-						break loop
-					default:
-					}
+				// Resume syncing after the rollback block if a reorg rolled
+				// back some of the data.
+				if resumeBlock >= 0 && resumeBlock < filterOpts.FromBlock.Int64() {
+					filterOpts.FromBlock = big.NewInt(resumeBlock)
 				}
 
+				currentBestBlock, err := s.confirmedBlock()
 				if err != nil {
-					log.Errorf("events data syncing ended with an error: %v", err)
-				}
-
-			case <-s.ctx.Done():
-				close(quitWithErr)
-
-			case data := <-bondBodyTermsChan:
-				eventsData <- &eventData{
-					method:  utils.UpdateBondBodyTerms,
-					blockNo: data.Raw.BlockNumber,
-					params: []interface{}{
-						data.Principal, data.CouponRate, data.CouponDate,
-						time.Unix(int64(data.MaturityDate), 0).UTC(), data.Currency,
-						time.Now().UTC(), data.Raw.BlockNumber, data.BondAddress.Hex(),
-					},
-				}
-
-			case data := <-bondMotivationChan:
-				eventsData <- &eventData{
-					method:  utils.UpdateBondMotivation,
-					blockNo: data.Raw.BlockNumber,
-					params: []interface{}{
-						data.Message, time.Now().UTC(), data.Raw.BlockNumber,
-						data.BondAddress.Hex(),
-					},
-				}
-
-			case data := <-holderUpdateChan:
-				eventsData <- &eventData{
-					method:  utils.UpdateHolder,
-					blockNo: data.Raw.BlockNumber,
-					params: []interface{}{
-						data.Holder.Hex(), time.Now().UTC(), data.Raw.BlockNumber,
-						data.BondAddress.Hex(),
-					},
+					s.finish(err)
+					return
 				}
 
-			case data := <-newBondCreatedChan:
-				eventsData <- &eventData{
-					method:  utils.InsertNewBondCreated,
-					blockNo: data.Raw.BlockNumber,
-					params: []interface{}{
-						data.BondAddress.Hex(), data.Raw.BlockNumber,
-						data.Sender.Hex(), data.Raw.BlockNumber,
-					},
+				// Refresh the events yet to be confirmed.
+				if err = s.syncPendingEvents(filterOpts, currentBestBlock); err != nil {
+					s.finish(err)
+					return
 				}
 
-			case data := <-newChatMessageChan:
-				eventsData <- &eventData{
-					method:  utils.InsertNewChatMessage,
-					blockNo: data.Raw.BlockNumber,
-					params: []interface{}{
-						data.Sender.Hex(), data.BondAddress.Hex(), data.Message,
-						data.Raw.BlockNumber,
-					},
+				// No new blocks have been confirmed since the last poll.
+				if currentBestBlock < filterOpts.FromBlock.Int64() {
+					s.markPolled()
+					continue
 				}
 
-			case data := <-statusChangeChan:
-				eventsData <- &eventData{
-					method:  utils.InsertStatusChange,
-					blockNo: data.Raw.BlockNumber,
-					params: []interface{}{
-						data.Sender.Hex(), data.BondAddress.Hex(), data.Status,
-						data.Raw.BlockNumber,
-					},
+				var counter int
+				fromBlock := filterOpts.FromBlock.Int64()
+				if currentBestBlock-fromBlock+1 > blocksFilterInterval {
+					// A deep rollback or a long stall left too many blocks
+					// for a single filter query, backfill them in windows.
+					counter, err = s.backfill(filterOpts, fromBlock, currentBestBlock)
+				} else {
+					filterOpts.ToBlock = big.NewInt(currentBestBlock)
+					counter, err = filterLogsFunc()
 				}
-
-				eventsData <- &eventData{
-					method:  utils.UpdateLastStatus,
-					blockNo: data.Raw.BlockNumber,
-					params: []interface{}{
-						data.Status, time.Now().UTC(), data.Raw.BlockNumber,
-						data.BondAddress.Hex(),
-					},
+				if err != nil {
+					s.finish(err)
+					return
 				}
 
-			case data := <-statusSignedChan:
-				eventsData <- &eventData{
-					method:  utils.InsertStatusSigned,
-					blockNo: data.Raw.BlockNumber,
-					params: []interface{}{
-						data.Sender.Hex(), data.BondAddress.Hex(), data.Status,
-						data.Raw.BlockNumber,
-					},
+				select {
+				case <-s.quit:
+					// shutdown request was received during the backfill.
+					return
+				case <-s.ctx.Done():
+					s.finish(nil)
+					return
+				default:
 				}
 
-			default:
-			eventsDataLoop:
-				for info := range eventsData {
-					log.Debugf("Local method type=%s is being processed", info.method)
-					if err := s.db.SetLocalData(info.method, info.params...); err != nil {
+				filterOpts.FromBlock = big.NewInt(currentBestBlock + 1)
+				s.markPolled()
 
-						// clean dirty writes made before on the current block.
-						// This is a safeguard built in here but execution should never
-						// here under normal circumstances.
-						s.db.CleanUpLocalData(info.blockNo)
-
-						quitWithErr <- err
-
-						// exit the for loop so that the shutdown error can be processed.
-						break eventsDataLoop
-					}
-				}
+				log.Infof("Processed events=%d upto the current confirmed block=%d",
+					counter, currentBestBlock)
 			}
 		}
 	}()
-}
 
-// parseEvents attempts to match the returned logs with one of the event parsers.
-// If none of the parsers was a postive match then an error is returned to indicate
-// presence of an unsupported event.
-func (s *ServerConfig) parseEvents(logs []types.Log) error {
-	var eventsCount int
-	for _, eventLog := range logs {
-		select {
-		case <-quit:
-			break // Breaks the for-loop
-		case <-s.ctx.Done():
-			break // Breaks the for-loop
-		default:
-			// No shutdown request received.
-		}
+	return nil
+}
 
-		eventsCount++
+// parseEvents attempts to match the returned logs with one of the event parsers
+// and packs the data to be persisted for each of them. If none of the parsers
+// was a postive match then an error is returned to indicate presence of an
+// unsupported event. The inserted events records are tagged with the contract
+// and network they were emitted on.
+func (s *Syncer) parseEvents(logs []types.Log) ([]*eventData, error) {
+	contract, network := s.contractAddr.Hex(), s.network.String()
 
+	events := make([]*eventData, 0, len(logs))
+	for _, eventLog := range logs {
 		newBondCreated, _ := s.bondChat.ChatFilterer.ParseNewBondCreated(eventLog)
 		if newBondCreated != nil {
-			newBondCreatedChan <- newBondCreated
+			events = append(events, &eventData{
+				method: utils.InsertNewBondCreated,
+				event:  "NewBondCreated",
+				params: []interface{}{
+					newBondCreated.BondAddress.Hex(), newBondCreated.Sender.Hex(),
+					newBondCreated.Raw.BlockNumber, newBondCreated.Raw.BlockNumber,
+					newBondCreated.Raw.TxHash.Hex(), contract, network,
+				},
+			})
 			continue
 		}
 
 		newChatMessage, _ := s.bondChat.ChatFilterer.ParseNewChatMessage(eventLog)
 		if newChatMessage != nil {
-			newChatMessageChan <- newChatMessage
+			events = append(events, &eventData{
+				method: utils.InsertNewChatMessage,
+				event:  "NewChatMessage",
+				params: []interface{}{
+					newChatMessage.Sender.Hex(), newChatMessage.BondAddress.Hex(),
+					newChatMessage.Message, newChatMessage.Raw.BlockNumber,
+					newChatMessage.Raw.TxHash.Hex(), newChatMessage.Raw.Index, contract, network,
+				},
+				notification: newChatMessageResp(newChatMessage),
+			})
 			continue
 		}
 
 		statusChange, _ := s.bondChat.ChatFilterer.ParseStatusChange(eventLog)
 		if statusChange != nil {
-			statusChangeChan <- statusChange
+			events = append(events, &eventData{
+				method: utils.InsertStatusChange,
+				event:  "StatusChange",
+				params: []interface{}{
+					statusChange.Sender.Hex(), statusChange.BondAddress.Hex(),
+					statusChange.Status, statusChange.Raw.BlockNumber,
+					statusChange.Raw.TxHash.Hex(), statusChange.Raw.Index, contract, network,
+				},
+			}, &eventData{
+				method: utils.UpdateLastStatus,
+				params: []interface{}{
+					statusChange.Status, time.Now().UTC(), statusChange.Raw.BlockNumber,
+					statusChange.BondAddress.Hex(),
+				},
+				// Notify once the bond's last status is updated too.
+				notification: statusChangeResp(statusChange),
+			})
 			continue
 		}
 
 		statusSigned, _ := s.bondChat.ChatFilterer.ParseStatusSigned(eventLog)
 		if statusSigned != nil {
-			statusSignedChan <- statusSigned
+			events = append(events, &eventData{
+				method: utils.InsertStatusSigned,
+				event:  "StatusSigned",
+				params: []interface{}{
+					statusSigned.Sender.Hex(), statusSigned.BondAddress.Hex(),
+					statusSigned.Status, statusSigned.Raw.BlockNumber,
+					statusSigned.Raw.TxHash.Hex(), statusSigned.Raw.Index, contract, network,
+				},
+				notification: statusSignedResp(statusSigned),
+			})
 			continue
 		}
 
 		bondBodyTerms, _ := s.bondChat.ChatFilterer.ParseBondBodyTerms(eventLog)
 		if bondBodyTerms != nil {
-			bondBodyTermsChan <- bondBodyTerms
+			events = append(events, &eventData{
+				method: utils.UpdateBondBodyTerms,
+				event:  "BondBodyTerms",
+				params: []interface{}{
+					bondBodyTerms.Principal, bondBodyTerms.CouponRate, bondBodyTerms.CouponDate,
+					time.Unix(int64(bondBodyTerms.MaturityDate), 0).UTC(), bondBodyTerms.Currency,
+					time.Now().UTC(), bondBodyTerms.Raw.BlockNumber, bondBodyTerms.BondAddress.Hex(),
+				},
+				notification: bondBodyTermsResp(bondBodyTerms),
+			})
 			continue
 		}
 
 		bondMotivation, _ := s.bondChat.ChatFilterer.ParseBondMotivation(eventLog)
 		if bondMotivation != nil {
-			bondMotivationChan <- bondMotivation
+			events = append(events, &eventData{
+				method: utils.UpdateBondMotivation,
+				event:  "BondMotivation",
+				params: []interface{}{
+					bondMotivation.Message, time.Now().UTC(), bondMotivation.Raw.BlockNumber,
+					bondMotivation.BondAddress.Hex(),
+				},
+				notification: bondMotivationResp(bondMotivation),
+			})
 			continue
 		}
 
 		holderUpdate, _ := s.bondChat.ChatFilterer.ParseHolderUpdate(eventLog)
 		if holderUpdate != nil {
-			holderUpdateChan <- holderUpdate
+			events = append(events, &eventData{
+				method: utils.UpdateHolder,
+				event:  "HolderUpdate",
+				params: []interface{}{
+					holderUpdate.Holder.Hex(), time.Now().UTC(), holderUpdate.Raw.BlockNumber,
+					holderUpdate.BondAddress.Hex(),
+				},
+				notification: holderUpdateResp(holderUpdate),
+			})
 			continue
 		}
 
 		// If one of the parsers failed to return a positive match then there
 		// must be an unsupported event in the returned logs.
-		return fmt.Errorf("unsupported event at contract address: %v and Block No: %v ",
+		return nil, fmt.Errorf("unsupported event at contract address: %v and Block No: %v ",
 			eventLog.Address, eventLog.BlockNumber)
 	}
+	return events, nil
+}
+
+// persistEvents writes all the events data of the filtered window together
+// with the window's processed block hashes and the sync cursor in a single
+// batch. If any write fails, nothing from the window is persisted. The subscribed clients are
+// notified only after the batch is committed.
+func (s *Syncer) persistEvents(events []*eventData, logs []types.Log, toBlock int64) error {
+	bestBlock, err := s.bestBlock()
+	if err != nil {
+		return err
+	}
+
+	batch, err := s.db.NewBatch()
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op if the batch has already been committed.
+	defer batch.Rollback()
+
+	for _, info := range events {
+		log.Debugf("Local method type=%s is being processed", info.method)
+		if err = batch.SetLocalData(info.method, info.params...); err != nil {
+			return err
+		}
+	}
+
+	minBlock := bestBlock - blockHashesRetention
+	if err = s.recordBlockHashes(batch, logs, toBlock, minBlock); err != nil {
+		return err
+	}
+
+	// Move the sync cursor to the window's last block.
+	err = batch.SetLocalData(utils.UpdateSyncState, s.contractAddr.Hex(),
+		s.network.String(), toBlock)
+	if err != nil {
+		return err
+	}
+
+	if err = batch.Commit(); err != nil {
+		return err
+	}
+
+	network := s.network.String()
+	syncLagBlocks.WithLabelValues(network).Set(float64(bestBlock - toBlock))
+
+	for _, info := range events {
+		if info.event != "" {
+			syncEvents.WithLabelValues(network, info.event).Inc()
+		}
+
+		if info.notification != nil && s.notify != nil {
+			s.notify(info.notification)
+		}
+	}
+
+	if minBlock > 0 {
+		return s.db.PruneBlockHashes(s.contractAddr.Hex(), network, uint64(minBlock))
+	}
+	return nil
+}
+
+// handleReorg compares the stored processed block hashes with the chain. If a
+// chain reorganisation is detected, all the local data written after the
+// common ancestor block is rolled back and the block from which syncing should
+// resume is returned. If no reorganisation was detected -1 is returned.
+func (s *Syncer) handleReorg() (int64, error) {
+	ancestor, err := s.findCommonAncestor()
+	if err != nil || ancestor < 0 {
+		return -1, err
+	}
+
+	log.Warnf("Chain reorganisation detected with the common ancestor block=%d", ancestor)
+
+	rollbackBlock, err := s.db.RollbackLocalData(uint64(ancestor), s.contractAddr.Hex(),
+		s.network.String())
+	if err != nil {
+		return -1, fmt.Errorf("rolling back the reorganised data failed: %v", err)
+	}
+
+	log.Infof("Resyncing the rolled back data from block=%d", rollbackBlock+1)
+
+	return int64(rollbackBlock) + 1, nil
+}
+
+// findCommonAncestor walks back the latest processed block hashes till one
+// matching the chain is found. If the latest processed block hash matches the
+// chain, -1 is returned to indicate that no reorganisation happened.
+func (s *Syncer) findCommonAncestor() (int64, error) {
+	data, err := s.db.QueryLocalData(utils.GetBlockHashes, new(servertypes.BlockHashResp), "",
+		s.contractAddr.Hex(), s.network.String(), maxReorgDepth)
+	if err != nil {
+		return -1, err
+	}
+
+	for i, d := range data {
+		blockHash := d.(*servertypes.BlockHashResp)
+
+		header, err := s.backend.HeaderByNumber(s.ctx, new(big.Int).SetUint64(blockHash.BlockNo))
+		if err != nil {
+			return -1, fmt.Errorf("fetching block %d header failed: %v", blockHash.BlockNo, err)
+		}
+
+		if header.Hash() == blockHash.Hash {
+			if i == 0 {
+				// latest processed block is still part of the chain.
+				return -1, nil
+			}
+			return int64(blockHash.BlockNo), nil
+		}
+	}
+
+	if len(data) == 0 {
+		return -1, nil
+	}
+
+	// None of the stored block hashes matched the chain. Rollback past the
+	// oldest processed block hash known.
+	oldest := data[len(data)-1].(*servertypes.BlockHashResp).BlockNo
+	log.Warnf("Chain reorganisation is deeper than the processed block=%d", oldest)
+
+	return int64(oldest) - 1, nil
+}
+
+// recordBlockHashes stores via the provided batch the hashes of the blocks with
+// processed events and of the processed window's last block. Only blocks from
+// the provided min block, close enough to the chain tip to be affected by a
+// reorganisation, are stored.
+func (s *Syncer) recordBlockHashes(batch *storage.Batch, logs []types.Log,
+	toBlock, minBlock int64,
+) error {
+	if toBlock < minBlock {
+		return nil
+	}
+
+	blocks := make(map[uint64]struct{})
+	for _, eventLog := range logs {
+		if int64(eventLog.BlockNumber) >= minBlock {
+			blocks[eventLog.BlockNumber] = struct{}{}
+		}
+	}
+	blocks[uint64(toBlock)] = struct{}{}
+
+	for blockNo := range blocks {
+		// The header hash is used on both recording and comparison for consistency.
+		header, err := s.backend.HeaderByNumber(s.ctx, new(big.Int).SetUint64(blockNo))
+		if err != nil {
+			return fmt.Errorf("fetching block %d header failed: %v", blockNo, err)
+		}
+
+		err = batch.SetLocalData(utils.InsertBlockHash, s.contractAddr.Hex(),
+			s.network.String(), blockNo, header.Hash().Hex())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// confirmedBlock returns the latest block with the required number of
+// confirmations. Events upto this block can be persisted.
+func (s *Syncer) confirmedBlock() (int64, error) {
+	bestBlock, err := s.bestBlock()
+	if err != nil {
+		return -1, err
+	}
+	return bestBlock - int64(s.confirmations), nil
+}
+
+// syncPendingEvents replaces the pending events view with events found after
+// the provided confirmed block. It is a no-op if the pending view is disabled.
+func (s *Syncer) syncPendingEvents(filterOpts ethereum.FilterQuery, confirmedBlock int64) error {
+	if s.pending == nil {
+		return nil
+	}
+
+	// A nil ToBlock returns logs upto the latest best block.
+	filterOpts.FromBlock = big.NewInt(confirmedBlock + 1)
+	filterOpts.ToBlock = nil
+
+	logs, err := s.backend.FilterLogs(s.ctx, filterOpts)
+	if err != nil {
+		return fmt.Errorf("syncing the pending events from block %d failed: %v",
+			confirmedBlock+1, err)
+	}
+
+	events := make([]*servertypes.EventResp, 0, len(logs))
+	for _, eventLog := range logs {
+		event, err := s.decodeEventResp(eventLog)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+
+	s.pending.replace(events)
 	return nil
 }
 
 // bestBlock returns the current chain best block. In case of an error,
 // -1 is returned.
-func (s *ServerConfig) bestBlock() (int64, error) {
+func (s *Syncer) bestBlock() (int64, error) {
 	targetHeader, err := s.backend.HeaderByNumber(s.ctx, nil)
 	if err != nil {
 		return -1, fmt.Errorf("fetching the current bestblock failed: %v", err)
@@ -440,7 +597,7 @@ func (s *ServerConfig) bestBlock() (int64, error) {
 // fetchTopics returns the search parameters for all the supported events,
 // this helps to narrow down the events topics search to only the supported
 // events.
-func (s *ServerConfig) fetchTopics() ([][]common.Hash, error) {
+func (s *Syncer) fetchTopics() ([][]common.Hash, error) {
 	chatABI, err := abi.JSON(strings.NewReader(contracts.ChatABI))
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse the ABI interface: %v", err)
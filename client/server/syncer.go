@@ -0,0 +1,146 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/contracts"
+	"github.com/dmigwi/dhamana-protocol/client/sapphire"
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/storage"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Syncer syncs the events emitted by the contract deployed on a given network
+// into the local storage. Each syncer holds its own state allowing several of
+// them to run within the same process.
+type Syncer struct {
+	network      utils.NetworkType
+	contractAddr common.Address
+
+	backend  *sapphire.WrappedBackend
+	bondChat *contracts.Chat
+	db       *storage.DB
+
+	// confirmations defines the number of blocks an event must be buried
+	// under before it is persisted.
+	confirmations uint64
+	// backfillWorkers defines the maximum number of historical events windows
+	// fetched at once.
+	backfillWorkers int
+	// pending if set holds the events yet to be confirmed.
+	pending *pendingEvents
+	// notify if set, pushes the persisted events to the subscribed clients.
+	notify func(event *servertypes.EventResp)
+
+	mtx     sync.Mutex
+	running bool
+	ctx     context.Context
+
+	// quit is used to indicate that a shutdown request was recieved and the
+	// loop or goroutine should exit too.
+	quit chan struct{}
+	// done recieves the error that ended the sync once it stops.
+	done chan error
+	// wg tracks the sync run till the goroutine polling for the future
+	// events exits.
+	wg sync.WaitGroup
+
+	// lastPoll holds the time when the events were last synced successfully.
+	lastPoll time.Time
+	// syncErr holds the error that ended the last sync if any.
+	syncErr error
+}
+
+// Start syncs the historical events data in a blocking operation before
+// shifting to poll for future events asynchronously till the syncer is stopped
+// or the provided context is cancelled. A stopped syncer can be started again.
+func (s *Syncer) Start(ctx context.Context) error {
+	s.mtx.Lock()
+	if s.running {
+		s.mtx.Unlock()
+		return errors.New("syncer is already running")
+	}
+
+	s.running = true
+	s.ctx = ctx
+	s.quit = make(chan struct{})
+	s.done = make(chan error, 1)
+	s.syncErr = nil
+
+	// The sync is tracked before the lock is released so that a concurrent
+	// Stop always waits for it to exit.
+	s.wg.Add(1)
+	s.mtx.Unlock()
+
+	if err := s.syncData(); err != nil {
+		s.finish(err)
+		return err
+	}
+	return nil
+}
+
+// Stop signals the running sync to exit and blocks till it does. It is a no-op
+// if the syncer is not running.
+func (s *Syncer) Stop() {
+	s.mtx.Lock()
+	if !s.running {
+		s.mtx.Unlock()
+		return
+	}
+
+	select {
+	case <-s.quit:
+		// shutdown request was already sent.
+	default:
+		log.Info("Sync shutdown request recieved")
+		close(s.quit)
+	}
+	s.mtx.Unlock()
+
+	s.wg.Wait()
+	s.finish(nil)
+}
+
+// Done returns a channel that recieves the error that ended the current sync
+// and is then closed. A nil error is sent if the sync was stopped or its
+// context cancelled. It should only be called after the syncer is started.
+func (s *Syncer) Done() <-chan error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.done
+}
+
+// markPolled records the time when the events were last synced successfully.
+func (s *Syncer) markPolled() {
+	s.mtx.Lock()
+	s.lastPoll = time.Now().UTC()
+	s.mtx.Unlock()
+}
+
+// finish marks the syncer as stopped and sends the provided error via the done
+// channel. Only the first call made for each sync run takes effect.
+func (s *Syncer) finish(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	if err != nil {
+		log.Errorf("events data syncing ended with an error: %v", err)
+	}
+
+	s.running = false
+	s.syncErr = err
+	s.done <- err
+	close(s.done)
+}
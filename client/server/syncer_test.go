@@ -0,0 +1,76 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// runningSyncer returns a syncer in the state set once its started.
+func runningSyncer() *Syncer {
+	return &Syncer{
+		running: true,
+		quit:    make(chan struct{}),
+		done:    make(chan error, 1),
+	}
+}
+
+// TestSyncerStop tests if stopping the syncer is idempotent and that the
+// error ending the sync is sent via the done channel.
+func TestSyncerStop(t *testing.T) {
+	UseLogger(btclog.Disabled)
+
+	t.Run("stopped_sync", func(t *testing.T) {
+		s := runningSyncer()
+		s.Stop()
+		s.Stop() // Must not panic on the closed quit channel.
+
+		if err := <-s.Done(); err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if _, ok := <-s.Done(); ok {
+			t.Fatal("expected the done channel to be closed")
+		}
+	})
+
+	t.Run("stop_during_sync", func(t *testing.T) {
+		s := runningSyncer()
+		// The sync run is tracked once started.
+		s.wg.Add(1)
+
+		stopped := make(chan struct{})
+		go func() {
+			s.Stop()
+			close(stopped)
+		}()
+
+		<-s.quit
+		select {
+		case <-stopped:
+			t.Fatal("expected stop to wait for the running sync to exit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		s.wg.Done()
+		<-stopped
+	})
+
+	t.Run("failed_sync", func(t *testing.T) {
+		s := runningSyncer()
+		syncErr := errors.New("sync failed")
+
+		s.finish(syncErr)
+		s.Stop() // Must be a no-op once the sync has ended.
+
+		if err := <-s.Done(); err != syncErr {
+			t.Fatalf("expected error %v but found: %v", syncErr, err)
+		}
+
+		if s.running {
+			t.Fatal("expected the syncer to be stopped")
+		}
+	})
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/trustorg"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// loadTrustOrgs adds the CA certificates of the registered trust organisations
+// that haven't been revoked to the client certificates CAs pool.
+func loadTrustOrgs(registry *trustorg.Registry, auth *clientAuth) error {
+	orgs, err := registry.Orgs()
+	if err != nil {
+		return fmt.Errorf("fetching the trust organisations failed: %v", err)
+	}
+
+	for _, org := range orgs {
+		if org.Status == trustorg.Revoked {
+			continue
+		}
+
+		ca, err := org.Certificate()
+		if err != nil {
+			log.Errorf("trust organisation %q CA certificate ignored: %v", org.Name, err)
+			continue
+		}
+		auth.addCA(ca)
+	}
+
+	auth.orgs = registry
+	return nil
+}
+
+// checkSenderOrg packs an error into the message if the trust organisation
+// vouching for the sender has been suspended or revoked.
+func (s *ServerConfig) checkSenderOrg(msg *servertypes.RPCMessage) {
+	if s.trustOrgs == nil {
+		return
+	}
+
+	org, err := s.trustOrgs.UserOrg(msg.Sender.Address)
+	switch {
+	case err != nil:
+		msg.PackServerError(utils.ErrInternalFailure, err)
+
+	case org != nil && !org.IsActive():
+		err = fmt.Errorf("trust organisation %q vouching for the sender is %s",
+			org.Name, org.Status)
+		msg.PackServerError(utils.ErrOrgSuspended, err)
+	}
+}
+
+// isAdmin returns true if the sender can manage the trust organisations.
+func (s *ServerConfig) isAdmin(sender common.Address) bool {
+	_, ok := s.admins[sender]
+	return ok
+}
+
+// executeAdminMsg executes the trust organisations management methods
+// requested by an admin. The result or the error returned is packed into the
+// message.
+func (s *ServerConfig) executeAdminMsg(msg *servertypes.RPCMessage) {
+	if !s.isAdmin(msg.Sender.Address) {
+		msg.PackServerError(utils.ErrNotAdmin, nil)
+		return
+	}
+
+	if s.trustOrgs == nil {
+		err := errors.New("trust organisations registry is disabled")
+		msg.PackServerError(utils.ErrUnknownMethod, err)
+		return
+	}
+
+	var res interface{}
+	var err error
+
+	switch msg.Method {
+	case utils.AddTrustOrg:
+		name := msg.Params[0].(string)
+		ca, addErr := s.trustOrgs.Add(name, msg.Params[1].(string))
+		if addErr != nil {
+			err = addErr
+			break
+		}
+
+		// POAs of the new organisation can connect without a server restart.
+		if s.clientAuth != nil {
+			s.clientAuth.addCA(ca)
+		}
+		log.Infof("Trust organisation %q added by %v", name, msg.Sender.Address)
+
+		res, err = s.trustOrgs.Org(name)
+
+	case utils.SuspendTrustOrg:
+		res, err = s.setOrgStatus(msg, trustorg.Suspended)
+
+	case utils.ReinstateTrustOrg:
+		res, err = s.setOrgStatus(msg, trustorg.Active)
+
+	case utils.RevokeTrustOrg:
+		res, err = s.setOrgStatus(msg, trustorg.Revoked)
+
+	case utils.AddOrgUser:
+		err = s.trustOrgs.AddUser(msg.Params[0].(string), msg.Params[1].(common.Address))
+		res = struct{}{}
+
+	case utils.GetTrustOrgs:
+		res, err = s.trustOrgs.Orgs()
+
+	default:
+		err = fmt.Errorf("missing implementation for method %s", msg.Method)
+	}
+
+	packOrgResult(msg, res, err)
+}
+
+// executeReferralMsg records the referrals made by the vetted users and
+// returns the users' profiles. The result or the error returned is packed
+// into the message.
+func (s *ServerConfig) executeReferralMsg(msg *servertypes.RPCMessage) {
+	if s.trustOrgs == nil {
+		err := errors.New("trust organisations registry is disabled")
+		msg.PackServerError(utils.ErrUnknownMethod, err)
+		return
+	}
+
+	var res interface{}
+	var err error
+
+	user := msg.Params[0].(common.Address)
+
+	switch msg.Method {
+	case utils.ReferUser:
+		var org *trustorg.Org
+		org, err = s.trustOrgs.Refer(msg.Sender.Address, user)
+		if err == nil {
+			res = struct {
+				Org string `json:"org"`
+			}{
+				Org: org.Name,
+			}
+		}
+
+	case utils.GetUserProfile:
+		res, err = s.trustOrgs.Profile(user)
+
+	default:
+		err = fmt.Errorf("missing implementation for method %s", msg.Method)
+	}
+
+	packOrgResult(msg, res, err)
+}
+
+// packOrgResult packs the result or the error returned by the trust
+// organisations registry into the message.
+func packOrgResult(msg *servertypes.RPCMessage, res interface{}, err error) {
+	switch {
+	case errors.Is(err, trustorg.ErrNotVetted):
+		msg.PackServerError(utils.ErrNotVetted, err)
+
+	case errors.Is(err, trustorg.ErrUnknownOrg), errors.Is(err, trustorg.ErrOrgRevoked),
+		errors.Is(err, trustorg.ErrOrgNotActive), errors.Is(err, trustorg.ErrInvalidOrg),
+		errors.Is(err, trustorg.ErrInvalidReferral):
+		msg.PackServerError(utils.ErrInvalidReq, err)
+
+	case err != nil:
+		msg.PackServerError(utils.ErrInternalFailure, err)
+
+	default:
+		msg.PackServerResult(res)
+	}
+}
+
+// setOrgStatus updates the status of the trust organisation in the message params.
+func (s *ServerConfig) setOrgStatus(msg *servertypes.RPCMessage, status trustorg.Status) (*trustorg.Org, error) {
+	name := msg.Params[0].(string)
+
+	org, err := s.trustOrgs.SetStatus(name, status)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Trust organisation %q is now %s, set by %v", name, status, msg.Sender.Address)
+	return org, nil
+}
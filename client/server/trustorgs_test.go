@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog"
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/storage"
+	"github.com/dmigwi/dhamana-protocol/client/trustorg"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// orgsStore is an in-memory store of the trust organisations.
+type orgsStore struct {
+	orgs  map[string]*trustorg.Org
+	users map[string]string
+}
+
+func (o *orgsStore) QueryLocalData(method utils.Method, r storage.Reader, _ string,
+	params ...interface{},
+) ([]interface{}, error) {
+	var org *trustorg.Org
+	switch method {
+	case utils.GetTrustOrg:
+		org = o.orgs[params[0].(string)]
+	case utils.GetUserOrg:
+		org = o.orgs[o.users[params[0].(string)]]
+	}
+
+	if org == nil {
+		return nil, nil
+	}
+
+	data, err := r.Read(func(fields ...any) error {
+		*fields[0].(*string) = org.Name
+		*fields[1].(*string) = org.CACert
+		*fields[2].(*string) = string(org.Status)
+		*fields[3].(*time.Time) = org.AddedOn
+		*fields[4].(*time.Time) = org.LastUpdate
+		return nil
+	})
+	return []interface{}{data}, err
+}
+
+func (o *orgsStore) SetLocalData(method utils.Method, params ...interface{}) error {
+	switch method {
+	case utils.InsertTrustOrg:
+		name := params[0].(string)
+		o.orgs[name] = &trustorg.Org{Name: name, CACert: params[1].(string), Status: trustorg.Active}
+	case utils.UpdateTrustOrgStatus:
+		o.orgs[params[1].(string)].Status = trustorg.Status(params[0].(string))
+	case utils.InsertOrgUser:
+		o.users[params[0].(string)] = params[1].(string)
+	}
+	return nil
+}
+
+// TestExecuteAdminMsg tests that only the admins can manage the trust
+// organisations and that the users vouched for by the suspended ones are
+// denied access.
+func TestExecuteAdminMsg(t *testing.T) {
+	UseLogger(btclog.Disabled)
+
+	admin, user := sampleHexAddress2, sampleHexAddress3
+
+	sessions := newMemSessions()
+	for _, sender := range []common.Address{admin, user} {
+		sessions.Store(sender, servertypes.ServerKeyResp{
+			Expiry:       uint64(time.Now().UTC().Add(10 * time.Minute).Unix()),
+			SharedKey:    []byte(sharedKey2),
+			SessionToken: sampleSessionToken,
+		})
+	}
+
+	s := &ServerConfig{
+		sessions:    sessions,
+		signingMode: utils.ServerSigning,
+		trustOrgs: trustorg.NewRegistry(&orgsStore{
+			orgs:  make(map[string]*trustorg.Org),
+			users: make(map[string]string),
+		}),
+		admins: map[common.Address]struct{}{admin: {}},
+	}
+
+	ca := newTestCA(t, "Org A")
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+
+	// The test cases are executed in order.
+	td := []struct {
+		testName string
+		sender   common.Address
+		method   utils.Method
+		params   []interface{}
+		errCode  uint16
+	}{
+		{"Test-for-non-admin-sender", user, utils.GetTrustOrgs, nil, utils.GetErrorCode(utils.ErrNotAdmin)},
+		{"Test-for-invalid-ca-certificate", admin, utils.AddTrustOrg, []interface{}{"Org A", "certificate"}, utils.GetErrorCode(utils.ErrInvalidReq)},
+		{"Test-for-adding-organisation", admin, utils.AddTrustOrg, []interface{}{"Org A", caCert}, 0},
+		{"Test-for-vouching-user", admin, utils.AddOrgUser, []interface{}{"Org A", user}, 0},
+		{"Test-for-active-organisation-user", user, utils.SuspendTrustOrg, []interface{}{"Org A"}, utils.GetErrorCode(utils.ErrNotAdmin)},
+		{"Test-for-suspending-organisation", admin, utils.SuspendTrustOrg, []interface{}{"Org A"}, 0},
+		{"Test-for-suspended-organisation-user", user, utils.GetTrustOrgs, nil, utils.GetErrorCode(utils.ErrOrgSuspended)},
+		{"Test-for-reinstating-organisation", admin, utils.ReinstateTrustOrg, []interface{}{"Org A"}, 0},
+		{"Test-for-revoking-organisation", admin, utils.RevokeTrustOrg, []interface{}{"Org A"}, 0},
+		{"Test-for-reinstating-revoked-organisation", admin, utils.ReinstateTrustOrg, []interface{}{"Org A"}, utils.GetErrorCode(utils.ErrInvalidReq)},
+		{"Test-for-revoked-organisation-user", user, utils.GetTrustOrgs, nil, utils.GetErrorCode(utils.ErrOrgSuspended)},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			msg := &servertypes.RPCMessage{
+				Method: v.method,
+				Params: v.params,
+				Sender: &servertypes.SenderInfo{Address: v.sender, SessionToken: sampleSessionToken},
+			}
+
+			s.executeBackendMsg(context.Background(), msg, utils.AdminType)
+
+			var errCode uint16
+			if msg.Error != nil {
+				errCode = msg.Error.Code
+			}
+
+			if errCode != v.errCode {
+				t.Fatalf("expected error code %d but found %d (%v)", v.errCode, errCode, msg.Error)
+			}
+		})
+	}
+}
+
+// TestExecuteReferralMsg tests that only the users vetted by an active trust
+// organisation can refer new users.
+func TestExecuteReferralMsg(t *testing.T) {
+	vetted, unvetted := sampleHexAddress2, sampleHexAddress3
+
+	store := &orgsStore{
+		orgs: map[string]*trustorg.Org{
+			"Org A": {Name: "Org A", Status: trustorg.Active},
+		},
+		users: map[string]string{vetted.Hex(): "Org A"},
+	}
+
+	td := []struct {
+		testName  string
+		trustOrgs *trustorg.Registry
+		sender    common.Address
+		referee   common.Address
+		errCode   uint16
+	}{
+		{"Test-for-disabled-registry", nil, vetted, sampleHexAddress1, utils.GetErrorCode(utils.ErrUnknownMethod)},
+		{"Test-for-unvetted-sender", trustorg.NewRegistry(store), unvetted, sampleHexAddress1, utils.GetErrorCode(utils.ErrNotVetted)},
+		{"Test-for-self-referral", trustorg.NewRegistry(store), vetted, vetted, utils.GetErrorCode(utils.ErrInvalidReq)},
+		{"Test-for-vetted-sender", trustorg.NewRegistry(store), vetted, sampleHexAddress1, 0},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			s := &ServerConfig{trustOrgs: v.trustOrgs}
+			msg := &servertypes.RPCMessage{
+				Method: utils.ReferUser,
+				Params: []interface{}{v.referee},
+				Sender: &servertypes.SenderInfo{Address: v.sender},
+			}
+
+			s.executeReferralMsg(msg)
+
+			var errCode uint16
+			if msg.Error != nil {
+				errCode = msg.Error.Code
+			}
+
+			if errCode != v.errCode {
+				t.Fatalf("expected error code %d but found %d (%v)", v.errCode, errCode, msg.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/contracts"
+	"github.com/dmigwi/dhamana-protocol/client/sapphire"
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/storage"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// txTrackingInterval describes the intervals at which the pending
+	// transactions are checked for their receipts.
+	txTrackingInterval = 15 * time.Second
+
+	// txDropTimeout defines how long a transaction unknown to the node is kept
+	// pending before its nonce is checked to confirm whether it was dropped.
+	txDropTimeout = 2 * time.Minute
+
+	// droppedTxRepollWindow defines how long the dropped transactions are
+	// still checked for a late receipt.
+	droppedTxRepollWindow = 30 * time.Minute
+
+	// maxTrackedTxs defines the maximum number of pending transactions checked
+	// in a single tracking interval.
+	maxTrackedTxs = 100
+
+	// revertPrefix prefixes the revert reason in the node error messages.
+	revertPrefix = "execution reverted:"
+)
+
+// txTracker tracks the submitted transactions through the pending, mined,
+// reverted and dropped stages.
+type txTracker struct {
+	contractAddr common.Address
+	backend      *sapphire.WrappedBackend
+	bondChat     *contracts.Chat
+	db           *storage.DB
+}
+
+// track records the transaction submitted by the sender as pending together
+// with the sender's trust organisation and the plain input, if known, used to
+// replay it if it reverts. A nil tracker doesn't track the transactions.
+func (t *txTracker) track(sender *servertypes.SenderInfo, method utils.Method,
+	tx *types.Transaction, input []byte,
+) {
+	if t == nil {
+		return
+	}
+
+	var org, callData interface{}
+	if sender.Org != "" {
+		org = sender.Org
+	}
+	if input != nil {
+		callData = hexutil.Encode(input)
+	}
+
+	err := t.db.SetLocalData(utils.InsertPendingTx, tx.Hash().String(),
+		sender.Address.String(), string(method), tx.Nonce(), org, callData)
+	if err != nil {
+		log.Errorf("tracking tx %v failed: %v", tx.Hash(), err)
+	}
+}
+
+// run checks the pending transactions at every tracking interval until the
+// context is cancelled.
+func (t *txTracker) run(ctx context.Context) {
+	ticker := time.NewTicker(txTrackingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.checkPendingTxs(ctx)
+		}
+	}
+}
+
+// checkPendingTxs updates the status of the oldest pending and recently
+// dropped transactions whose outcome is now known.
+func (t *txTracker) checkPendingTxs(ctx context.Context) {
+	data, err := t.db.QueryLocalData(utils.GetPendingTxs, new(servertypes.TrackedTxResp),
+		"", maxTrackedTxs, int(droppedTxRepollWindow.Seconds()))
+	if err != nil {
+		log.Errorf("fetching the pending txs failed: %v", err)
+		return
+	}
+
+	for _, d := range data {
+		ptx := d.(*servertypes.TrackedTxResp)
+
+		updated, err := t.checkTx(ctx, ptx)
+		if err != nil {
+			log.Errorf("checking tx %v status failed: %v", ptx.TxHash, err)
+			continue
+		}
+
+		if !updated {
+			continue
+		}
+
+		var blockNo, revertReason, bondAddress interface{}
+		if ptx.BlockNo > 0 {
+			blockNo = ptx.BlockNo
+		}
+		if ptx.RevertReason != "" {
+			revertReason = ptx.RevertReason
+		}
+		if ptx.BondAddress != nil {
+			bondAddress = ptx.BondAddress.String()
+		}
+
+		err = t.db.SetLocalData(utils.UpdateTxStatus, string(ptx.Status), blockNo,
+			revertReason, bondAddress, ptx.TxHash.String())
+		if err != nil {
+			log.Errorf("updating tx %v status failed: %v", ptx.TxHash, err)
+			continue
+		}
+
+		log.Debugf("Tx %v submitted by %v is now %s", ptx.TxHash, ptx.Sender, ptx.Status)
+	}
+}
+
+// checkTx sets the tracked transaction status from its receipt. It returns
+// true if the transaction status changed.
+func (t *txTracker) checkTx(ctx context.Context, ptx *servertypes.TrackedTxResp) (bool, error) {
+	receipt, err := t.backend.TransactionReceipt(ctx, ptx.TxHash)
+	switch {
+	case errors.Is(err, ethereum.NotFound) && ptx.Status == servertypes.TxDropped:
+		// Still no late receipt for the dropped transaction.
+		return false, nil
+
+	case errors.Is(err, ethereum.NotFound):
+		// Not yet mined, confirm the node still knows about the transaction.
+		_, _, err = t.backend.TransactionByHash(ctx, ptx.TxHash)
+		if !errors.Is(err, ethereum.NotFound) {
+			return false, err
+		}
+
+		if time.Since(ptx.SubmittedAt) < txDropTimeout {
+			return false, nil
+		}
+
+		// The transaction is only dropped once another one of the sender's
+		// transactions was confirmed with its nonce.
+		confirmedNonce, err := t.backend.NonceAt(ctx, ptx.Sender, nil)
+		if err != nil {
+			return false, err
+		}
+
+		if confirmedNonce <= ptx.Nonce {
+			// The unknown tx may leave a gap in the sender's nonces, reseed
+			// them so that the next tx fills it.
+			t.backend.Nonces().Reset(ptx.Sender)
+			return false, nil
+		}
+
+		ptx.Status = servertypes.TxDropped
+		return true, nil
+
+	case err != nil:
+		return false, err
+	}
+
+	ptx.BlockNo = receipt.BlockNumber.Uint64()
+
+	if receipt.Status == types.ReceiptStatusFailed {
+		ptx.Status = servertypes.TxReverted
+		ptx.RevertReason = t.revertReason(ctx, ptx, receipt.BlockNumber)
+		return true, nil
+	}
+
+	ptx.Status = servertypes.TxMined
+	ptx.BondAddress = t.bondCreated(receipt)
+	return true, nil
+}
+
+// revertReason replays the reverted transaction input at the block it was
+// mined in to recover the reason it reverted.
+func (t *txTracker) revertReason(ctx context.Context, ptx *servertypes.TrackedTxResp,
+	blockNumber *big.Int,
+) string {
+	tx, _, err := t.backend.TransactionByHash(ctx, ptx.TxHash)
+	if err != nil {
+		log.Errorf("fetching the reverted tx %v failed: %v", ptx.TxHash, err)
+		return ""
+	}
+
+	err = t.backend.ReplayTx(ctx, tx, ptx.Sender, ptx.CallData, blockNumber)
+	if errors.Is(err, sapphire.ErrNoTxInput) {
+		log.Debugf("replaying the reverted tx %v failed: %v", ptx.TxHash, err)
+		return ""
+	}
+	return decodeRevertReason(err)
+}
+
+// bondCreated returns the address of the bond created by the mined transaction
+// if it emitted the NewBondCreated event.
+func (t *txTracker) bondCreated(receipt *types.Receipt) *common.Address {
+	for _, eventLog := range receipt.Logs {
+		if eventLog.Address != t.contractAddr {
+			continue
+		}
+
+		newBondCreated, err := t.bondChat.ParseNewBondCreated(*eventLog)
+		if err == nil {
+			return &newBondCreated.BondAddress
+		}
+	}
+	return nil
+}
+
+// decodeRevertReason returns the require string a reverted call failed with.
+// If the revert data can't be decoded the node error message is returned.
+func decodeRevertReason(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var callErr *sapphire.CallError
+	if errors.As(err, &callErr) && callErr.Reason != "" {
+		return callErr.Reason
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if revertData, err := hexutil.Decode(data); err == nil {
+				if reason, err := abi.UnpackRevert(revertData); err == nil {
+					return reason
+				}
+			}
+		}
+	}
+
+	msg := err.Error()
+	if i := strings.Index(msg, revertPrefix); i >= 0 {
+		return strings.TrimSpace(msg[i+len(revertPrefix):])
+	}
+	return msg
+}
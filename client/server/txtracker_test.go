@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/contracts"
+	"github.com/dmigwi/dhamana-protocol/client/sapphire"
+	"github.com/dmigwi/dhamana-protocol/client/servertypes"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// revertErr mocks the node error returned by a reverted call.
+type revertErr struct {
+	msg  string
+	data interface{}
+}
+
+func (e revertErr) Error() string          { return e.msg }
+func (e revertErr) ErrorData() interface{} { return e.data }
+
+// txReaderWrapper mocks a client that can query transactions.
+type txReaderWrapper struct {
+	mockWrapper
+	receipts map[common.Hash]*types.Receipt
+	mempool  map[common.Hash]*types.Transaction
+	txs      map[common.Hash]*types.Transaction
+	callErr  error
+	nonce    uint64
+}
+
+func (m *txReaderWrapper) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if receipt, ok := m.receipts[txHash]; ok {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (m *txReaderWrapper) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	if tx, ok := m.mempool[txHash]; ok {
+		return tx, true, nil
+	}
+	if tx, ok := m.txs[txHash]; ok {
+		return tx, false, nil
+	}
+	return nil, false, ethereum.NotFound
+}
+
+func (m *txReaderWrapper) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return m.nonce, nil
+}
+
+func (m *txReaderWrapper) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, m.callErr
+}
+
+// packRevert returns the revert data of a require statement failing with the
+// provided reason.
+func packRevert(t *testing.T, reason string) string {
+	stringType, _ := abi.NewType("string", "", nil)
+	data, err := abi.Arguments{{Type: stringType}}.Pack(reason)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	// Error(string) function selector.
+	return hexutil.Encode(append([]byte{0x08, 0xc3, 0x79, 0xa0}, data...))
+}
+
+// TestDecodeRevertReason tests the extraction of the require strings from the
+// reverted calls errors.
+func TestDecodeRevertReason(t *testing.T) {
+	td := []struct {
+		testName string
+		err      error
+		reason   string
+	}{
+		{"no_error", nil, ""},
+		{
+			"abi_encoded_revert_data",
+			revertErr{"execution reverted", packRevert(t, "Missing bond holder address")},
+			"Missing bond holder address",
+		},
+		{
+			"revert_reason_in_message",
+			errors.New("execution reverted: Issuer & Holder must be separate"),
+			"Issuer & Holder must be separate",
+		},
+		{"other_error", errors.New("connection refused"), "connection refused"},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			if reason := decodeRevertReason(v.err); reason != v.reason {
+				t.Fatalf("expected revert reason %q but found %q", v.reason, reason)
+			}
+		})
+	}
+}
+
+// TestCheckTx tests the status set on the pending transactions depending on
+// their receipts.
+func TestCheckTx(t *testing.T) {
+	chatABI, err := contracts.ChatMetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	event := chatABI.Events["NewBondCreated"]
+	eventData, err := event.Inputs.NonIndexed().Pack(sampleHexAddress2, sampleHexAddress3)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	minedHash := common.HexToHash("0x01")
+	revertedHash := common.HexToHash("0x02")
+	mempoolHash := common.HexToHash("0x03")
+
+	conn := &txReaderWrapper{
+		receipts: map[common.Hash]*types.Receipt{
+			minedHash: {
+				Status:      types.ReceiptStatusSuccessful,
+				BlockNumber: big.NewInt(100),
+				Logs: []*types.Log{{
+					Address: sampleHexAddress,
+					Topics:  []common.Hash{event.ID},
+					Data:    eventData,
+				}},
+			},
+			revertedHash: {
+				Status:      types.ReceiptStatusFailed,
+				BlockNumber: big.NewInt(101),
+			},
+		},
+		txs: map[common.Hash]*types.Transaction{
+			revertedHash: types.NewTx(&types.LegacyTx{To: &sampleHexAddress}),
+		},
+		mempool: map[common.Hash]*types.Transaction{
+			mempoolHash: types.NewTx(&types.LegacyTx{To: &sampleHexAddress}),
+		},
+		callErr: revertErr{"execution reverted", packRevert(t, "Missing bond holder address")},
+		nonce:   5,
+	}
+
+	backend, err := sapphire.WrapClient(context.Background(), conn, utils.LocalTesting, mockSigner)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	chatInstance, err := contracts.NewChat(sampleHexAddress, backend)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	tracker := &txTracker{
+		contractAddr: sampleHexAddress,
+		backend:      backend,
+		bondChat:     chatInstance,
+	}
+
+	old := time.Now().Add(-2 * txDropTimeout)
+
+	pending, dropped := servertypes.TxPending, servertypes.TxDropped
+
+	td := []struct {
+		testName    string
+		txHash      common.Hash
+		prevStatus  servertypes.TxStatus
+		nonce       uint64
+		submittedAt time.Time
+		updated     bool
+		status      servertypes.TxStatus
+		reason      string
+		bondAddress *common.Address
+	}{
+		{"mined_createBond_tx", minedHash, pending, 4, time.Now(), true, servertypes.TxMined, "", &sampleHexAddress3},
+		{"reverted_tx", revertedHash, pending, 4, time.Now(), true, servertypes.TxReverted, "Missing bond holder address", nil},
+		{"tx_in_mempool", mempoolHash, pending, 5, old, false, pending, "", nil},
+		{"recently_submitted_unknown_tx", common.HexToHash("0x04"), pending, 4, time.Now(), false, pending, "", nil},
+		{"unknown_tx_with_unconfirmed_nonce", common.HexToHash("0x05"), pending, 5, old, false, pending, "", nil},
+		{"dropped_tx", common.HexToHash("0x05"), pending, 4, old, true, dropped, "", nil},
+		{"dropped_tx_without_receipt", common.HexToHash("0x05"), dropped, 4, old, false, dropped, "", nil},
+		{"dropped_tx_mined_late", minedHash, dropped, 4, old, true, servertypes.TxMined, "", &sampleHexAddress3},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			ptx := &servertypes.TrackedTxResp{
+				TxStatusResp: servertypes.TxStatusResp{
+					TxHash:      v.txHash,
+					Sender:      sampleHexAddress2,
+					Nonce:       v.nonce,
+					Status:      v.prevStatus,
+					SubmittedAt: v.submittedAt,
+				},
+				CallData: common.FromHex("0x2b9e4b5f"),
+			}
+
+			updated, err := tracker.checkTx(context.Background(), ptx)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if updated != v.updated {
+				t.Fatalf("expected the tx updated to be %v but found %v", v.updated, updated)
+			}
+
+			if ptx.Status != v.status {
+				t.Fatalf("expected tx status %q but found %q", v.status, ptx.Status)
+			}
+
+			if ptx.RevertReason != v.reason {
+				t.Fatalf("expected revert reason %q but found %q", v.reason, ptx.RevertReason)
+			}
+
+			if (ptx.BondAddress == nil) != (v.bondAddress == nil) ||
+				(ptx.BondAddress != nil && *ptx.BondAddress != *v.bondAddress) {
+				t.Fatalf("expected bond address %v but found %v", v.bondAddress, ptx.BondAddress)
+			}
+		})
+	}
+}
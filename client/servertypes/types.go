@@ -4,6 +4,7 @@
 package servertypes
 
 import (
+	"database/sql"
 	"encoding/json"
 	"time"
 
@@ -32,7 +33,16 @@ type SenderInfo struct {
 	// SigningKey must be encrypted with the session's public key before being sent.
 	// Failure to do so could expose the actual user key to hackers.
 	// It must be signed via the diffie-hellman passed pubkey.
+	// If not set on a contract method, the unsigned transaction is returned
+	// for the client to sign when the client signing mode is enabled.
 	SigningKey string `json:"signingkey,omitempty"`
+	// SessionToken is the token issued once the sender signed the session
+	// challenge. It is required by all the methods executed in a session.
+	SessionToken string `json:"session_token,omitempty"`
+
+	// Org is the trust organisation identified by the verified client
+	// certificate the request was made with. It is only set by the server.
+	Org string `json:"-"`
 }
 
 // RPCError defines the error message information sent to the user on happening.
@@ -47,13 +57,23 @@ type RPCError struct {
 // ServerKeyResp defines the response returned once the server public key is
 // requested by a POA (Point Of Access) client.
 type ServerKeyResp struct {
-	Pubkey string `json:"pubkey"`
-	Expiry uint64 `json:"expiry"` // timestamp in seconds at UTC timezone
+	Pubkey       string `json:"pubkey"`
+	Expiry       uint64 `json:"expiry"` // timestamp in seconds at UTC timezone
+	SessionToken string `json:"session_token"`
 
 	// private field ignored by the JSON encoder.
 	SharedKey []byte `json:"-"` // Generate using the remote Pubkey + local private key.
 }
 
+// SessionChallengeResp defines the response returned once the session
+// challenge is requested by a POA client. The message must be signed using
+// the EIP-191 personal_sign scheme and sent back with the nonce.
+type SessionChallengeResp struct {
+	Nonce   string `json:"nonce"`
+	Message string `json:"message"`
+	Expiry  uint64 `json:"expiry"` // timestamp in seconds at UTC timezone
+}
+
 // BondResp defines the response returned in an array form
 // when get bonds local type method is queried by a POA client.
 type BondResp struct {
@@ -77,11 +97,25 @@ type BondByAddressResp struct {
 	IntroMessage    string         `json:"intro_msg"`
 	LastUpdate      time.Time      `json:"last_update"`
 	LastSyncedBlock uint64         `json:"last_synced_block"`
+
+	// IssuerOrg and HolderOrg are the trust organisations that vetted the
+	// bond parties.
+	IssuerOrg string `json:"issuer_org,omitempty"`
+	HolderOrg string `json:"holder_org,omitempty"`
+
+	// PendingEvents holds the bond events yet to be confirmed if requested.
+	PendingEvents []*EventResp `json:"pending_events,omitempty"`
 }
 
 // LastSyncedBlockResp defines the block last synced.
 type LastSyncedBlockResp uint64
 
+// BlockHashResp defines the hash of a processed block.
+type BlockHashResp struct {
+	BlockNo uint64
+	Hash    common.Hash
+}
+
 // ChatMsgsResp defines the response returned in an array form when get
 // chats local type method is queried by the client.
 type ChatMsgsResp struct {
@@ -90,6 +124,121 @@ type ChatMsgsResp struct {
 	Message         string         `json:"chat_msg"`
 	CreatedTime     time.Time      `json:"created_at"`
 	LastSyncedBlock uint64         `json:"last_synced_block"`
+
+	// Pending is set on the chats yet to be confirmed.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// EventResp defines the bond event pushed to the subscribed websocket clients
+// once it has been persisted.
+type EventResp struct {
+	Event       string         `json:"event"`
+	BondAddress common.Address `json:"bond_address"`
+	BlockNo     uint64         `json:"block_no"`
+	Data        interface{}    `json:"data"`
+}
+
+// SyncStatusResp defines the events sync progress returned on the /healthz
+// and /syncstatus routes.
+type SyncStatusResp struct {
+	Running         bool      `json:"running"`
+	Ready           bool      `json:"ready"`
+	BestBlock       int64     `json:"best_block"`
+	LastSyncedBlock int64     `json:"last_synced_block"`
+	LagBlocks       int64     `json:"lag_blocks"`
+	LagSeconds      int64     `json:"lag_seconds"`
+	LastPoll        time.Time `json:"last_poll"`
+	DBConnected     bool      `json:"db_connected"`
+	SyncError       string    `json:"sync_error,omitempty"`
+
+	// RuntimeKeyEpoch and RuntimeKeyAge describe the Sapphire runtime public
+	// key encrypting the calls. The age is the number of seconds since the
+	// key was last fetched and verified.
+	RuntimeKeyEpoch uint64 `json:"runtime_key_epoch,omitempty"`
+	RuntimeKeyAge   int64  `json:"runtime_key_age,omitempty"`
+}
+
+// UnsignedTxResp defines the unsigned transaction returned when a contract
+// method is sent without a signing key. The client signs the signing hash and
+// sends the signature back with the unsigned tx via the sendSignedTx method.
+type UnsignedTxResp struct {
+	UnsignedTx  string `json:"unsigned_tx"`
+	SigningHash string `json:"signing_hash"`
+	ChainID     string `json:"chain_id"`
+}
+
+// BondSecureDetailsResp defines the bond details only accessible to the bond
+// issuer and holder.
+type BondSecureDetailsResp struct {
+	Security string `json:"security"`
+	Appendix string `json:"appendix"`
+}
+
+// TxStatus defines the lifecycle stages of a transaction submitted via the server.
+type TxStatus string
+
+const (
+	// TxPending is the status of a transaction yet to be mined.
+	TxPending TxStatus = "pending"
+	// TxMined is the status of a transaction mined successfully.
+	TxMined TxStatus = "mined"
+	// TxReverted is the status of a transaction mined but reverted.
+	TxReverted TxStatus = "reverted"
+	// TxDropped is the status of a transaction no longer known by the node.
+	TxDropped TxStatus = "dropped"
+)
+
+// TxStatusResp defines the response returned when the lifecycle status of the
+// transactions submitted by the sender is queried.
+type TxStatusResp struct {
+	TxHash       common.Hash     `json:"tx_hash"`
+	Sender       common.Address  `json:"sender"`
+	Method       utils.Method    `json:"method"`
+	Nonce        uint64          `json:"nonce"`
+	Status       TxStatus        `json:"status"`
+	BlockNo      uint64          `json:"block_no,omitempty"`
+	RevertReason string          `json:"revert_reason,omitempty"`
+	BondAddress  *common.Address `json:"bond_address,omitempty"`
+	SubmittedAt  time.Time       `json:"submitted_at"`
+	LastUpdate   time.Time       `json:"last_update"`
+}
+
+// TrackedTxResp defines a transaction whose status is checked by the server
+// together with the plain input it was submitted with, if known.
+type TrackedTxResp struct {
+	TxStatusResp
+	CallData []byte
+}
+
+// StatusResp defines the data pushed with the StatusChange and StatusSigned
+// bond events.
+type StatusResp struct {
+	Sender common.Address `json:"sender"`
+	Status uint8          `json:"status"`
+}
+
+// NewBondResp defines the data pushed with the NewBondCreated bond event.
+type NewBondResp struct {
+	Issuer common.Address `json:"issuer_address"`
+}
+
+// MotivationResp defines the data pushed with the BondMotivation bond event.
+type MotivationResp struct {
+	Message string `json:"intro_msg"`
+}
+
+// HolderResp defines the data pushed with the HolderUpdate bond event.
+type HolderResp struct {
+	Holder common.Address `json:"holder_address"`
+}
+
+// BondBodyTermsResp defines the data pushed with the BondBodyTerms bond event.
+type BondBodyTermsResp struct {
+	Principal    uint64    `json:"principal"`
+	CouponRate   uint8     `json:"coupon_rate"`
+	CouponDate   uint8     `json:"coupon_date"`
+	MaturityDate time.Time `json:"maturity_date"`
+	Currency     uint8     `json:"currency"`
 }
 
 // packServerError packs the errors identified into a response ready to be sent
@@ -146,16 +295,59 @@ func (r *BondResp) Read(fn func(fields ...any) error) (interface{}, error) {
 func (r *BondByAddressResp) Read(fn func(fields ...any) error) (interface{}, error) {
 	var resp BondByAddressResp
 	var bondAddress, issuer, holder string
+	var issuerOrg, holderOrg sql.NullString
 
 	err := fn(&bondAddress, &issuer, &holder, &resp.BondResp.CreatedTime,
 		&resp.CreatedAtBlock, &resp.Principal, &resp.BondResp.CouponRate,
 		&resp.CouponDate, &resp.MaturityDate, &resp.BondResp.Currency, &resp.IntroMessage,
 		&resp.BondResp.LastStatus, &resp.LastUpdate, &resp.LastSyncedBlock,
+		&issuerOrg, &holderOrg,
 	)
 
 	resp.BondResp.BondAddress = common.HexToAddress(bondAddress)
 	resp.BondResp.Issuer = common.HexToAddress(issuer)
 	resp.Holder = common.HexToAddress(holder)
+	resp.IssuerOrg = issuerOrg.String
+	resp.HolderOrg = holderOrg.String
+	return &resp, err
+}
+
+// Reader interface implementation for type TxStatusResp.
+func (r *TxStatusResp) Read(fn func(fields ...any) error) (interface{}, error) {
+	var resp TxStatusResp
+	var txHash, sender, method, status string
+	var blockNo sql.NullInt64
+	var revertReason, bondAddress sql.NullString
+
+	err := fn(&txHash, &sender, &method, &resp.Nonce, &status, &blockNo,
+		&revertReason, &bondAddress, &resp.SubmittedAt, &resp.LastUpdate,
+	)
+
+	resp.TxHash = common.HexToHash(txHash)
+	resp.Sender = common.HexToAddress(sender)
+	resp.Method = utils.Method(method)
+	resp.Status = TxStatus(status)
+	resp.BlockNo = uint64(blockNo.Int64)
+	resp.RevertReason = revertReason.String
+	if bondAddress.Valid {
+		address := common.HexToAddress(bondAddress.String)
+		resp.BondAddress = &address
+	}
+	return &resp, err
+}
+
+// Reader interface implementation for type TrackedTxResp.
+func (r *TrackedTxResp) Read(fn func(fields ...any) error) (interface{}, error) {
+	var callData sql.NullString
+
+	data, err := r.TxStatusResp.Read(func(fields ...any) error {
+		return fn(append(fields, &callData)...)
+	})
+
+	resp := TrackedTxResp{TxStatusResp: *data.(*TxStatusResp)}
+	if callData.Valid {
+		resp.CallData = common.FromHex(callData.String)
+	}
 	return &resp, err
 }
 
@@ -167,6 +359,17 @@ func (r *LastSyncedBlockResp) Read(fn func(fields ...any) error) (interface{}, e
 	return &resp, err
 }
 
+// Reader interface implementation for type BlockHashResp.
+func (r *BlockHashResp) Read(fn func(fields ...any) error) (interface{}, error) {
+	var resp BlockHashResp
+	var hash string
+
+	err := fn(&resp.BlockNo, &hash)
+
+	resp.Hash = common.HexToHash(hash)
+	return &resp, err
+}
+
 // Reader interface implementation for type ChatMsgsResp.
 func (r *ChatMsgsResp) Read(fn func(fields ...any) error) (interface{}, error) {
 	var resp ChatMsgsResp
@@ -19,7 +19,7 @@ const (
 	// otherwise the system will not be able initiate the db instance until the
 	// user manually handles the data migration or creates a new
 	// database to use with the new tables.
-	semVersion = "v0.0.1"
+	semVersion = "v0.0.10"
 
 	// createVersionTable enables version tables preventing tables with
 	// incompatible schemas from being used.
@@ -45,7 +45,10 @@ const (
 		"intro_msg TEXT," +
 		"last_status SMALLINT CHECK (last_status BETWEEN 0 AND 10)," +
 		"last_update TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP," +
-		"last_synced_block INTEGER NOT NULL)"
+		"last_synced_block INTEGER NOT NULL," +
+		"org VARCHAR(64)," +
+		"contract_address VARCHAR(42) NOT NULL," +
+		"network VARCHAR(30) NOT NULL)"
 
 	// createTableBondStatus is a prepared statement creating a table identified
 	// with the name table_status if it doesn't exists.
@@ -55,7 +58,12 @@ const (
 		"bond_address VARCHAR(42) NOT NULL," +
 		"bond_status SMALLINT NOT NULL CHECK(bond_status BETWEEN 0 AND 10)," +
 		"added_on TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP," +
-		"last_synced_block INTEGER NOT NULL)"
+		"last_synced_block INTEGER NOT NULL," +
+		"tx_hash VARCHAR(66) NOT NULL," +
+		"log_index INTEGER NOT NULL," +
+		"contract_address VARCHAR(42) NOT NULL," +
+		"network VARCHAR(30) NOT NULL," +
+		"UNIQUE (tx_hash, log_index))"
 
 	// createTableBondStatusSigned is a prepared statement creating a table
 	// identified with the name table_status if it doesn't exists.
@@ -65,7 +73,12 @@ const (
 		"bond_address VARCHAR(42) NOT NULL," +
 		"bond_status SMALLINT NOT NULL CHECK(bond_status BETWEEN 0 AND 10)," +
 		"signed_on TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP," +
-		"last_synced_block INTEGER NOT NULL)"
+		"last_synced_block INTEGER NOT NULL," +
+		"tx_hash VARCHAR(66) NOT NULL," +
+		"log_index INTEGER NOT NULL," +
+		"contract_address VARCHAR(42) NOT NULL," +
+		"network VARCHAR(30) NOT NULL," +
+		"UNIQUE (tx_hash, log_index))"
 
 	// createChatTable is a prepared statement creating a table identified with
 	// the name table_chat if it doesn't exists.
@@ -75,7 +88,102 @@ const (
 		"bond_address VARCHAR(42) NOT NULL," +
 		"chat_msg TEXT NOT NULL," +
 		"created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP," +
-		"last_synced_block INTEGER NOT NULL)"
+		"last_synced_block INTEGER NOT NULL," +
+		"tx_hash VARCHAR(66) NOT NULL," +
+		"log_index INTEGER NOT NULL," +
+		"org VARCHAR(64)," +
+		"contract_address VARCHAR(42) NOT NULL," +
+		"network VARCHAR(30) NOT NULL," +
+		"UNIQUE (tx_hash, log_index))"
+
+	// createBlockHashTable is a prepared statement creating a table identified
+	// with the name table_block_hash if it doesn't exists. It holds the hashes
+	// of the processed blocks of each contract and network that are used to
+	// detect chain reorganisations.
+	createBlockHashTable = "CREATE TABLE IF NOT EXISTS table_block_hash (" +
+		"contract_address VARCHAR(42) NOT NULL," +
+		"network VARCHAR(30) NOT NULL," +
+		"block_number INTEGER NOT NULL," +
+		"block_hash VARCHAR(66) NOT NULL," +
+		"added_on TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP," +
+		"PRIMARY KEY (network, contract_address, block_number))"
+
+	// createSyncStateTable is a prepared statement creating a table identified
+	// with the name sync_state if it doesn't exists. It holds the last block
+	// fully processed for each contract and network.
+	createSyncStateTable = "CREATE TABLE IF NOT EXISTS sync_state (" +
+		"contract_address VARCHAR(42) NOT NULL," +
+		"network VARCHAR(30) NOT NULL," +
+		"last_synced_block INTEGER NOT NULL," +
+		"last_update TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP," +
+		"PRIMARY KEY (contract_address, network))"
+
+	// createPendingTxTable is a prepared statement creating a table identified
+	// with the name pending_tx if it doesn't exists. It tracks the lifecycle
+	// of the transactions submitted via the server.
+	createPendingTxTable = "CREATE TABLE IF NOT EXISTS pending_tx (" +
+		"tx_hash VARCHAR(66) PRIMARY KEY," +
+		"sender VARCHAR(42) NOT NULL," +
+		"method VARCHAR(30) NOT NULL," +
+		"nonce BIGINT NOT NULL," +
+		"tx_status VARCHAR(10) NOT NULL DEFAULT 'pending'," +
+		"block_number INTEGER," +
+		"revert_reason TEXT," +
+		"bond_address VARCHAR(42)," +
+		"submitted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP," +
+		"last_update TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP," +
+		"org VARCHAR(64)," +
+		"call_data TEXT)"
+
+	// createTrustOrgTable is a prepared statement creating a table identified
+	// with the name table_trust_org if it doesn't exists. It holds the trust
+	// organisations vetting the users and their CA certificates.
+	createTrustOrgTable = "CREATE TABLE IF NOT EXISTS table_trust_org (" +
+		"org_name VARCHAR(64) PRIMARY KEY," +
+		"ca_cert TEXT NOT NULL," +
+		"org_status VARCHAR(10) NOT NULL DEFAULT 'active' " +
+		"CHECK (org_status IN ('active', 'suspended', 'revoked'))," +
+		"added_on TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP," +
+		"last_update TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP)"
+
+	// createOrgUserTable is a prepared statement creating a table identified
+	// with the name table_org_user if it doesn't exists. It holds the user
+	// addresses each trust organisation vouches for.
+	createOrgUserTable = "CREATE TABLE IF NOT EXISTS table_org_user (" +
+		"user_address VARCHAR(42) PRIMARY KEY," +
+		"org_name VARCHAR(64) NOT NULL REFERENCES table_trust_org (org_name)," +
+		"added_on TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP)"
+
+	// createReferralTable is a prepared statement creating a table identified
+	// with the name table_referral if it doesn't exists. It holds the new user
+	// addresses referred by the vetted users under their trust organisation.
+	createReferralTable = "CREATE TABLE IF NOT EXISTS table_referral (" +
+		"referee VARCHAR(42) PRIMARY KEY," +
+		"referrer VARCHAR(42) NOT NULL," +
+		"org_name VARCHAR(64) NOT NULL REFERENCES table_trust_org (org_name)," +
+		"added_on TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP)"
+
+	// createSessionTable is a prepared statement creating a table identified
+	// with the name table_session if it doesn't exists. It holds the sessions
+	// established by the POA clients. The shared key and the session token are
+	// stored encrypted.
+	createSessionTable = "CREATE TABLE IF NOT EXISTS table_session (" +
+		"sender VARCHAR(42) PRIMARY KEY," +
+		"pubkey TEXT NOT NULL," +
+		"shared_key TEXT NOT NULL," +
+		"session_token TEXT NOT NULL," +
+		"expiry TIMESTAMPTZ NOT NULL," +
+		"created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP)"
+
+	// createSessionChallengeTable is a prepared statement creating a table
+	// identified with the name table_session_challenge if it doesn't exists.
+	// It holds the session challenges pending the POA clients signatures, so
+	// that they can be signed on any server instance.
+	createSessionChallengeTable = "CREATE TABLE IF NOT EXISTS table_session_challenge (" +
+		"sender VARCHAR(42) PRIMARY KEY," +
+		"nonce VARCHAR(66) NOT NULL," +
+		"message TEXT NOT NULL," +
+		"expiry TIMESTAMPTZ NOT NULL)"
 
 	// fetchBonds is a prepared statement that fetches all the bonds that owned
 	// by the bond party with the address or they are still in the negotiation stage.
@@ -86,11 +194,20 @@ const (
 	// fetchBondByAddress is a prepared statement that returns a bond identified by
 	// the provided address if the sender is a party to the bond or the bond
 	// is still in the negotiation stage.
-	fetchBondByAddress = "SELECT bond_address,issuer_address,holder_address," +
-		"created_at,created_at_block,principal,coupon_rate,coupon_date," +
-		"maturity_date,currency,intro_msg,last_status,last_update,last_synced_block " +
-		"FROM table_bond WHERE bond_address = $1 AND " +
-		"(last_status = 0 OR issuer_address = $2 OR holder_address = $3)"
+	// The trust organisations vetting the bond parties are also returned.
+	fetchBondByAddress = "SELECT b.bond_address,b.issuer_address,b.holder_address," +
+		"b.created_at,b.created_at_block,b.principal,b.coupon_rate,b.coupon_date," +
+		"b.maturity_date,b.currency,b.intro_msg,b.last_status,b.last_update," +
+		"b.last_synced_block,io.org_name,ho.org_name FROM table_bond AS b " +
+		"LEFT JOIN table_org_user AS io ON io.user_address = b.issuer_address " +
+		"LEFT JOIN table_org_user AS ho ON ho.user_address = b.holder_address " +
+		"WHERE b.bond_address = $1 AND " +
+		"(b.last_status = 0 OR b.issuer_address = $2 OR b.holder_address = $3)"
+
+	// fetchBondParties returns the fields of the bond identified by the
+	// provided address that decide who can view it.
+	fetchBondParties = "SELECT issuer_address, holder_address, last_status " +
+		"FROM table_bond WHERE bond_address = $1"
 
 	// fetchChats is a prepared statement that fetches the conversation within
 	// the bond identified by the provided address if the sender is a bond party
@@ -105,9 +222,74 @@ const (
 	fetchTableVersion = "SELECT sem_version,tables_created_on " +
 		"FROM tables_version ORDER BY id DESC LIMIT 1"
 
-	// fetchLastSyncBlock returns the last block to be synced on the table_bond.
-	fetchLastSyncBlock = "SELECT last_synced_block FROM table_bond ORDER BY" +
-		" last_synced_block DESC LIMIT 1"
+	// fetchLastSyncBlock returns the last block fully processed for the
+	// provided contract and network.
+	fetchLastSyncBlock = "SELECT last_synced_block FROM sync_state " +
+		"WHERE contract_address = $1 AND network = $2"
+
+	// fetchTxStatus returns the transaction identified by the provided hash if
+	// it was submitted by the sender.
+	fetchTxStatus = "SELECT tx_hash, sender, method, nonce, tx_status, block_number, " +
+		"revert_reason, bond_address, submitted_at, last_update FROM pending_tx " +
+		"WHERE tx_hash = $1 AND sender = $2"
+
+	// fetchMyPendingTxs returns the sender's transactions yet to be mined.
+	fetchMyPendingTxs = "SELECT tx_hash, sender, method, nonce, tx_status, block_number, " +
+		"revert_reason, bond_address, submitted_at, last_update FROM pending_tx " +
+		"WHERE sender = $1 AND tx_status = 'pending' ORDER BY submitted_at DESC " +
+		"LIMIT $2 OFFSET $3"
+
+	// fetchPendingTxs returns the oldest transactions yet to be mined and the
+	// ones dropped within the provided number of seconds, together with the
+	// input they were submitted with.
+	fetchPendingTxs = "SELECT tx_hash, sender, method, nonce, tx_status, block_number, " +
+		"revert_reason, bond_address, submitted_at, last_update, call_data FROM pending_tx " +
+		"WHERE tx_status = 'pending' OR (tx_status = 'dropped' AND " +
+		"last_update > CURRENT_TIMESTAMP - $2 * INTERVAL '1 second') " +
+		"ORDER BY submitted_at LIMIT $1"
+
+	// fetchTrustOrgs returns all the registered trust organisations.
+	fetchTrustOrgs = "SELECT org_name, ca_cert, org_status, added_on, last_update " +
+		"FROM table_trust_org ORDER BY org_name"
+
+	// fetchTrustOrg returns the trust organisation with the provided name.
+	fetchTrustOrg = "SELECT org_name, ca_cert, org_status, added_on, last_update " +
+		"FROM table_trust_org WHERE org_name = $1"
+
+	// fetchUserOrg returns the trust organisation vouching for the user address.
+	fetchUserOrg = "SELECT o.org_name, o.ca_cert, o.org_status, o.added_on, " +
+		"o.last_update FROM table_org_user AS u INNER JOIN table_trust_org AS o " +
+		"ON u.org_name = o.org_name WHERE u.user_address = $1"
+
+	// fetchUserProfile returns the trust organisation vouching for the user,
+	// the user's referrer, the number of users referred and the outcomes of
+	// the bonds the user is a party to. Bonds that reached BondFinalised(6)
+	// are finalised while those in BondInDispute(2) never finalised are disputed.
+	fetchUserProfile = "WITH bonds AS (SELECT bond_address FROM table_bond " +
+		"WHERE issuer_address = $1 OR holder_address = $1), " +
+		"outcomes AS (SELECT s.bond_address, BOOL_OR(s.bond_status = 6) AS finalised, " +
+		"BOOL_OR(s.bond_status = 2) AS disputed FROM table_status AS s " +
+		"INNER JOIN bonds AS b ON s.bond_address = b.bond_address GROUP BY s.bond_address) " +
+		"SELECT (SELECT u.org_name FROM table_org_user AS u WHERE u.user_address = $1), " +
+		"(SELECT o.org_status FROM table_org_user AS u INNER JOIN table_trust_org AS o " +
+		"ON u.org_name = o.org_name WHERE u.user_address = $1), " +
+		"(SELECT referrer FROM table_referral WHERE referee = $1), " +
+		"(SELECT COUNT(*) FROM table_referral WHERE referrer = $1), " +
+		"(SELECT COUNT(*) FROM bonds), " +
+		"COUNT(*) FILTER (WHERE finalised), " +
+		"COUNT(*) FILTER (WHERE disputed AND NOT finalised) FROM outcomes"
+
+	// fetchSession returns the session established by the sender.
+	fetchSession = "SELECT pubkey, shared_key, session_token, expiry " +
+		"FROM table_session WHERE sender = $1"
+
+	// fetchSessionsCount returns the number of the sessions held.
+	fetchSessionsCount = "SELECT COUNT(*) FROM table_session"
+
+	// fetchBlockHashes returns the latest processed blocks hashes of the
+	// provided contract and network.
+	fetchBlockHashes = "SELECT block_number, block_hash FROM table_block_hash " +
+		"WHERE contract_address = $1 AND network = $2 ORDER BY block_number DESC LIMIT $3"
 
 	// setBondBodyTerms updates the table_bond with data from the BondBodyTerms event.
 	setBondBodyTerms = "UPDATE table_bond SET principal = $1, coupon_rate = $2, " +
@@ -126,29 +308,155 @@ const (
 	setLastStatus = "UPDATE table_bond SET last_status = $1, last_update = $2, " +
 		"last_synced_block = $3 WHERE bond_address = $4"
 
-	// addNewBondCreated inserts into table_bond new data from event NewBondCreated.
+	// addNewBondCreated inserts into table_bond new data from event NewBondCreated
+	// emitted by the provided contract and network. The trust organisation of
+	// the POA that submitted the creating tx is recorded if the tx was submitted
+	// via the server. Bonds already inserted are ignored.
 	addNewBondCreated = "INSERT INTO table_bond (bond_address, issuer_address, " +
-		"created_at_block, last_synced_block) VALUES ($1, $2, $3, $4)"
-
-	// ddNewChatMessage inserts into table_chat new data from event NewChatMessage.
+		"created_at_block, last_synced_block, org, contract_address, network) " +
+		"VALUES ($1, $2, $3, $4, (SELECT org FROM pending_tx WHERE tx_hash = $5), $6, $7) " +
+		"ON CONFLICT (bond_address) DO NOTHING"
+
+	// ddNewChatMessage inserts into table_chat new data from event NewChatMessage
+	// emitted by the provided contract and network. The trust organisation of
+	// the POA that submitted the tx is recorded if the tx was submitted via the
+	// server. Events already inserted are ignored.
 	addNewChatMessage = "INSERT INTO table_chat (sender, bond_address, " +
-		"chat_msg, last_synced_block) VALUES ($1, $2, $3, $4)"
+		"chat_msg, last_synced_block, tx_hash, log_index, org, contract_address, network) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, (SELECT org FROM pending_tx WHERE tx_hash = $5), $7, $8) " +
+		"ON CONFLICT (tx_hash, log_index) DO NOTHING"
 
-	// addStatusChange inserts into table_status new data from event StatusChange.
+	// addStatusChange inserts into table_status new data from event StatusChange
+	// emitted by the provided contract and network. Events already inserted are
+	// ignored.
 	addStatusChange = "INSERT INTO table_status (sender, bond_address, " +
-		"bond_status, last_synced_block) VALUES ($1, $2, $3, $4)"
+		"bond_status, last_synced_block, tx_hash, log_index, contract_address, network) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) " +
+		"ON CONFLICT (tx_hash, log_index) DO NOTHING"
 
-	// addStatusSigned inserts into table_status_signed new data from event StatusSigned.
+	// addStatusSigned inserts into table_status_signed new data from event
+	// StatusSigned emitted by the provided contract and network. Events already
+	// inserted are ignored.
 	addStatusSigned = "INSERT INTO table_status_signed (sender, bond_address, " +
-		"bond_status, last_synced_block) VALUES ($1, $2, $3, $4)"
+		"bond_status, last_synced_block, tx_hash, log_index, contract_address, network) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) " +
+		"ON CONFLICT (tx_hash, log_index) DO NOTHING"
+
+	// addBlockHash inserts into table_block_hash the hash of a block processed
+	// for the provided contract and network. If the block number already
+	// exists its hash is replaced.
+	addBlockHash = "INSERT INTO table_block_hash (contract_address, network, " +
+		"block_number, block_hash) VALUES ($1, $2, $3, $4) " +
+		"ON CONFLICT (network, contract_address, block_number) DO UPDATE SET " +
+		"block_hash = EXCLUDED.block_hash, added_on = CURRENT_TIMESTAMP"
+
+	// setSyncState sets into sync_state the last block fully processed for the
+	// provided contract and network.
+	setSyncState = "INSERT INTO sync_state (contract_address, network, " +
+		"last_synced_block) VALUES ($1, $2, $3) ON CONFLICT (contract_address, network) " +
+		"DO UPDATE SET last_synced_block = EXCLUDED.last_synced_block, " +
+		"last_update = CURRENT_TIMESTAMP"
+
+	// addPendingTx inserts into pending_tx a newly submitted transaction, the
+	// trust organisation of the POA that submitted it and its plain input if
+	// known. Transactions already inserted are ignored.
+	addPendingTx = "INSERT INTO pending_tx (tx_hash, sender, method, nonce, org, call_data) " +
+		"VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (tx_hash) DO NOTHING"
+
+	// setTxStatus updates the lifecycle status of a transaction in pending_tx.
+	setTxStatus = "UPDATE pending_tx SET tx_status = $1, block_number = $2, " +
+		"revert_reason = $3, bond_address = $4, last_update = CURRENT_TIMESTAMP " +
+		"WHERE tx_hash = $5"
+
+	// addTrustOrg inserts into table_trust_org a new trust organisation.
+	addTrustOrg = "INSERT INTO table_trust_org (org_name, ca_cert) VALUES ($1, $2)"
+
+	// setTrustOrgStatus updates the status of a trust organisation. Revoked
+	// organisations can't be updated.
+	setTrustOrgStatus = "UPDATE table_trust_org SET org_status = $1, " +
+		"last_update = CURRENT_TIMESTAMP WHERE org_name = $2 AND org_status <> 'revoked'"
+
+	// addOrgUser inserts into table_org_user the user address vouched for by
+	// the trust organisation. Users already vouched for are ignored.
+	addOrgUser = "INSERT INTO table_org_user (user_address, org_name) " +
+		"VALUES ($1, $2) ON CONFLICT (user_address) DO NOTHING"
+
+	// addReferral inserts into table_referral the new user address referred
+	// under the trust organisation. Users already referred are ignored.
+	addReferral = "INSERT INTO table_referral (referee, referrer, org_name) " +
+		"VALUES ($1, $2, $3) ON CONFLICT (referee) DO NOTHING"
+
+	// addSession inserts into table_session the sender's session. An existing
+	// session of the sender is replaced.
+	addSession = "INSERT INTO table_session (sender, pubkey, shared_key, " +
+		"session_token, expiry) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (sender) " +
+		"DO UPDATE SET pubkey = EXCLUDED.pubkey, shared_key = EXCLUDED.shared_key, " +
+		"session_token = EXCLUDED.session_token, expiry = EXCLUDED.expiry, " +
+		"created_at = CURRENT_TIMESTAMP"
+
+	// dropSession deletes the sender's session.
+	dropSession = "DELETE FROM table_session WHERE sender = $1"
+
+	// dropExpiredSessions deletes the sessions expired before the provided
+	// time and returns their senders.
+	dropExpiredSessions = "DELETE FROM table_session WHERE expiry < $1 RETURNING sender"
+
+	// addSessionChallenge inserts into table_session_challenge the sender's
+	// pending session challenge. An existing challenge of the sender is replaced.
+	addSessionChallenge = "INSERT INTO table_session_challenge (sender, nonce, " +
+		"message, expiry) VALUES ($1, $2, $3, $4) ON CONFLICT (sender) " +
+		"DO UPDATE SET nonce = EXCLUDED.nonce, message = EXCLUDED.message, " +
+		"expiry = EXCLUDED.expiry"
+
+	// takeSessionChallenge deletes the sender's session challenge with the
+	// provided nonce and returns it.
+	takeSessionChallenge = "DELETE FROM table_session_challenge WHERE sender = $1 " +
+		"AND nonce = $2 RETURNING message, expiry"
+
+	// dropExpiredChallenges deletes the session challenges expired before the
+	// provided time.
+	dropExpiredChallenges = "DELETE FROM table_session_challenge WHERE expiry < $1"
 
 	// addTablesVersion inserts into tables_version the latest supported tables version.
 	addTablesVersion = "INSERT INTO tables_version (sem_version) VALUES ($1)"
 
-	dropTableBondRecords         = "DELETE FROM table_bond WHERE last_synced_block = $1"
-	dropTableStatusRecords       = "DELETE FROM table_status WHERE last_synced_block = $1"
-	dropTableStatusSignedRecords = "DELETE FROM table_status_signed WHERE last_synced_block = $1"
-	dropTableChatRecords         = "DELETE FROM table_chat WHERE last_synced_block = $1"
+	// The dirty writes are only dropped for the provided contract and network.
+	dropTableBondRecords = "DELETE FROM table_bond WHERE last_synced_block = $1 " +
+		"AND contract_address = $2 AND network = $3"
+	dropTableStatusRecords = "DELETE FROM table_status WHERE last_synced_block = $1 " +
+		"AND contract_address = $2 AND network = $3"
+	dropTableStatusSignedRecords = "DELETE FROM table_status_signed WHERE last_synced_block = $1 " +
+		"AND contract_address = $2 AND network = $3"
+	dropTableChatRecords = "DELETE FROM table_chat WHERE last_synced_block = $1 " +
+		"AND contract_address = $2 AND network = $3"
+
+	// dropOldBlockHashes deletes the block hashes of the provided contract and
+	// network older than the provided block.
+	dropOldBlockHashes = "DELETE FROM table_block_hash WHERE contract_address = $1 " +
+		"AND network = $2 AND block_number < $3"
+
+	// fetchRevertedBondStart returns the oldest creation block of the bonds of
+	// the provided contract and network created upto the rollback block but
+	// updated after it.
+	fetchRevertedBondStart = "SELECT MIN(created_at_block) FROM table_bond " +
+		"WHERE created_at_block <= $1 AND last_synced_block > $1 " +
+		"AND contract_address = $2 AND network = $3"
+
+	// The records are only rolled back for the contract and network whose
+	// chain was reorganised.
+	rollbackTableBondRecords = "DELETE FROM table_bond WHERE last_synced_block > $1 " +
+		"AND contract_address = $2 AND network = $3"
+	rollbackTableStatusRecords = "DELETE FROM table_status WHERE last_synced_block > $1 " +
+		"AND contract_address = $2 AND network = $3"
+	rollbackTableStatusSignedRecords = "DELETE FROM table_status_signed WHERE last_synced_block > $1 " +
+		"AND contract_address = $2 AND network = $3"
+	rollbackTableChatRecords = "DELETE FROM table_chat WHERE last_synced_block > $1 " +
+		"AND contract_address = $2 AND network = $3"
+	rollbackTableBlockHashRecords = "DELETE FROM table_block_hash WHERE block_number > $1 " +
+		"AND contract_address = $2 AND network = $3"
+	rollbackSyncStateRecords = "UPDATE sync_state SET last_synced_block = $1, " +
+		"last_update = CURRENT_TIMESTAMP WHERE last_synced_block > $1 " +
+		"AND contract_address = $2 AND network = $3"
 )
 
 // tablesToSQLStmt is an array of sql statements used to create the missing tables
@@ -159,6 +467,14 @@ var tablesSQLStmt = []string{
 	createTableBondStatus,
 	createTableBondStatusSigned,
 	createChatTable,
+	createBlockHashTable,
+	createSyncStateTable,
+	createPendingTxTable,
+	createTrustOrgTable,
+	createOrgUserTable,
+	createReferralTable,
+	createSessionTable,
+	createSessionChallengeTable,
 }
 
 // This are clean up methods employed if corrupt or dirty writes are made at
@@ -170,14 +486,31 @@ var cleanUpStmt = []string{
 	dropTableChatRecords,
 }
 
+// rollbackStmt are the clean up methods employed to delete all the records
+// of a contract and network written after a certain block when a chain
+// reorganisation is detected.
+var rollbackStmt = []string{
+	rollbackTableBondRecords,
+	rollbackTableStatusRecords,
+	rollbackTableStatusSignedRecords,
+	rollbackTableChatRecords,
+	rollbackTableBlockHashRecords,
+	rollbackSyncStateRecords,
+}
+
 // reqToStmt matches the respective local type Methods supported to their sql queries.
 var reqToStmt = map[utils.Method]string{
 	utils.GetBonds:         fetchBonds,
 	utils.GetBondByAddress: fetchBondByAddress,
 	utils.GetChats:         fetchChats,
+	utils.GetTxStatus:      fetchTxStatus,
+	utils.GetMyPendingTxs:  fetchMyPendingTxs,
 
 	// method needed locally. Results are not sent via the server
 	utils.GetLastSyncedBlock: fetchLastSyncBlock,
+	utils.GetBlockHashes:     fetchBlockHashes,
+	utils.GetBondParties:     fetchBondParties,
+	utils.GetPendingTxs:      fetchPendingTxs,
 
 	utils.UpdateBondBodyTerms:  setBondBodyTerms,
 	utils.UpdateBondMotivation: setBondMotivation,
@@ -187,6 +520,29 @@ var reqToStmt = map[utils.Method]string{
 	utils.InsertNewChatMessage: addNewChatMessage,
 	utils.InsertStatusChange:   addStatusChange,
 	utils.InsertStatusSigned:   addStatusSigned,
+	utils.InsertBlockHash:      addBlockHash,
+	utils.UpdateSyncState:      setSyncState,
+	utils.InsertPendingTx:      addPendingTx,
+	utils.UpdateTxStatus:       setTxStatus,
+
+	utils.GetTrustOrgs:         fetchTrustOrgs,
+	utils.GetTrustOrg:          fetchTrustOrg,
+	utils.GetUserOrg:           fetchUserOrg,
+	utils.InsertTrustOrg:       addTrustOrg,
+	utils.UpdateTrustOrgStatus: setTrustOrgStatus,
+	utils.InsertOrgUser:        addOrgUser,
+	utils.GetUserProfile:       fetchUserProfile,
+	utils.InsertReferral:       addReferral,
+
+	utils.GetSession:            fetchSession,
+	utils.CountSessions:         fetchSessionsCount,
+	utils.InsertSession:         addSession,
+	utils.DeleteSession:         dropSession,
+	utils.DeleteExpiredSessions: dropExpiredSessions,
+
+	utils.InsertSessionChallenge:  addSessionChallenge,
+	utils.TakeSessionChallenge:    takeSessionChallenge,
+	utils.DeleteExpiredChallenges: dropExpiredChallenges,
 }
 
 // DB defines the parameters needed to use a persistence db instance connect to.
@@ -195,6 +551,19 @@ type DB struct {
 	ctx context.Context
 }
 
+// Batch defines a set of local data writes made within a single database
+// transaction. A batch must either be committed or rolled back.
+type Batch struct {
+	tx  *sql.Tx
+	ctx context.Context
+}
+
+// execer defines the method shared by the db instance and its transactions in
+// executing sql statements.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 // Reader defines the method that reads the row fields into the require data interface.
 // To read data, pass pointers to the expect field the parameter function.
 type Reader interface {
@@ -281,10 +650,18 @@ func (d *DB) QueryLocalData(method utils.Method, r Reader, sender string,
 		return nil, fmt.Errorf("missing query for method %q", method)
 	}
 
+	defer observeQuery(method, time.Now())
+
 	switch method {
 	case utils.GetBondByAddress:
 		params = append(params, []interface{}{sender, sender}...)
 
+	case utils.GetTxStatus:
+		params = append(params, sender)
+
+	case utils.GetMyPendingTxs:
+		params = append([]interface{}{sender}, params...)
+
 	case utils.GetBonds, utils.GetChats:
 		// The last two param values are always {limit, offset}.
 		// Append sender params before those two params.
@@ -322,12 +699,56 @@ func (d *DB) QueryLocalData(method utils.Method, r Reader, sender string,
 // SetLocalData inserts the provided data using the sql staements associated with
 // method param provided.
 func (d *DB) SetLocalData(method utils.Method, params ...interface{}) error {
+	return setLocalData(d.ctx, d.db, method, params...)
+}
+
+// NewBatch starts a database transaction where all the local data writes made
+// via the returned batch are either committed together or not at all.
+func (d *DB) NewBatch() (*Batch, error) {
+	tx, err := d.db.BeginTx(d.ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting the batch transaction failed: %v", err)
+	}
+
+	return &Batch{
+		tx:  tx,
+		ctx: d.ctx,
+	}, nil
+}
+
+// SetLocalData inserts the provided data using the sql staements associated with
+// method param provided within the batch transaction.
+func (b *Batch) SetLocalData(method utils.Method, params ...interface{}) error {
+	return setLocalData(b.ctx, b.tx, method, params...)
+}
+
+// Commit persists all the local data writes made via the batch.
+func (b *Batch) Commit() error {
+	if err := b.tx.Commit(); err != nil {
+		return fmt.Errorf("committing the batch transaction failed: %v", err)
+	}
+	return nil
+}
+
+// Rollback discards all the local data writes made via the batch. It is a
+// no-op if the batch has already been committed.
+func (b *Batch) Rollback() {
+	if err := b.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		log.Errorf("rolling back the batch transaction failed: %v", err)
+	}
+}
+
+// setLocalData executes the sql statement associated with the provided method
+// using the execer provided.
+func setLocalData(ctx context.Context, e execer, method utils.Method, params ...interface{}) error {
 	stmt, ok := reqToStmt[method]
 	if !ok {
 		return fmt.Errorf("missing query for method %q", method)
 	}
 
-	if _, err := d.db.ExecContext(d.ctx, stmt, params...); err != nil {
+	defer observeQuery(method, time.Now())
+
+	if _, err := e.ExecContext(ctx, stmt, params...); err != nil {
 		err = fmt.Errorf("inserting data for method %q failed: %v", method, err)
 		return err
 	}
@@ -335,13 +756,77 @@ func (d *DB) SetLocalData(method utils.Method, params ...interface{}) error {
 	return nil
 }
 
-// CleanUpLocalData removes any dirty writes that may have been written on a certain
-// last synced block.
-func (d *DB) CleanUpLocalData(lastSyncedBlock uint64) {
+// CleanUpLocalData removes any dirty writes of the provided contract and network
+// that may have been written on a certain last synced block.
+func (d *DB) CleanUpLocalData(lastSyncedBlock uint64, contract, network string) {
 	for _, stmt := range cleanUpStmt {
 		// if an error in one query occurs, do no stop.
-		if _, err := d.db.ExecContext(d.ctx, stmt, lastSyncedBlock); err != nil {
+		if _, err := d.db.ExecContext(d.ctx, stmt, lastSyncedBlock, contract, network); err != nil {
 			log.Errorf("query %q failed: %v", stmt, err)
 		}
 	}
 }
+
+// RollbackLocalData deletes all the records written after the provided common
+// ancestor block once a chain reorganisation is detected. Since the bonds
+// table only holds the latest bond state, bonds created before the ancestor
+// block but updated after it cannot be reverted in place. The rollback block
+// is moved back to just before such bonds were created so that they can be
+// rebuilt from scratch. Only the records of the provided contract and network
+// are rolled back. The block from which syncing should resume is returned.
+func (d *DB) RollbackLocalData(ancestorBlock uint64, contract, network string) (uint64, error) {
+	tx, err := d.db.BeginTx(d.ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("starting the rollback transaction failed: %v", err)
+	}
+
+	// Rollback is a no-op if the transaction has already been committed.
+	defer tx.Rollback()
+
+	rollbackBlock := ancestorBlock
+	for {
+		var createdAt sql.NullInt64
+		err = tx.QueryRowContext(d.ctx, fetchRevertedBondStart, rollbackBlock,
+			contract, network).Scan(&createdAt)
+		if err != nil {
+			return 0, fmt.Errorf("fetching the reverted bonds failed: %v", err)
+		}
+
+		if !createdAt.Valid {
+			break
+		}
+
+		// Move the rollback block before the reverted bond was created.
+		rollbackBlock = uint64(createdAt.Int64) - 1
+	}
+
+	for _, stmt := range rollbackStmt {
+		if _, err := tx.ExecContext(d.ctx, stmt, rollbackBlock, contract, network); err != nil {
+			return 0, fmt.Errorf("query %q failed: %v", stmt, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing the rollback transaction failed: %v", err)
+	}
+
+	log.Infof("Rolled back local data written after block=%d", rollbackBlock)
+
+	return rollbackBlock, nil
+}
+
+// Ping confirms that the connection to the db is still alive.
+func (d *DB) Ping() error {
+	return d.db.PingContext(d.ctx)
+}
+
+// PruneBlockHashes deletes the processed block hashes of the provided contract
+// and network older than the provided block since they are too deep to be
+// affected by a chain reorganisation.
+func (d *DB) PruneBlockHashes(contract, network string, blockNo uint64) error {
+	if _, err := d.db.ExecContext(d.ctx, dropOldBlockHashes, contract, network, blockNo); err != nil {
+		return fmt.Errorf("pruning block hashes older than block %d failed: %v",
+			blockNo, err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -14,6 +15,7 @@ import (
 	"github.com/dmigwi/dhamana-protocol/client/servertypes"
 	"github.com/dmigwi/dhamana-protocol/client/utils"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/lib/pq"
 )
 
@@ -104,6 +106,9 @@ func TestMain(m *testing.M) {
 
 // insertTestData inserts sample data into the tables.
 func insertTestData() error {
+	// txHash is the transaction hash shared by the sample events.
+	txHash := "0x6f20ecf64482f580f1ab39df75cc14f6d5ceb9d878094d7c2970656c3561a9e1"
+
 	tableBondStmt := "INSERT INTO table_bond (" +
 		"bond_address, issuer_address, holder_address, created_at_block, " +
 		"principal, coupon_rate, coupon_date, maturity_date, currency, " +
@@ -147,58 +152,68 @@ func insertTestData() error {
 	}
 
 	tableStatusStmt := "INSERT INTO table_status(" +
-		"sender, bond_address, bond_status, last_synced_block" +
-		") VALUES ($1, $2, $3, $4)"
+		"sender, bond_address, bond_status, last_synced_block, tx_hash, log_index" +
+		") VALUES ($1, $2, $3, $4, $5, $6)"
 
 	tableStatusData := [][]interface{}{
 		{ // Data when on setting holder address during status HolderUpdate. Update made by the bond Issuer.
 			"0xf977814e90da44bfa03b6295a0616a897441aadd", // sender
 			"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756dba", // bond_address
-			1, 76,
+			1, 76, txHash, 0,
 		},
 		{ // Data when the bond moved to status TermsAgreement. Update made by the bond holder.
 			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod",
 			"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756dba",
-			2, 80,
+			2, 80, txHash, 1,
 		},
 		{ // Data when the bond moved to status bondInDiputed. Update made by the bond Issuer.
 			"0xf977814e90da44bfa03b6295a0616a897441aadd",
 			"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756dba",
-			3, 85,
+			3, 85, txHash, 2,
 		},
 	}
 
 	tableStatusSignedStmt := "INSERT INTO table_status_signed(" +
-		"sender, bond_address, bond_status, last_synced_block" +
-		") VALUES ($1, $2, $3, $4)"
+		"sender, bond_address, bond_status, last_synced_block, tx_hash, log_index" +
+		") VALUES ($1, $2, $3, $4, $5, $6)"
 
 	tableStatusSignedData := [][]interface{}{
 		{ // Data when the bond Holder signed status bondInDiputed. Sent by the bond Holder.
 			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // sender
 			"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756dba", // bond_address
-			3, 89,
+			3, 89, txHash, 3,
 		},
 	}
 
 	tableChatStmt := "INSERT INTO table_chat(" +
-		"sender, bond_address, chat_msg, last_synced_block" +
-		") VALUES ($1, $2, $3, $4)"
+		"sender, bond_address, chat_msg, last_synced_block, tx_hash, log_index" +
+		") VALUES ($1, $2, $3, $4, $5, $6)"
 
 	tableChatData := [][]interface{}{
 		{ // Data when a potential bond Holder expressed interest. Sent by the bond Holder.
 			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // sender
 			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507567845", // bond_address
-			"xxxxxxx-encrypted", 74,
+			"xxxxxxx-encrypted", 74, txHash, 4,
 		},
 		{ // Data when the bond Holder accepted the Issuer bond terms. Sent by the bond Holder.
 			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod",
 			"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756dba",
-			"xxxxxxx-encrypted", 76,
+			"xxxxxxx-encrypted", 76, txHash, 5,
 		},
 		{ // Data when the bond Issuer explain why they moved the bond to status bondInDiputed.
 			"0xf977814e90da44bfa03b6295a0616a897441aadd",
 			"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756dba",
-			"uywteuyrw ddhhdyugdhjdna", 90,
+			"uywteuyrw ddhhdyugdhjdna", 90, txHash, 6,
+		},
+	}
+
+	syncStateStmt := "INSERT INTO sync_state(" +
+		"contract_address, network, last_synced_block) VALUES ($1, $2, $3)"
+
+	syncStateData := [][]interface{}{
+		{
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", // contract_address
+			"sapphirelocalnet", 89,
 		},
 	}
 
@@ -207,6 +222,7 @@ func insertTestData() error {
 		tableStatusStmt:       tableStatusData,
 		tableStatusSignedStmt: tableStatusSignedData,
 		tableChatStmt:         tableChatData,
+		syncStateStmt:         syncStateData,
 	}
 
 	for query, data := range tablesdata {
@@ -354,6 +370,25 @@ func TestQueryLocalData(t *testing.T) {
 		compare((*res).MaturityDate, dataExp.MaturityDate)
 	})
 
+	t.Run("Test GetBondParties result", func(t *testing.T) {
+		data, err := db.QueryLocalData(utils.GetBondParties, new(bondParties), "",
+			"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756dba")
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 1 {
+			t.Fatalf("expected one record returned but found %v records", len(data))
+		}
+
+		res := data[0].(*bondParties)
+		if common.HexToAddress(res.issuer) != dataExp.Issuer ||
+			common.HexToAddress(res.holder) != dataExp.Holder {
+			t.Fatalf("expected bond parties %v and %v but found %v and %v",
+				dataExp.Issuer, dataExp.Holder, res.issuer, res.holder)
+		}
+	})
+
 	chatsExp := []servertypes.ChatMsgsResp{
 		{
 			Sender:          common.HexToAddress("0xf977814e90da44bfa03b6295a0616a897441aadd"),
@@ -404,7 +439,8 @@ func TestQueryLocalData(t *testing.T) {
 	blockExp := servertypes.LastSyncedBlockResp(89)
 
 	t.Run("Test GetLastSyncedBlock result", func(t *testing.T) {
-		data, err := db.QueryLocalData(utils.GetLastSyncedBlock, new(servertypes.LastSyncedBlockResp), "")
+		data, err := db.QueryLocalData(utils.GetLastSyncedBlock, new(servertypes.LastSyncedBlockResp), "",
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphirelocalnet")
 		if err != nil {
 			t.Fatalf("expected no error but found: %v", err)
 		}
@@ -428,24 +464,39 @@ func TestSetLocalData(t *testing.T) {
 			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // issuer_address
 			80,  // created_at_block
 			100, // last_synced_block
+			"0x9a1b3c07e7a0b2d6d7b4f0e1d3c2b1a09f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c", // tx_hash
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd",                         // contract_address
+			"sapphiretestnet", // network
 		},
 		utils.InsertNewChatMessage: {
 			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // sender
 			"0xc61b9bb3a7a0767e317971000000000000001dbd", // bond_address
 			"yuqteuqteuqeqe", // chat_msg
 			120,              // last_synced_block
+			"0x9a1b3c07e7a0b2d6d7b4f0e1d3c2b1a09f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c", // tx_hash
+			0, // log_index
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", // contract_address
+			"sapphiretestnet", // network
 		},
 		utils.InsertStatusChange: {
 			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // sender
 			"0xc61b9bb3a7a0767e317971000000000000001dbd", // bond_address
 			1,   // bond_status
 			120, // last_synced_block
+			"0x9a1b3c07e7a0b2d6d7b4f0e1d3c2b1a09f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c", // tx_hash
+			1, // log_index
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", // contract_address
+			"sapphiretestnet", // network
 		},
 		utils.InsertStatusSigned: {
 			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // sender
 			"0xc61b9bb3a7a0767e317971000000000000001dbd", // bond_address
 			3,   // bond_status
 			120, // last_synced_block
+			"0x9a1b3c07e7a0b2d6d7b4f0e1d3c2b1a09f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c", // tx_hash
+			2, // log_index
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", // contract_address
+			"sapphiretestnet", // network
 		},
 		utils.UpdateBondBodyTerms: {
 			41564316,                        // principal
@@ -475,6 +526,11 @@ func TestSetLocalData(t *testing.T) {
 			120,                             // last_synced_block
 			"0xc61b9bb3a7a0767e317971000000000000001dbd", // bond_address
 		},
+		utils.UpdateSyncState: {
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", // contract_address
+			"sapphiretestnet", // network
+			120,               // last_synced_block
+		},
 	}
 
 	for mthd, td := range testData {
@@ -485,24 +541,437 @@ func TestSetLocalData(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("Test duplicate events insert", func(t *testing.T) {
+		td := testData[utils.InsertNewChatMessage]
+		if err := db.SetLocalData(utils.InsertNewChatMessage, td...); err != nil {
+			t.Fatal(err)
+		}
+
+		var count int
+		err := db.db.QueryRow("SELECT COUNT(*) FROM table_chat WHERE tx_hash = $1",
+			td[4]).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if count != 1 {
+			t.Fatalf("expected one chat record for the event but found %d", count)
+		}
+	})
+}
+
+// TestPendingTx tests the tracking of the submitted transactions lifecycle.
+func TestPendingTx(t *testing.T) {
+	txHash := "0x8ad6c1e45c0b2ab1fa9e3a19ef4dd7e9b3d5c5d90e1b1e6e6a8b7c2e38e8c9a1"
+	sender := "0x43a4d5d40e2f2a3ad9b3cd6ca9a0bb0c3d8c4b11"
+	bondAddress := "0x5b9e3e9e6d7d7f2a4d1aa2a8ee1bda2a0d2a9c11"
+
+	err := db.SetLocalData(utils.InsertPendingTx, txHash, sender, string(utils.CreateBond), 7,
+		"Sample Trust Org", "0x2b9e4b5f")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Test GetMyPendingTxs result", func(t *testing.T) {
+		data, err := db.QueryLocalData(utils.GetMyPendingTxs, new(servertypes.TxStatusResp),
+			sender, 10, 0)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 1 {
+			t.Fatalf("expected one record returned but found %v records", len(data))
+		}
+
+		res := data[0].(*servertypes.TxStatusResp)
+		if res.Status != servertypes.TxPending || res.Nonce != 7 {
+			t.Fatalf("expected pending tx with nonce 7 but found %s tx with nonce %d",
+				res.Status, res.Nonce)
+		}
+	})
+
+	t.Run("Test GetTxStatus of another sender", func(t *testing.T) {
+		data, err := db.QueryLocalData(utils.GetTxStatus, new(servertypes.TxStatusResp),
+			"0x0000000000000000000000000000000000000001", txHash)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 0 {
+			t.Fatalf("expected no records returned but found %v records", len(data))
+		}
+	})
+
+	t.Run("Test GetTxStatus of a mined tx", func(t *testing.T) {
+		err := db.SetLocalData(utils.UpdateTxStatus, string(servertypes.TxMined), 120,
+			nil, bondAddress, txHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := db.QueryLocalData(utils.GetTxStatus, new(servertypes.TxStatusResp),
+			sender, txHash)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 1 {
+			t.Fatalf("expected one record returned but found %v records", len(data))
+		}
+
+		res := data[0].(*servertypes.TxStatusResp)
+		if res.Status != servertypes.TxMined || res.BlockNo != 120 {
+			t.Fatalf("expected tx mined at block 120 but found %s tx at block %d",
+				res.Status, res.BlockNo)
+		}
+
+		if res.BondAddress == nil || *res.BondAddress != common.HexToAddress(bondAddress) {
+			t.Fatalf("expected bond address %s but found %v", bondAddress, res.BondAddress)
+		}
+
+		data, err = db.QueryLocalData(utils.GetMyPendingTxs, new(servertypes.TxStatusResp),
+			sender, 10, 0)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 0 {
+			t.Fatalf("expected no pending records returned but found %v records", len(data))
+		}
+	})
+
+	t.Run("Test GetPendingTxs of a dropped tx", func(t *testing.T) {
+		droppedHash := "0x9ad6c1e45c0b2ab1fa9e3a19ef4dd7e9b3d5c5d90e1b1e6e6a8b7c2e38e8c9a2"
+		err := db.SetLocalData(utils.InsertPendingTx, droppedHash, sender,
+			string(utils.CreateBond), 8, nil, "0x2b9e4b5f")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.SetLocalData(utils.UpdateTxStatus, string(servertypes.TxDropped), nil,
+			nil, nil, droppedHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := db.QueryLocalData(utils.GetPendingTxs, new(servertypes.TrackedTxResp),
+			"", 10, 60)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 1 {
+			t.Fatalf("expected one record returned but found %v records", len(data))
+		}
+
+		res := data[0].(*servertypes.TrackedTxResp)
+		if res.Status != servertypes.TxDropped || hexutil.Encode(res.CallData) != "0x2b9e4b5f" {
+			t.Fatalf("expected dropped tx with call data 0x2b9e4b5f but found %s tx with %x",
+				res.Status, res.CallData)
+		}
+
+		// Dropped txs are no longer checked once the re-poll window elapses.
+		data, err = db.QueryLocalData(utils.GetPendingTxs, new(servertypes.TrackedTxResp),
+			"", 10, 0)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 0 {
+			t.Fatalf("expected no records returned but found %v records", len(data))
+		}
+	})
+}
+
+// bondParties holds the bond parties fields read. The server package reading
+// them can't be imported here.
+type bondParties struct {
+	issuer, holder string
+	lastStatus     uint8
+}
+
+// Read is the reader interface implementation for type bondParties.
+func (p *bondParties) Read(fn func(fields ...any) error) (interface{}, error) {
+	var resp bondParties
+	err := fn(&resp.issuer, &resp.holder, &resp.lastStatus)
+	return &resp, err
+}
+
+// userProfile holds the user profile fields read. The trustorg package
+// reading them can't be imported here.
+type userProfile struct {
+	org, orgStatus, referrer sql.NullString
+
+	referrals, bonds, finalised, disputed uint64
+}
+
+// Read is the reader interface implementation for type userProfile.
+func (u *userProfile) Read(fn func(fields ...any) error) (interface{}, error) {
+	var p userProfile
+	err := fn(&p.org, &p.orgStatus, &p.referrer, &p.referrals, &p.bonds,
+		&p.finalised, &p.disputed)
+	return &p, err
+}
+
+// TestUserProfile tests the referrals and the bond outcomes returned in the
+// users profiles.
+func TestUserProfile(t *testing.T) {
+	org := "Sample Trust Org"
+	issuer := "0xf977814e90da44bfa03b6295a0616a897441aadd"
+	referee := "0x2b6ed29a95753c3ad948348e3e7b1a2510800001"
+
+	if err := db.SetLocalData(utils.InsertTrustOrg, org, "-----BEGIN CERTIFICATE-----"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.SetLocalData(utils.InsertOrgUser, issuer, org); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.SetLocalData(utils.InsertReferral, referee, issuer, org); err != nil {
+		t.Fatal(err)
+	}
+
+	// A referee already referred keeps their first referral.
+	if err := db.SetLocalData(utils.InsertReferral, referee, "0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", org); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Test GetUserProfile of the bond issuer", func(t *testing.T) {
+		data, err := db.QueryLocalData(utils.GetUserProfile, new(userProfile), "", issuer)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 1 {
+			t.Fatalf("expected one record returned but found %v records", len(data))
+		}
+
+		// The issuer's only bond was disputed and never finalised.
+		res := data[0].(*userProfile)
+		if res.org.String != org || res.orgStatus.String != "active" || res.referrals != 1 ||
+			res.bonds != 1 || res.finalised != 0 || res.disputed != 1 {
+			t.Fatalf("unexpected profile %+v returned", *res)
+		}
+	})
+
+	t.Run("Test GetUserProfile of the referee", func(t *testing.T) {
+		data, err := db.QueryLocalData(utils.GetUserProfile, new(userProfile), "", referee)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 1 {
+			t.Fatalf("expected one record returned but found %v records", len(data))
+		}
+
+		res := data[0].(*userProfile)
+		if res.referrer.String != issuer {
+			t.Fatalf("expected the referee to be referred by %s but found %q",
+				issuer, res.referrer.String)
+		}
+
+		if res.org.Valid || res.bonds != 0 {
+			t.Fatalf("expected no organisation and bonds but found %q and %d",
+				res.org.String, res.bonds)
+		}
+	})
+}
+
+// sessionSender reads the sender of a purged session.
+type sessionSender string
+
+// Read is the reader interface implementation for type sessionSender.
+func (s *sessionSender) Read(fn func(fields ...any) error) (interface{}, error) {
+	var sender sessionSender
+	err := fn(&sender)
+	return &sender, err
+}
+
+// TestSessions tests the replacement and the purging of the persisted sessions.
+func TestSessions(t *testing.T) {
+	sender := "0x2b6ed29a95753c3ad948348e3e7b1a2510800002"
+	expiry := time.Now().UTC().Add(-time.Minute)
+
+	if err := db.SetLocalData(utils.InsertSession, sender, "pubkey", "key", "token", expiry); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new session of the sender replaces the existing one.
+	if err := db.SetLocalData(utils.InsertSession, sender, "pubkey", "key", "token-2", expiry); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Test DeleteExpiredSessions result", func(t *testing.T) {
+		data, err := db.QueryLocalData(utils.DeleteExpiredSessions, new(sessionSender), "",
+			time.Now().UTC())
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		if len(data) != 1 || string(*data[0].(*sessionSender)) != sender {
+			t.Fatalf("expected the session of %s to be purged but found %d records",
+				sender, len(data))
+		}
+
+		var count int
+		err = db.db.QueryRow("SELECT COUNT(*) FROM table_session WHERE sender = $1",
+			sender).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if count != 0 {
+			t.Fatalf("expected no sessions of %s but found %d", sender, count)
+		}
+	})
+}
+
+// challengeMessage is the reader of the message of a session challenge taken.
+type challengeMessage string
+
+// Read is the reader interface implementation for type challengeMessage.
+func (c *challengeMessage) Read(fn func(fields ...any) error) (interface{}, error) {
+	var message challengeMessage
+	var expiry time.Time
+	err := fn(&message, &expiry)
+	return &message, err
+}
+
+// TestSessionChallenges tests that the pending session challenges are replaced
+// by the new ones and can only be taken once.
+func TestSessionChallenges(t *testing.T) {
+	sender := "0x2b6ed29a95753c3ad948348e3e7b1a2510800003"
+	expiry := time.Now().UTC().Add(time.Minute)
+
+	if err := db.SetLocalData(utils.InsertSessionChallenge, sender, "0x01", "message-1", expiry); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new challenge of the sender replaces the pending one.
+	if err := db.SetLocalData(utils.InsertSessionChallenge, sender, "0x02", "message-2", expiry); err != nil {
+		t.Fatal(err)
+	}
+
+	td := []struct {
+		testName string
+		nonce    string
+		message  string
+	}{
+		{"Test-for-replaced-challenge", "0x01", ""},
+		{"Test-for-pending-challenge", "0x02", "message-2"},
+		{"Test-for-taken-challenge", "0x02", ""},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			data, err := db.QueryLocalData(utils.TakeSessionChallenge, new(challengeMessage), "",
+				sender, v.nonce)
+			if err != nil {
+				t.Fatalf("expected no error but found: %v", err)
+			}
+
+			var message string
+			if len(data) > 0 {
+				message = string(*data[0].(*challengeMessage))
+			}
+
+			if message != v.message {
+				t.Fatalf("expected the challenge message %q but found %q", v.message, message)
+			}
+		})
+	}
+}
+
+// TestBatch tests if the batch writes are persisted only once committed and
+// discarded entirely if rolled back.
+func TestBatch(t *testing.T) {
+	bondAddress := "0xc61b9bb3a7a0767e317971000000000000004dbd"
+	countBonds := func() (count int) {
+		err := db.db.QueryRow("SELECT COUNT(*) FROM table_bond WHERE bond_address = $1",
+			bondAddress).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	writeBatch := func() *Batch {
+		batch, err := db.NewBatch()
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		err = batch.SetLocalData(utils.InsertNewBondCreated, bondAddress,
+			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", 900, 900,
+			"0x4c1e8a7f2d3b5c6e9a0f1d2c3b4a5e6f7d8c9b0a1e2f3d4c5b6a7e8f9d0c1b2a",
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiretestnet")
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+		return batch
+	}
+
+	t.Run("Test Batch Rollback", func(t *testing.T) {
+		batch := writeBatch()
+
+		// A failed write must not persist any of the batch's earlier writes.
+		err := batch.SetLocalData(utils.UpdateLastStatus, 100,
+			"2023-09-01 02:45:00.501361+03", 900, bondAddress)
+		if err == nil {
+			t.Fatal("expected an error but found none")
+		}
+
+		batch.Rollback()
+
+		if count := countBonds(); count != 0 {
+			t.Fatalf("expected no bond to be persisted but found %d", count)
+		}
+	})
+
+	t.Run("Test Batch Commit", func(t *testing.T) {
+		batch := writeBatch()
+
+		if count := countBonds(); count != 0 {
+			t.Fatalf("expected no bond to be persisted before commit but found %d", count)
+		}
+
+		if err := batch.Commit(); err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		// Rollback after a commit is a no-op.
+		batch.Rollback()
+
+		if count := countBonds(); count != 1 {
+			t.Fatalf("expected the bond to be persisted but found %d", count)
+		}
+	})
 }
 
+// fetchMaxBondSyncedBlock returns the last block synced on the table_bond and
+// the contract and network it was synced for.
+const fetchMaxBondSyncedBlock = "SELECT last_synced_block, contract_address, network " +
+	"FROM table_bond ORDER BY last_synced_block DESC LIMIT 1"
+
 // TestCleanUpLocalData test if records on a certain synced block can be deleted
 // on all tables.
 func TestCleanUpLocalData(t *testing.T) {
 	t.Run("Test CleanUpLocalData", func(t *testing.T) {
 		var lastSyncedBlock uint64
+		var contract, network string
 
-		err := db.db.QueryRow(fetchLastSyncBlock).Scan(&lastSyncedBlock)
+		err := db.db.QueryRow(fetchMaxBondSyncedBlock).Scan(&lastSyncedBlock, &contract, &network)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		db.CleanUpLocalData(lastSyncedBlock)
+		db.CleanUpLocalData(lastSyncedBlock, contract, network)
 
 		var newLastSyncedBlock uint64
 
-		err = db.db.QueryRow(fetchLastSyncBlock).Scan(&newLastSyncedBlock)
+		err = db.db.QueryRow(fetchMaxBondSyncedBlock).Scan(&newLastSyncedBlock, &contract, &network)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -513,3 +982,154 @@ func TestCleanUpLocalData(t *testing.T) {
 		}
 	})
 }
+
+// TestRollbackLocalData tests if the records written after the common ancestor
+// block are deleted and bonds updated after it are rebuilt from scratch.
+func TestRollbackLocalData(t *testing.T) {
+	testData := []struct {
+		method utils.Method
+		params []interface{}
+	}{
+		{utils.InsertNewBondCreated, []interface{}{
+			"0xc61b9bb3a7a0767e317971000000000000002dbd", // bond_address
+			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // issuer_address
+			1000, 1000,
+			"0x1d5f0b3c8e2a4f6d9b7c0e1a2d3f4b5c6e7a8d9f0b1c2e3d4f5a6b7c8d9e0f1a",
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiremainnet",
+		}},
+		{utils.InsertNewBondCreated, []interface{}{
+			"0xc61b9bb3a7a0767e317971000000000000003dbd", // bond_address
+			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // issuer_address
+			1010, 1010,
+			"0x2e6a1c4d9f3b5a7e0c8d1f2b3e4a5c6d7f8b9e0a1c2d3f4e5b6a7c8d9f0e1b2c",
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiremainnet",
+		}},
+		{utils.UpdateLastStatus, []interface{}{
+			1, "2023-09-01 02:45:00.501361+03", 1030,
+			"0xc61b9bb3a7a0767e317971000000000000003dbd", // bond_address
+		}},
+		{utils.InsertNewChatMessage, []interface{}{
+			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // sender
+			"0xc61b9bb3a7a0767e317971000000000000003dbd", // bond_address
+			"yuqteuqteuqeqe", 1030,
+			"0x6f20ecf64482f580f1ab39df75cc14f6d5ceb9d878094d7c2970656c3561a9e1", 0,
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiremainnet",
+		}},
+		// The records of another contract sharing the db are not rolled back.
+		{utils.InsertNewBondCreated, []interface{}{
+			"0xc61b9bb3a7a0767e317971000000000000005dbd", // bond_address
+			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // issuer_address
+			1010, 1030,
+			"0x3f7b2d5e0a4c6b8f1d9e2a3c4f5b6d7e8a9c0f1b2d3e4a5f6c7b8d9e0a1f2c3d",
+			"0x9c3e1f7a6b5d4c3b2a1f0e9d8c7b6a5f4e3d2c1b", "sapphiremainnet",
+		}},
+		{utils.InsertNewChatMessage, []interface{}{
+			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // sender
+			"0xc61b9bb3a7a0767e317971000000000000005dbd", // bond_address
+			"yuqteuqteuqeqe", 1030,
+			"0x4a8c3e6f1b5d7c9a2e0f3b4d5a6c7e8f9b0d1a2c3e4f5b6d7a8c9e0f1b2d3a4e", 0,
+			"0x9c3e1f7a6b5d4c3b2a1f0e9d8c7b6a5f4e3d2c1b", "sapphiremainnet",
+		}},
+		{utils.InsertStatusChange, []interface{}{
+			"0x47ac0fb4f2d84898e4d9e7b4dab3c24507a6dbod", // sender
+			"0xc61b9bb3a7a0767e317971000000000000005dbd", // bond_address
+			1, 1030,
+			"0x4a8c3e6f1b5d7c9a2e0f3b4d5a6c7e8f9b0d1a2c3e4f5b6d7a8c9e0f1b2d3a4e", 1,
+			"0x9c3e1f7a6b5d4c3b2a1f0e9d8c7b6a5f4e3d2c1b", "sapphiremainnet",
+		}},
+		{utils.InsertBlockHash, []interface{}{
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiremainnet",
+			1030, "0x6f20ecf64482f580f1ab39df75cc14f6d5ceb9d878094d7c2970656c3561a9e1",
+		}},
+		{utils.UpdateSyncState, []interface{}{
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiremainnet", 1030,
+		}},
+		// The sync records of another network are not rolled back.
+		{utils.InsertBlockHash, []interface{}{
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiretestnet",
+			1030, "0x6f20ecf64482f580f1ab39df75cc14f6d5ceb9d878094d7c2970656c3561a9e1",
+		}},
+		{utils.UpdateSyncState, []interface{}{
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiretestnet", 1030,
+		}},
+	}
+
+	for _, td := range testData {
+		if err := db.SetLocalData(td.method, td.params...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Test RollbackLocalData", func(t *testing.T) {
+		rollbackBlock, err := db.RollbackLocalData(1020,
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiremainnet")
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		// Bond created on block 1010 was updated after the common ancestor block.
+		if rollbackBlock != 1009 {
+			t.Fatalf("expected rollback block 1009 but found %d", rollbackBlock)
+		}
+
+		var count int
+		err = db.db.QueryRow("SELECT COUNT(*) FROM table_bond WHERE bond_address = $1",
+			"0xc61b9bb3a7a0767e317971000000000000002dbd").Scan(&count)
+		if err != nil || count != 1 {
+			t.Fatalf("expected bond created before the rollback block to exist, err: %v", err)
+		}
+
+		err = db.db.QueryRow("SELECT COUNT(*) FROM table_chat WHERE last_synced_block > $1 "+
+			"AND contract_address = $2", rollbackBlock,
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd").Scan(&count)
+		if err != nil || count != 0 {
+			t.Fatalf("expected chats after the rollback block to be deleted, err: %v", err)
+		}
+
+		data, err := db.QueryLocalData(utils.GetLastSyncedBlock, new(servertypes.LastSyncedBlockResp), "",
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiremainnet")
+		if err != nil || len(data) != 1 {
+			t.Fatalf("expected the sync state to be returned, err: %v", err)
+		}
+
+		if block := uint64(*data[0].(*servertypes.LastSyncedBlockResp)); block != rollbackBlock {
+			t.Fatalf("expected sync state block %d but found %d", rollbackBlock, block)
+		}
+
+		data, err = db.QueryLocalData(utils.GetBlockHashes, new(servertypes.BlockHashResp), "",
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiremainnet", 1)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		for _, d := range data {
+			if d.(*servertypes.BlockHashResp).BlockNo > rollbackBlock {
+				t.Fatal("expected block hashes after the rollback block to be deleted")
+			}
+		}
+
+		data, err = db.QueryLocalData(utils.GetLastSyncedBlock, new(servertypes.LastSyncedBlockResp), "",
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiretestnet")
+		if err != nil || len(data) != 1 {
+			t.Fatalf("expected the sync state to be returned, err: %v", err)
+		}
+
+		if block := uint64(*data[0].(*servertypes.LastSyncedBlockResp)); block != 1030 {
+			t.Fatalf("expected the other network sync state block 1030 but found %d", block)
+		}
+
+		for _, table := range []string{"table_bond", "table_chat", "table_status"} {
+			err = db.db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE bond_address = $1",
+				"0xc61b9bb3a7a0767e317971000000000000005dbd").Scan(&count)
+			if err != nil || count != 1 {
+				t.Fatalf("expected the other contract %s record to be kept, err: %v", table, err)
+			}
+		}
+
+		data, err = db.QueryLocalData(utils.GetBlockHashes, new(servertypes.BlockHashResp), "",
+			"0x2b6ed29a95753c3ad948348e3e7b1a251080fadd", "sapphiretestnet", 1)
+		if err != nil || len(data) != 1 {
+			t.Fatalf("expected the other network block hash to be kept, err: %v", err)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// queryDuration tracks the latency of the sql statements executed per method.
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "dhamana",
+	Subsystem: "db",
+	Name:      "query_duration_seconds",
+	Help:      "Duration of the sql statements executed per method.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method"})
+
+// observeQuery records the duration of the sql statement executed for the
+// provided method.
+func observeQuery(method utils.Method, start time.Time) {
+	queryDuration.WithLabelValues(string(method)).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package trustorg
+
+import (
+	"database/sql"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// vettedScore is earned by the users vouched for by an active trust
+	// organisation.
+	vettedScore = 30
+
+	// referredScore is earned by the users referred by a vetted user.
+	referredScore = 10
+
+	// referralScore is earned for every user referred, upto maxReferralsScore.
+	referralScore     = 2
+	maxReferralsScore = 20
+
+	// maxBondsScore is earned by the users whose bonds were all finalised.
+	// The users without finalised or disputed bonds earn none of it.
+	maxBondsScore = 40
+)
+
+// Profile defines the trust earned by a user through the trust organisation
+// vouching for them, the referrals and the outcomes of the bonds they are a
+// party to.
+type Profile struct {
+	Address    common.Address  `json:"address"`
+	Org        string          `json:"org,omitempty"`
+	OrgStatus  Status          `json:"org_status,omitempty"`
+	ReferredBy *common.Address `json:"referred_by,omitempty"`
+	Referrals  uint64          `json:"referrals"`
+
+	Bonds          uint64 `json:"bonds"`
+	BondsFinalised uint64 `json:"bonds_finalised"`
+	BondsDisputed  uint64 `json:"bonds_disputed"`
+
+	// TrustScore ranges from 0 to 100.
+	TrustScore uint8 `json:"trust_score"`
+}
+
+// Read is the reader interface implementation for type Profile.
+func (p *Profile) Read(fn func(fields ...any) error) (interface{}, error) {
+	var profile Profile
+	var org, status, referrer sql.NullString
+
+	err := fn(&org, &status, &referrer, &profile.Referrals, &profile.Bonds,
+		&profile.BondsFinalised, &profile.BondsDisputed)
+
+	profile.Org = org.String
+	profile.OrgStatus = Status(status.String)
+	if referrer.Valid {
+		address := common.HexToAddress(referrer.String)
+		profile.ReferredBy = &address
+	}
+	return &profile, err
+}
+
+// score computes the trust score of the profile. It is made up of:
+//   - 30 if vouched for by an active trust organisation.
+//   - 10 if referred by a vetted user.
+//   - 2 for every user referred, upto 20.
+//   - upto 40 in proportion to the bonds finalised against those disputed.
+func (p *Profile) score() uint8 {
+	var score uint64
+	if p.Org != "" && p.OrgStatus == Active {
+		score += vettedScore
+	}
+
+	if p.ReferredBy != nil {
+		score += referredScore
+	}
+
+	score += min(p.Referrals*referralScore, maxReferralsScore)
+
+	if outcomes := p.BondsFinalised + p.BondsDisputed; outcomes > 0 {
+		score += maxBondsScore * p.BondsFinalised / outcomes
+	}
+	return uint8(score)
+}
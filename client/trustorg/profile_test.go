@@ -0,0 +1,43 @@
+package trustorg
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TestProfileScore tests the trust score computed from the profiles.
+func TestProfileScore(t *testing.T) {
+	referrer := common.HexToAddress("0x01")
+
+	td := []struct {
+		testName string
+		profile  Profile
+		score    uint8
+	}{
+		{"Test-for-new-user", Profile{}, 0},
+		{"Test-for-vetted-user", Profile{Org: "Org A", OrgStatus: Active}, 30},
+		{"Test-for-suspended-organisation-user", Profile{Org: "Org A", OrgStatus: Suspended}, 0},
+		{"Test-for-referred-user", Profile{ReferredBy: &referrer}, 10},
+		{"Test-for-referrals", Profile{Referrals: 3}, 6},
+		{"Test-for-referrals-cap", Profile{Referrals: 50}, 20},
+		{"Test-for-disputed-bonds", Profile{Bonds: 2, BondsDisputed: 2}, 0},
+		{"Test-for-mixed-bonds", Profile{Bonds: 5, BondsFinalised: 3, BondsDisputed: 1}, 30},
+		{
+			"Test-for-maximum-score",
+			Profile{
+				Org: "Org A", OrgStatus: Active, ReferredBy: &referrer,
+				Referrals: 10, Bonds: 4, BondsFinalised: 4,
+			},
+			100,
+		},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			if score := v.profile.score(); score != v.score {
+				t.Fatalf("expected the trust score %d but found %d", v.score, score)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+package trustorg
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/dmigwi/dhamana-protocol/client/storage"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Store defines the methods used to persist the trust organisations.
+type Store interface {
+	QueryLocalData(method utils.Method, r storage.Reader, sender string,
+		params ...interface{}) ([]interface{}, error)
+	SetLocalData(method utils.Method, params ...interface{}) error
+}
+
+// Registry registers the trust organisations and the users they vouch for.
+type Registry struct {
+	db Store
+}
+
+// NewRegistry returns a registry persisting the trust organisations in the
+// provided store.
+func NewRegistry(db Store) *Registry {
+	return &Registry{db: db}
+}
+
+// Add registers a new active trust organisation and returns its decoded CA
+// certificate.
+func (r *Registry) Add(name, caCert string) (*x509.Certificate, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	cert, err := ParseCACert(caCert)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.db.SetLocalData(utils.InsertTrustOrg, name, caCert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// Org returns the trust organisation with the provided name. ErrUnknownOrg
+// is returned if it isn't registered.
+func (r *Registry) Org(name string) (*Org, error) {
+	data, err := r.db.QueryLocalData(utils.GetTrustOrg, new(Org), "", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, ErrUnknownOrg
+	}
+	return data[0].(*Org), nil
+}
+
+// Orgs returns all the registered trust organisations.
+func (r *Registry) Orgs() ([]*Org, error) {
+	data, err := r.db.QueryLocalData(utils.GetTrustOrgs, new(Org), "")
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := make([]*Org, 0, len(data))
+	for _, d := range data {
+		orgs = append(orgs, d.(*Org))
+	}
+	return orgs, nil
+}
+
+// SetStatus updates the status of the trust organisation and returns its
+// updated details. Revoked organisations can't be updated.
+func (r *Registry) SetStatus(name string, status Status) (*Org, error) {
+	switch status {
+	case Active, Suspended, Revoked:
+	default:
+		return nil, fmt.Errorf("%w: unsupported status %q", ErrInvalidOrg, status)
+	}
+
+	org, err := r.Org(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if org.Status == Revoked {
+		return nil, ErrOrgRevoked
+	}
+
+	if err = r.db.SetLocalData(utils.UpdateTrustOrgStatus, string(status), name); err != nil {
+		return nil, err
+	}
+
+	return r.Org(name)
+}
+
+// AddUser records the user address vouched for by the active trust
+// organisation. Users already vouched for by an organisation are ignored.
+func (r *Registry) AddUser(name string, user common.Address) error {
+	org, err := r.Org(name)
+	if err != nil {
+		return err
+	}
+
+	if !org.IsActive() {
+		return ErrOrgNotActive
+	}
+
+	return r.db.SetLocalData(utils.InsertOrgUser, user.Hex(), name)
+}
+
+// UserOrg returns the trust organisation vouching for the user. A nil
+// organisation is returned if the user hasn't been vouched for.
+func (r *Registry) UserOrg(user common.Address) (*Org, error) {
+	data, err := r.db.QueryLocalData(utils.GetUserOrg, new(Org), "", user.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return data[0].(*Org), nil
+}
+
+// VerifyChain confirms that the verified client certificate chain issued to
+// the organisation was issued by its registered CA and that the organisation
+// is still active. Organisations not registered are only verified by the CAs
+// configured on the server.
+func (r *Registry) VerifyChain(name string, chain []*x509.Certificate) error {
+	org, err := r.Org(name)
+	switch {
+	case errors.Is(err, ErrUnknownOrg):
+		return nil
+	case err != nil:
+		return err
+	case !org.IsActive():
+		return fmt.Errorf("%w: organisation %q is %s", ErrOrgNotActive, name, org.Status)
+	}
+
+	ca, err := org.Certificate()
+	if err != nil {
+		return err
+	}
+
+	if len(chain) == 0 || !bytes.Equal(chain[len(chain)-1].Raw, ca.Raw) {
+		return fmt.Errorf("client certificate of %q not issued by its registered CA", name)
+	}
+	return nil
+}
+
+// Refer records the new user address referred by the referrer under the
+// active trust organisation vouching for the referrer. Users already referred
+// keep their first referral.
+func (r *Registry) Refer(referrer, referee common.Address) (*Org, error) {
+	if referrer == referee {
+		return nil, fmt.Errorf("%w: users can't refer themselves", ErrInvalidReferral)
+	}
+
+	org, err := r.UserOrg(referrer)
+	switch {
+	case err != nil:
+		return nil, err
+	case org == nil:
+		return nil, ErrNotVetted
+	case !org.IsActive():
+		return nil, fmt.Errorf("%w: organisation %q is %s", ErrOrgNotActive, org.Name, org.Status)
+	}
+
+	err = r.db.SetLocalData(utils.InsertReferral, referee.Hex(), referrer.Hex(), org.Name)
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// Profile returns the user's profile and the trust score computed from it.
+func (r *Registry) Profile(user common.Address) (*Profile, error) {
+	data, err := r.db.QueryLocalData(utils.GetUserProfile, new(Profile), "", user.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	// The aggregated profile query always returns a single row.
+	if len(data) == 0 {
+		return nil, fmt.Errorf("missing profile of user %v", user)
+	}
+
+	profile := data[0].(*Profile)
+	profile.Address = user
+	profile.TrustScore = profile.score()
+	return profile, nil
+}
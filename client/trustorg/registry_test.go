@@ -0,0 +1,363 @@
+package trustorg
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dmigwi/dhamana-protocol/client/storage"
+	"github.com/dmigwi/dhamana-protocol/client/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var sampleUser = common.HexToAddress("0x3396FD816Dd81100477c8ea3853039822f36B7ed")
+
+// memStore is an in-memory store of the trust organisations.
+type memStore struct {
+	orgs  map[string]*Org
+	users map[string]string
+
+	// referrals holds the referrer of each referee.
+	referrals map[string]string
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		orgs:      make(map[string]*Org),
+		users:     make(map[string]string),
+		referrals: make(map[string]string),
+	}
+}
+
+func (m *memStore) QueryLocalData(method utils.Method, r storage.Reader, _ string,
+	params ...interface{},
+) ([]interface{}, error) {
+	var orgs []*Org
+	switch method {
+	case utils.GetUserProfile:
+		return m.profile(r, params[0].(string))
+	case utils.GetTrustOrgs:
+		for _, org := range m.orgs {
+			orgs = append(orgs, org)
+		}
+	case utils.GetTrustOrg:
+		if org, ok := m.orgs[params[0].(string)]; ok {
+			orgs = append(orgs, org)
+		}
+	case utils.GetUserOrg:
+		if org, ok := m.orgs[m.users[params[0].(string)]]; ok {
+			orgs = append(orgs, org)
+		}
+	}
+
+	data := make([]interface{}, 0, len(orgs))
+	for _, org := range orgs {
+		row, err := r.Read(func(fields ...any) error {
+			*fields[0].(*string) = org.Name
+			*fields[1].(*string) = org.CACert
+			*fields[2].(*string) = string(org.Status)
+			*fields[3].(*time.Time) = org.AddedOn
+			*fields[4].(*time.Time) = org.LastUpdate
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, row)
+	}
+	return data, nil
+}
+
+func (m *memStore) SetLocalData(method utils.Method, params ...interface{}) error {
+	switch method {
+	case utils.InsertTrustOrg:
+		name := params[0].(string)
+		if _, ok := m.orgs[name]; ok {
+			return errors.New("duplicate trust organisation")
+		}
+		m.orgs[name] = &Org{Name: name, CACert: params[1].(string), Status: Active}
+	case utils.UpdateTrustOrgStatus:
+		m.orgs[params[1].(string)].Status = Status(params[0].(string))
+	case utils.InsertOrgUser:
+		if _, ok := m.users[params[0].(string)]; !ok {
+			m.users[params[0].(string)] = params[1].(string)
+		}
+	case utils.InsertReferral:
+		if _, ok := m.referrals[params[0].(string)]; !ok {
+			m.referrals[params[0].(string)] = params[1].(string)
+		}
+	}
+	return nil
+}
+
+// profile reads the user's profile. The user isn't a party to any bond.
+func (m *memStore) profile(r storage.Reader, user string) ([]interface{}, error) {
+	row, err := r.Read(func(fields ...any) error {
+		if org, ok := m.orgs[m.users[user]]; ok {
+			*fields[0].(*sql.NullString) = sql.NullString{String: org.Name, Valid: true}
+			*fields[1].(*sql.NullString) = sql.NullString{String: string(org.Status), Valid: true}
+		}
+
+		if referrer, ok := m.referrals[user]; ok {
+			*fields[2].(*sql.NullString) = sql.NullString{String: referrer, Valid: true}
+		}
+
+		for _, referrer := range m.referrals {
+			if referrer == user {
+				*fields[3].(*uint64)++
+			}
+		}
+		return nil
+	})
+	return []interface{}{row}, err
+}
+
+// newCACert returns a PEM encoded self-signed certificate issued to the org.
+func newCACert(t *testing.T, org string, isCA bool) (string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{org}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), cert
+}
+
+// TestAdd tests the validation of the trust organisations registered.
+func TestAdd(t *testing.T) {
+	caCert, _ := newCACert(t, "Org A", true)
+	leafCert, _ := newCACert(t, "Org A", false)
+
+	td := []struct {
+		testName string
+		name     string
+		caCert   string
+		err      error
+	}{
+		{"Test-for-valid-organisation", "Org A", caCert, nil},
+		{"Test-for-empty-name", "", caCert, ErrInvalidOrg},
+		{"Test-for-long-name", string(make([]byte, maxNameLength+1)), caCert, ErrInvalidOrg},
+		{"Test-for-non-pem-certificate", "Org A", "certificate", ErrInvalidOrg},
+		{"Test-for-non-ca-certificate", "Org A", leafCert, ErrInvalidOrg},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			registry := NewRegistry(newMemStore())
+			_, err := registry.Add(v.name, v.caCert)
+			if !errors.Is(err, v.err) {
+				t.Fatalf("expected error %v but found %v", v.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			org, err := registry.Org(v.name)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if !org.IsActive() {
+				t.Fatalf("expected a new organisation to be active but found %s", org.Status)
+			}
+		})
+	}
+}
+
+// TestSetStatus tests the status updates of the trust organisations and
+// their effect on the users they vouch for.
+func TestSetStatus(t *testing.T) {
+	caCert, _ := newCACert(t, "Org A", true)
+
+	registry := NewRegistry(newMemStore())
+	if _, err := registry.Add("Org A", caCert); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if err := registry.AddUser("Org A", sampleUser); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	td := []struct {
+		testName string
+		name     string
+		status   Status
+		err      error
+		userErr  error
+	}{
+		{"Test-for-unknown-organisation", "Org X", Suspended, ErrUnknownOrg, nil},
+		{"Test-for-unsupported-status", "Org A", Status("deleted"), ErrInvalidOrg, nil},
+		{"Test-for-suspended-organisation", "Org A", Suspended, nil, ErrOrgNotActive},
+		{"Test-for-reinstated-organisation", "Org A", Active, nil, nil},
+		{"Test-for-revoked-organisation", "Org A", Revoked, nil, ErrOrgNotActive},
+		{"Test-for-reinstating-revoked-organisation", "Org A", Active, ErrOrgRevoked, ErrOrgNotActive},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			_, err := registry.SetStatus(v.name, v.status)
+			if !errors.Is(err, v.err) {
+				t.Fatalf("expected error %v but found %v", v.err, err)
+			}
+
+			err = registry.AddUser("Org A", common.HexToAddress("0x01"))
+			if !errors.Is(err, v.userErr) {
+				t.Fatalf("expected the new user error %v but found %v", v.userErr, err)
+			}
+
+			org, err := registry.UserOrg(sampleUser)
+			if err != nil {
+				t.Fatalf("expected no error but found %q", err)
+			}
+
+			if org.IsActive() != (v.userErr == nil) {
+				t.Fatalf("expected the user organisation to be active=%v but found %s",
+					v.userErr == nil, org.Status)
+			}
+		})
+	}
+}
+
+// TestVerifyChain tests that the client certificates chains are verified
+// against the registered trust organisations CAs.
+func TestVerifyChain(t *testing.T) {
+	caCertA, certA := newCACert(t, "Org A", true)
+	caCertB, certB := newCACert(t, "Org B", true)
+	_, otherCert := newCACert(t, "Org A", true)
+
+	registry := NewRegistry(newMemStore())
+	for name, ca := range map[string]string{"Org A": caCertA, "Org B": caCertB} {
+		if _, err := registry.Add(name, ca); err != nil {
+			t.Fatalf("expected no error but found %q", err)
+		}
+	}
+
+	if _, err := registry.SetStatus("Org B", Suspended); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	td := []struct {
+		testName string
+		name     string
+		chain    []*x509.Certificate
+		isValid  bool
+	}{
+		{"Test-for-registered-ca", "Org A", []*x509.Certificate{certA}, true},
+		{"Test-for-unregistered-organisation", "Org X", []*x509.Certificate{otherCert}, true},
+		{"Test-for-other-ca", "Org A", []*x509.Certificate{otherCert}, false},
+		{"Test-for-empty-chain", "Org A", nil, false},
+		{"Test-for-suspended-organisation", "Org B", []*x509.Certificate{certB}, false},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			err := registry.VerifyChain(v.name, v.chain)
+			if (err == nil) != v.isValid {
+				t.Fatalf("expected the chain validity to be %v but found error %v",
+					v.isValid, err)
+			}
+		})
+	}
+}
+
+// TestRefer tests that only the users vetted by an active trust organisation
+// can refer new users and that the referrals count in their profiles.
+func TestRefer(t *testing.T) {
+	caCert, _ := newCACert(t, "Org A", true)
+	referee := common.HexToAddress("0x01")
+	unvetted := common.HexToAddress("0x02")
+
+	registry := NewRegistry(newMemStore())
+	if _, err := registry.Add("Org A", caCert); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if err := registry.AddUser("Org A", sampleUser); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	td := []struct {
+		testName string
+		referrer common.Address
+		referee  common.Address
+		err      error
+	}{
+		{"Test-for-self-referral", sampleUser, sampleUser, ErrInvalidReferral},
+		{"Test-for-unvetted-referrer", unvetted, referee, ErrNotVetted},
+		{"Test-for-vetted-referrer", sampleUser, referee, nil},
+		{"Test-for-referee-already-referred", sampleUser, referee, nil},
+	}
+
+	for _, v := range td {
+		t.Run(v.testName, func(t *testing.T) {
+			org, err := registry.Refer(v.referrer, v.referee)
+			if !errors.Is(err, v.err) {
+				t.Fatalf("expected error %v but found %v", v.err, err)
+			}
+
+			if err == nil && org.Name != "Org A" {
+				t.Fatalf("expected the referral under %q but found %q", "Org A", org.Name)
+			}
+		})
+	}
+
+	profile, err := registry.Profile(sampleUser)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if profile.Org != "Org A" || profile.Referrals != 1 {
+		t.Fatalf("expected 1 referral under %q but found %d under %q",
+			"Org A", profile.Referrals, profile.Org)
+	}
+
+	if profile.TrustScore != vettedScore+referralScore {
+		t.Fatalf("expected the trust score %d but found %d",
+			vettedScore+referralScore, profile.TrustScore)
+	}
+
+	profile, err = registry.Profile(referee)
+	if err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if profile.ReferredBy == nil || *profile.ReferredBy != sampleUser {
+		t.Fatalf("expected the referee to be referred by %v but found %v",
+			sampleUser, profile.ReferredBy)
+	}
+
+	if _, err = registry.SetStatus("Org A", Suspended); err != nil {
+		t.Fatalf("expected no error but found %q", err)
+	}
+
+	if _, err = registry.Refer(sampleUser, unvetted); !errors.Is(err, ErrOrgNotActive) {
+		t.Fatalf("expected error %v but found %v", ErrOrgNotActive, err)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2023 Migwi Ndung'u
+// See LICENSE for details.
+
+// Package trustorg models the trust organisations that vet the users before
+// they are granted access to the dhamana protocol via a POA.
+package trustorg
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Status defines the access status of a trust organisation.
+type Status string
+
+const (
+	// Active organisations and the users they vouch for can access the server.
+	Active Status = "active"
+
+	// Suspended organisations and the users they vouch for are temporarily
+	// denied access to the server.
+	Suspended Status = "suspended"
+
+	// Revoked organisations and the users they vouch for are permanently
+	// denied access to the server.
+	Revoked Status = "revoked"
+
+	// maxNameLength defines the maximum length of an organisation name. It
+	// matches the upper bound of the certificate subject organisation.
+	maxNameLength = 64
+)
+
+var (
+	// ErrUnknownOrg is returned if the trust organisation isn't registered.
+	ErrUnknownOrg = errors.New("unknown trust organisation")
+
+	// ErrOrgRevoked is returned if the revoked trust organisation is updated.
+	ErrOrgRevoked = errors.New("trust organisation revoked")
+
+	// ErrOrgNotActive is returned if the trust organisation is suspended or revoked.
+	ErrOrgNotActive = errors.New("trust organisation not active")
+
+	// ErrInvalidOrg is returned if the trust organisation details are invalid.
+	ErrInvalidOrg = errors.New("invalid trust organisation")
+
+	// ErrNotVetted is returned if the referrer isn't vouched for by an active
+	// trust organisation.
+	ErrNotVetted = errors.New("user not vetted by a trust organisation")
+
+	// ErrInvalidReferral is returned if the referral can't be recorded.
+	ErrInvalidReferral = errors.New("invalid referral")
+)
+
+// Org defines a trust organisation and the CA certificate issuing the client
+// certificates of its POAs.
+type Org struct {
+	Name       string    `json:"name"`
+	CACert     string    `json:"ca_cert"` // PEM encoded.
+	Status     Status    `json:"status"`
+	AddedOn    time.Time `json:"added_on"`
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// Read is the reader interface implementation for type Org.
+func (o *Org) Read(fn func(fields ...any) error) (interface{}, error) {
+	var org Org
+	var status string
+
+	err := fn(&org.Name, &org.CACert, &status, &org.AddedOn, &org.LastUpdate)
+
+	org.Status = Status(status)
+	return &org, err
+}
+
+// IsActive returns true if the organisation and its users can access the server.
+func (o *Org) IsActive() bool {
+	return o.Status == Active
+}
+
+// Certificate returns the decoded organisation CA certificate.
+func (o *Org) Certificate() (*x509.Certificate, error) {
+	return ParseCACert(o.CACert)
+}
+
+// ParseCACert decodes the PEM encoded CA certificate of a trust organisation.
+func ParseCACert(caCert string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(caCert))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%w: expected a PEM encoded CA certificate", ErrInvalidOrg)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOrg, err)
+	}
+
+	if !cert.IsCA {
+		return nil, fmt.Errorf("%w: certificate %s isn't a CA", ErrInvalidOrg, cert.Subject)
+	}
+
+	if time.Now().After(cert.NotAfter) {
+		return nil, fmt.Errorf("%w: certificate %s expired", ErrInvalidOrg, cert.Subject)
+	}
+	return cert, nil
+}
+
+// validateName confirms that the organisation name can be set on the subject
+// of its client certificates.
+func validateName(name string) error {
+	if name == "" || len(name) > maxNameLength {
+		return fmt.Errorf("%w: expected a name of 1 to %d characters",
+			ErrInvalidOrg, maxNameLength)
+	}
+	return nil
+}
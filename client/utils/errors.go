@@ -30,6 +30,17 @@ var (
 		ErrUnknownParam:      1009,
 		ErrInvalidSigningKey: 1010,
 		ErrMissingServerKey:  1011,
+		ErrSigningDisabled:   1012,
+		ErrInvalidSignedTx:   1013,
+		ErrNotBondParty:      1014,
+		ErrContractReverted:  1015,
+		ErrSapphireCall:      1016,
+		ErrInvalidChallenge:  1017,
+		ErrInvalidSession:    1018,
+		ErrOrgSuspended:      1019,
+		ErrNotAdmin:          1020,
+		ErrNotVetted:         1021,
+		ErrInvalidSignedCall: 1022,
 	}
 
 	// ErrInvalidJSON returned if an error occurred while parsing the request JSON
@@ -75,6 +86,50 @@ var (
 	// ErrMissingServerKey is returned if a sender doesn't request for the public
 	// server keys before accessing the contract backend.
 	ErrMissingServerKey = errors.New("missing server key")
+
+	// ErrSigningDisabled is returned if the transaction signing mode used by
+	// the sender has been disabled on the server.
+	ErrSigningDisabled = errors.New("signing mode disabled")
+
+	// ErrInvalidSignedTx is returned if the client signed transaction can't be
+	// decoded or wasn't signed by the sender.
+	ErrInvalidSignedTx = errors.New("invalid signed transaction")
+
+	// ErrInvalidSignedCall is returned if the client signed call payload
+	// wasn't signed by the sender or its leash expired.
+	ErrInvalidSignedCall = errors.New("invalid signed call")
+
+	// ErrNotBondParty is returned if the sender requests bond details only
+	// accessible to the bond issuer and holder.
+	ErrNotBondParty = errors.New("sender not a bond party")
+
+	// ErrContractReverted is returned if the contract reverted the call or
+	// the transaction sent. The revert reason is sent as the error data.
+	ErrContractReverted = errors.New("contract execution reverted")
+
+	// ErrSapphireCall is returned if the Sapphire paratime failed the call
+	// before it reached the contract.
+	ErrSapphireCall = errors.New("sapphire call failed")
+
+	// ErrInvalidChallenge is returned if the session challenge is missing,
+	// expired or wasn't signed by the sender.
+	ErrInvalidChallenge = errors.New("invalid session challenge")
+
+	// ErrInvalidSession is returned if the session token sent doesn't match
+	// the one issued to the sender.
+	ErrInvalidSession = errors.New("invalid session token")
+
+	// ErrOrgSuspended is returned if the trust organisation that vouched for
+	// the sender has been suspended or revoked.
+	ErrOrgSuspended = errors.New("trust organisation access denied")
+
+	// ErrNotAdmin is returned if a sender who isn't an admin requests the
+	// trust organisations management methods.
+	ErrNotAdmin = errors.New("sender not an admin")
+
+	// ErrNotVetted is returned if a sender who isn't vouched for by an active
+	// trust organisation refers a new user.
+	ErrNotVetted = errors.New("sender not vetted by a trust organisation")
 )
 
 // GetErrorCode returns the set error code if it exists or max(uint16) if otherwise.
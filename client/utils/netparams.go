@@ -4,6 +4,8 @@
 package utils
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
 	"math/big"
 )
@@ -14,6 +16,7 @@ const (
 	SapphireMainnet NetworkType = iota
 	SapphireTestnet
 	SapphireLocalnet
+	Development
 	LocalTesting
 	UnsupportedNet
 )
@@ -27,8 +30,38 @@ type NetworkParams struct {
 	ChainID        big.Int
 	DefaultGateway string
 	RuntimeID      string
+
+	// KeyManagerPubKey is the base64 encoded public key the network's key
+	// manager signs the runtime public keys with. It must be set via
+	// SetKeyManagerPubKey before encrypting calls on the confidential networks.
+	KeyManagerPubKey string
+
+	// PlainCalls sends the calldata unencrypted on the non-confidential EVM
+	// networks.
+	PlainCalls bool
+
+	// GasMultiplier is the safety margin applied on the node's gas estimates.
+	GasMultiplier float64
+	// MaxGasLimit is the gas ceiling of the transactions submitted.
+	MaxGasLimit uint64
+	// MinGasPrice and MaxGasPrice bound the gas price suggested by the node.
+	MinGasPrice big.Int
+	MaxGasPrice big.Int
 }
 
+const (
+	// defaultGasMultiplier adds a 20% safety margin on the gas estimates.
+	defaultGasMultiplier = 1.2
+	// defaultMaxGasLimit matches the Sapphire paratime's max batch gas.
+	defaultMaxGasLimit = 15_000_000
+	// defaultMinGasPrice is the Sapphire paratime's minimum gas price in wei.
+	defaultMinGasPrice = 100_000_000_000
+	// defaultMaxGasPrice is the highest gas price in wei paid on the transactions.
+	defaultMaxGasPrice = 500_000_000_000
+	// defaultDevGasLimit matches the ganache default block gas limit.
+	defaultDevGasLimit = 6_721_975
+)
+
 // Networks defines the configurations mappings to the various networks supported.
 var networks = map[NetworkType]NetworkParams{
 	SapphireMainnet: {
@@ -36,18 +69,40 @@ var networks = map[NetworkType]NetworkParams{
 		ChainID:        *big.NewInt(0x5afe),
 		DefaultGateway: "https://sapphire.oasis.io",
 		RuntimeID:      "0x000000000000000000000000000000000000000000000000f80306c9858e7279",
+		GasMultiplier:  defaultGasMultiplier,
+		MaxGasLimit:    defaultMaxGasLimit,
+		MinGasPrice:    *big.NewInt(defaultMinGasPrice),
+		MaxGasPrice:    *big.NewInt(defaultMaxGasPrice),
 	},
 	SapphireTestnet: {
 		Name:           "testnet",
 		ChainID:        *big.NewInt(0x5aff),
 		DefaultGateway: "https://testnet.sapphire.oasis.dev",
 		RuntimeID:      "0x000000000000000000000000000000000000000000000000a6d1e3ebf60dff6c",
+		GasMultiplier:  defaultGasMultiplier,
+		MaxGasLimit:    defaultMaxGasLimit,
+		MinGasPrice:    *big.NewInt(defaultMinGasPrice),
+		MaxGasPrice:    *big.NewInt(defaultMaxGasPrice),
 	},
 	SapphireLocalnet: {
 		Name:           "localnet",
 		ChainID:        *big.NewInt(0x5afd),
 		DefaultGateway: "http://localhost:8545",
 		RuntimeID:      "0x8000000000000000000000000000000000000000000000000000000000000000",
+		GasMultiplier:  defaultGasMultiplier,
+		MaxGasLimit:    defaultMaxGasLimit,
+		MinGasPrice:    *big.NewInt(defaultMinGasPrice),
+		MaxGasPrice:    *big.NewInt(defaultMaxGasPrice),
+	},
+	// Development is a vanilla non-confidential EVM dev chain such as the one
+	// the truffle development network targets.
+	Development: {
+		Name:           "development",
+		ChainID:        *big.NewInt(1337),
+		DefaultGateway: "http://127.0.0.1:8545",
+		PlainCalls:     true,
+		GasMultiplier:  defaultGasMultiplier,
+		MaxGasLimit:    defaultDevGasLimit,
 	},
 	// Network params configuration is empty on purpose because its meant to used
 	// only by the unit tests.
@@ -66,6 +121,24 @@ func GetNetworkConfig(net NetworkType) (*NetworkParams, error) {
 	return &params, nil
 }
 
+// SetKeyManagerPubKey sets the base64 encoded key manager public key the
+// runtime public keys of the provided network are verified against.
+func SetKeyManagerPubKey(net NetworkType, pubKey string) error {
+	params, ok := networks[net]
+	if !ok {
+		return fmt.Errorf("could not fetch %v network", net.String())
+	}
+
+	key, err := base64.StdEncoding.DecodeString(pubKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid %s key manager public key", params.Name)
+	}
+
+	params.KeyManagerPubKey = pubKey
+	networks[net] = params
+	return nil
+}
+
 // String defines the default stringer for NetworkType.
 func (n NetworkType) String() string {
 	switch n {
@@ -75,6 +148,8 @@ func (n NetworkType) String() string {
 		return "SapphireTestnet"
 	case SapphireLocalnet:
 		return "SapphireLocalnet"
+	case Development:
+		return "Development"
 	default:
 		return "UnsupportedNet"
 	}
@@ -90,6 +165,8 @@ func ToNetType(net string) NetworkType {
 		return SapphireTestnet
 	case "SapphireLocalnet", "sapphire_localnet":
 		return SapphireLocalnet
+	case "Development", "development":
+		return Development
 	default:
 		return UnsupportedNet
 	}
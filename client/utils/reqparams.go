@@ -12,6 +12,9 @@ type (
 
 	// Method defines the specific method names implemented.
 	Method string
+
+	// SigningMode defines who signs the contract transactions.
+	SigningMode string
 )
 
 const (
@@ -36,6 +39,10 @@ const (
 	// LimitType defines unsigned LIMIT integer parameter of value type uint8.
 	LimitType ParamType = "uint8_LIMIT"
 
+	// OptionalBoolType defines an optional boolean parameter. It can only be
+	// the last parameter and defaults to false if missing.
+	OptionalBoolType ParamType = "bool_OPTIONAL"
+
 	// MaxLimit restricts the max limit that can be set into 100 when querying
 	// more than 1 record.
 	MaxLimit = uint(100)
@@ -43,10 +50,25 @@ const (
 	// UnsupportedType defines all other types not classified as int, float or string
 	UnsupportedType ParamType = "unsupported"
 
-	LocalType     MethodType = iota // Locally implemented
-	ContractType                    // Implemented by the contracts
-	ServerKeyType                   // Method for route /serverpubkey
-	UnknownType                     // method not supported
+	LocalType        MethodType = iota // Locally implemented
+	ContractType                       // Implemented by the contracts
+	ServerKeyType                      // Method for route /serverpubkey
+	SubscriptionType                   // Method for route /subscribe
+	SignedTxType                       // Method sending client signed txs and calls
+	AdminType                          // Method managing the trust organisations
+	UnknownType                        // method not supported
+
+	// ServerSigning sets the server to sign the transactions using the client
+	// private key sent encrypted in the sender's signing key.
+	ServerSigning SigningMode = "server"
+
+	// ClientSigning sets the server to build the unsigned transactions which
+	// the client signs before sending them back to be broadcasted. The client
+	// private keys are never sent to the server.
+	ClientSigning SigningMode = "client"
+
+	// AnySigning allows both the server and the client signing modes.
+	AnySigning SigningMode = "any"
 
 	// --- Server methods supported ---
 
@@ -61,17 +83,51 @@ const (
 
 	// server key type method - Sent via the server
 
-	GetServerPubKey Method = "getServerPubKey"
+	GetSessionChallenge Method = "getSessionChallenge"
+	GetServerPubKey     Method = "getServerPubKey"
+
+	// subscription type methods - Sent via the websocket connection
+
+	SubscribeBond   Method = "subscribeBond"
+	UnsubscribeBond Method = "unsubscribeBond"
+
+	// signed tx type methods - Sent via the server
+
+	SendSignedTx   Method = "sendSignedTx"
+	SendSignedCall Method = "sendSignedCall"
+
+	// BondEvent is the method set on the notifications pushed to the subscribed
+	// websocket clients.
+	BondEvent Method = "bondEvent"
 
 	// Local type methods - Sent via the server
 
 	GetBonds         Method = "getBonds"
 	GetBondByAddress Method = "getBondByAddress"
 	GetChats         Method = "getChats"
+	GetTxStatus      Method = "getTxStatus"
+	GetMyPendingTxs  Method = "getMyPendingTxs"
+
+	GetBondSecureDetails Method = "getBondSecureDetails"
+
+	ReferUser      Method = "referUser"
+	GetUserProfile Method = "getUserProfile"
+
+	// admin type methods - Sent via the server by the admins only
+
+	AddTrustOrg       Method = "addTrustOrg"
+	SuspendTrustOrg   Method = "suspendTrustOrg"
+	ReinstateTrustOrg Method = "reinstateTrustOrg"
+	RevokeTrustOrg    Method = "revokeTrustOrg"
+	AddOrgUser        Method = "addOrgUser"
+	GetTrustOrgs      Method = "getTrustOrgs"
 
 	// Local Utils Methods. Results not sent via the server
 
 	GetLastSyncedBlock Method = "getLastSyncedBlock"
+	GetBlockHashes     Method = "getBlockHashes"
+	GetBondParties     Method = "getBondParties"
+	GetPendingTxs      Method = "getPendingTxs"
 
 	UpdateBondBodyTerms  Method = "updateBondBodyTerms"
 	UpdateBondMotivation Method = "updateBondMotivation"
@@ -81,6 +137,27 @@ const (
 	InsertNewChatMessage Method = "insertNewchatMsg"
 	InsertStatusChange   Method = "insertStatusChange"
 	InsertStatusSigned   Method = "insertStatusSigned"
+	InsertBlockHash      Method = "insertBlockHash"
+	UpdateSyncState      Method = "updateSyncState"
+	InsertPendingTx      Method = "insertPendingTx"
+	UpdateTxStatus       Method = "updateTxStatus"
+
+	GetTrustOrg          Method = "getTrustOrg"
+	GetUserOrg           Method = "getUserOrg"
+	InsertTrustOrg       Method = "insertTrustOrg"
+	UpdateTrustOrgStatus Method = "updateTrustOrgStatus"
+	InsertOrgUser        Method = "insertOrgUser"
+	InsertReferral       Method = "insertReferral"
+
+	GetSession            Method = "getSession"
+	CountSessions         Method = "countSessions"
+	InsertSession         Method = "insertSession"
+	DeleteSession         Method = "deleteSession"
+	DeleteExpiredSessions Method = "deleteExpiredSessions"
+
+	InsertSessionChallenge  Method = "insertSessionChallenge"
+	TakeSessionChallenge    Method = "takeSessionChallenge"
+	DeleteExpiredChallenges Method = "deleteExpiredChallenges"
 )
 
 var (
@@ -172,9 +249,11 @@ var (
 		// getBondByAddress returns a bond at any status if the request sender is
 		// also the bond issuer otherwise only returns bond with status
 		// Negotiating.
-		// Parameter Required: bondAddress string
+		// Parameter Required: bondAddress string, includePending bool (optional)
 		// bondAddress => Defines the address of the bond in question.
-		GetBondByAddress: {AddressType},
+		// includePending => If true, the bond events yet to get the required
+		// 		number of confirmations are returned with the bond.
+		GetBondByAddress: {AddressType, OptionalBoolType},
 
 		// getBonds returns all the bonds with status Negotiating or owned by
 		// the sender if their current status status is past Negotiating stage.
@@ -186,25 +265,167 @@ var (
 		// getChats returns the conversation in the bond address provides.
 		// The specific bond must either be in the negotiation stage or
 		// the sender is a party to the bond.
-		// Parameter Required: bondAddress string, limit uint16, offset uint16
+		// Parameter Required: bondAddress string, limit uint16, offset uint16,
+		// 		includePending bool (optional)
 		// bondAddress => Defines the address of the bond in question.
 		// limit => Defines the number of chats to return. Max value is 100
 		// offset => Defines the number of chats to skip before returning the
 		//  	require number of chats.
-		GetChats: {AddressType, LimitType, Uint16Type},
+		// includePending => If true, the first page is preceded by the chats
+		// 		yet to get the required number of confirmations.
+		GetChats: {AddressType, LimitType, Uint16Type, OptionalBoolType},
+
+		// getTxStatus returns the lifecycle status of a transaction submitted
+		// by the sender. A mined createBond transaction also returns the
+		// address of the bond created.
+		// Parameter Required: txHash string
+		// txHash => Defines the hash of the transaction in question.
+		GetTxStatus: {StringType},
+
+		// getMyPendingTxs returns the sender's transactions yet to be mined.
+		// Parameter Required: limit uint16, offset uint16
+		// limit => Defines the number of transactions to return. Max value is 100
+		// offset => Defines the number of transactions to skip before returning
+		// 		the require number of transactions.
+		GetMyPendingTxs: {LimitType, Uint16Type},
+
+		// getBondSecureDetails returns the bond security and appendix details
+		// which are only visible to the bond issuer and holder. They are read
+		// from the contract through a call signed with the sender's signing
+		// key. Without a signing key, the EIP-712 signed call payload is
+		// returned instead for the client to sign and send via sendSignedCall.
+		// Parameter Required: bondAddress string
+		// bondAddress => Defines the address of the bond in question.
+		GetBondSecureDetails: {AddressType},
+
+		// referUser records the new user address referred by the sender. The
+		// sender must be vouched for by an active trust organisation, under
+		// which the referral is recorded. Addresses already referred are ignored.
+		// Parameter Required: userAddress address
+		// userAddress => Defines the address of the user referred.
+		ReferUser: {AddressType},
+
+		// getUserProfile returns the trust organisation, the referrals and the
+		// bond outcomes of the user together with the trust score computed
+		// from them.
+		// Parameter Required: userAddress address
+		// userAddress => Defines the address of the user in question.
+		GetUserProfile: {AddressType},
 	}
 
 	// serverKeyMethod defines the method used to query the server keys
 	serverKeyMethod = map[Method][]ParamType{
+		// getSessionChallenge is used to request the challenge the sender must
+		// sign to prove it controls the sender address.
+		// Parameter Required: None
+		// The server sends back the challenge nonce and the message to be
+		// signed using the EIP-191 personal_sign scheme.
+		GetSessionChallenge: {},
+
 		// getServerPubKey is used to query the session's server public key.
-		// Parameter Required: clientPubkey string
-		// The client provides its public key and in return the server sends
-		// back its public key. Using diffie-hellman, a sharedkey developed
-		// is used to communicate securely between the client and the server.
-		GetServerPubKey: {StringType},
+		// Parameter Required: clientPubkey string, nonce string, signature string
+		// The client provides its public key together with the signed session
+		// challenge and in return the server sends back its public key and the
+		// session token. Using diffie-hellman, a sharedkey developed is used to
+		// communicate securely between the client and the server.
+		GetServerPubKey: {StringType, StringType, StringType},
+	}
+
+	// subscriptionMethods defines the methods used to manage the bond events
+	// subscriptions on the websocket connection.
+	subscriptionMethods = map[Method][]ParamType{
+		// subscribeBond is used to recieve the bond events once they are
+		// persisted. The same visibility rules as getBondByAddress apply.
+		// Parameter Required: bondAddress string
+		// bondAddress => Defines the address of the bond in question.
+		SubscribeBond: {AddressType},
+
+		// unsubscribeBond is used to stop recieving the bond events.
+		// Parameter Required: bondAddress string
+		// bondAddress => Defines the address of the bond in question.
+		UnsubscribeBond: {AddressType},
+	}
+
+	// signedTxMethods defines the methods used to send the transactions and
+	// the calls signed by the client.
+	signedTxMethods = map[Method][]ParamType{
+		// sendSignedTx is used to broadcast the unsigned transaction returned
+		// by a contract method sent without a signing key, once the client has
+		// signed it. The signer must be the message sender.
+		// Parameter Required: unsignedTx string, signature string
+		// unsignedTx => Defines the hex encoded unsigned transaction returned.
+		// signature => Defines the hex encoded [R || S || V] signature of the
+		// 		signing hash returned with the unsigned transaction.
+		SendSignedTx: {StringType, StringType},
+
+		// sendSignedCall is used to read the bond secure details using the
+		// signed call payload returned by getBondSecureDetails, once the
+		// client has signed it. The signer must be the message sender.
+		// Parameter Required: bondAddress address, blockNumber uint64, signature string
+		// bondAddress => Defines the address of the bond in question.
+		// blockNumber => Defines the block number returned with the payload.
+		// signature => Defines the hex encoded [R || S || V] signature of the
+		// 		payload digest.
+		SendSignedCall: {AddressType, Uint64Type, StringType},
+	}
+
+	// adminMethods defines the methods used by the admins to manage the trust
+	// organisations vetting the users.
+	adminMethods = map[Method][]ParamType{
+		// addTrustOrg registers a new trust organisation whose CA certificate
+		// issues the client certificates of its POAs.
+		// Parameter Required: orgName string, caCert string
+		// orgName => Defines the organisation name set on its client certificates.
+		// caCert => Defines the PEM encoded organisation CA certificate.
+		AddTrustOrg: {StringType, StringType},
+
+		// suspendTrustOrg temporarily denies access to the trust organisation
+		// and the users it vouches for.
+		// Parameter Required: orgName string
+		SuspendTrustOrg: {StringType},
+
+		// reinstateTrustOrg restores the access of a suspended trust organisation.
+		// Parameter Required: orgName string
+		ReinstateTrustOrg: {StringType},
+
+		// revokeTrustOrg permanently denies access to the trust organisation
+		// and the users it vouches for.
+		// Parameter Required: orgName string
+		RevokeTrustOrg: {StringType},
+
+		// addOrgUser records the user address vouched for by the trust organisation.
+		// Parameter Required: orgName string, userAddress address
+		AddOrgUser: {StringType, AddressType},
+
+		// getTrustOrgs returns all the registered trust organisations.
+		// No user parameters are expected.
+		GetTrustOrgs: {},
 	}
 )
 
+// ToSigningMode returns the signing mode matching the provided string value.
+// An empty signing mode is returned if its not supported.
+func ToSigningMode(mode string) SigningMode {
+	switch m := SigningMode(mode); m {
+	case ServerSigning, ClientSigning, AnySigning:
+		return m
+	default:
+		return ""
+	}
+}
+
+// ServerSigns returns true if the server signs the transactions using the
+// client private keys sent.
+func (m SigningMode) ServerSigns() bool {
+	return m == ServerSigning || m == AnySigning
+}
+
+// ClientSigns returns true if the client signs the transactions built by the
+// server.
+func (m SigningMode) ClientSigns() bool {
+	return m == ClientSigning || m == AnySigning
+}
+
 // GetMethodParams returns the parameters of the method provided if supported.
 func GetMethodParams(method Method) (implementation MethodType, param []ParamType) {
 	// contract implemented methods
@@ -222,5 +443,20 @@ func GetMethodParams(method Method) (implementation MethodType, param []ParamTyp
 		return ServerKeyType, data
 	}
 
+	// Subscription methods
+	if data, ok := subscriptionMethods[method]; ok {
+		return SubscriptionType, data
+	}
+
+	// Signed tx methods
+	if data, ok := signedTxMethods[method]; ok {
+		return SignedTxType, data
+	}
+
+	// Admin methods
+	if data, ok := adminMethods[method]; ok {
+		return AdminType, data
+	}
+
 	return UnknownType, nil
 }